@@ -1,8 +1,17 @@
 package main
 
 import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/gegaryfa/tavern/domain/customer"
+	ledgermemory "github.com/gegaryfa/tavern/domain/ledger/memory"
 	"github.com/gegaryfa/tavern/domain/product"
+	"github.com/gegaryfa/tavern/services/billing"
+	"github.com/gegaryfa/tavern/services/kitchen"
 	"github.com/gegaryfa/tavern/services/order"
+	"github.com/gegaryfa/tavern/telemetry"
 	"github.com/google/uuid"
 
 	servicetavern "github.com/gegaryfa/tavern/services/tavern"
@@ -17,18 +26,42 @@ func main() {
 	os, err := order.NewOrderService(
 		order.WithMongoCustomerRepository("mongodb://localhost:27017"),
 		order.WithMemoryProductRepository(products),
+		order.WithLogger(slog.Default()),
+	)
+	if err != nil {
+		panic(err)
+	}
+	// Bill the customers by recording transactions on them and booking them in the ledger, the memory journal
+	// is lost when the tavern stops, the sql journal keeps it
+	bs, err := billing.NewCustomerBilling(
+		billing.WithCustomerRepository(os.Customers),
+		billing.WithJournal(ledgermemory.New()),
+		billing.WithLogger(slog.Default()),
+	)
+	if err != nil {
+		panic(err)
+	}
+	// Queue the placed orders at the bar and the kitchen
+	k, err := kitchen.NewKitchen(
+		kitchen.WithMemoryTicketRepository(),
+		kitchen.WithOrderRepository(os.Orders),
+		kitchen.WithLogger(slog.Default()),
 	)
 	if err != nil {
 		panic(err)
 	}
 	// Create tavern service
 	tavern, err := servicetavern.NewTavern(
-		servicetavern.WithOrderService(os))
+		servicetavern.WithOrderService(os),
+		servicetavern.WithBillingService(bs),
+		servicetavern.WithKitchen(k),
+		servicetavern.WithLogger(slog.Default()),
+	)
 	if err != nil {
 		panic(err)
 	}
 
-	uid, err := os.AddCustomer("Percy")
+	uid, err := os.AddCustomer("Percy", customer.WithDateOfBirth(time.Date(1990, 4, 12, 0, 0, 0, 0, time.UTC)))
 	if err != nil {
 		panic(err)
 	}
@@ -36,22 +69,36 @@ func main() {
 		products[0].GetID(),
 	}
 	// Execute Order
-	err = tavern.Order(uid, order)
+	ctx := telemetry.WithRequestID(context.Background(), uuid.NewString())
+	_, err = tavern.Order(ctx, uid, order)
 	if err != nil {
 		panic(err)
 	}
 }
 
 func productInventory() []product.Product {
-	beer, err := product.NewProduct("Beer", "Healthy Beverage", 1.99)
+	beer, err := product.NewProduct("Beer", "Healthy Beverage", 1.99,
+		product.WithCategory(product.CategoryDrinks),
+		product.WithTags(product.TagAlcoholic, product.TagVegan),
+		product.WithSection("Beers"),
+		product.WithAgeRestriction(),
+	)
 	if err != nil {
 		panic(err)
 	}
-	peenuts, err := product.NewProduct("Peenuts", "Healthy Snacks", 0.99)
+	peenuts, err := product.NewProduct("Peenuts", "Healthy Snacks", 0.99,
+		product.WithCategory(product.CategorySnacks),
+		product.WithTags(product.TagVegan, product.TagGlutenFree),
+	)
 	if err != nil {
 		panic(err)
 	}
-	wine, err := product.NewProduct("Wine", "Healthy Snacks", 0.99)
+	wine, err := product.NewProduct("Wine", "Red Wine", 0.99,
+		product.WithCategory(product.CategoryDrinks),
+		product.WithTags(product.TagAlcoholic, product.TagVegan, product.TagGlutenFree),
+		product.WithSection("Wines"),
+		product.WithAgeRestriction(),
+	)
 	if err != nil {
 		panic(err)
 	}
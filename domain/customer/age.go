@@ -0,0 +1,39 @@
+package customer
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrUnderage is returned when a customer is younger than the legal age for a product
+	ErrUnderage = errors.New("the customer is under the legal age")
+	// ErrAgeNotVerified is returned when a product needs a legal age but the date of birth of the customer is unknown
+	ErrAgeNotVerified = errors.New("the age of the customer has not been verified")
+)
+
+// AgeAt returns the age of the customer at the given instant, it is false when the date of birth is unknown
+func (c Customer) AgeAt(at time.Time) (int, bool) {
+	dob := c.GetDateOfBirth()
+	if dob.IsZero() {
+		return 0, false
+	}
+	age := at.Year() - dob.Year()
+	// The birthday of this year has not been reached yet
+	if at.Month() < dob.Month() || (at.Month() == dob.Month() && at.Day() < dob.Day()) {
+		age--
+	}
+	return age, true
+}
+
+// CheckLegalAge returns an error when the customer is not verified to be at least legalAge at the given instant
+func (c Customer) CheckLegalAge(legalAge int, at time.Time) error {
+	age, ok := c.AgeAt(at)
+	if !ok {
+		return ErrAgeNotVerified
+	}
+	if age < legalAge {
+		return ErrUnderage
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+// Package cache is a read-through caching decorator for any customer.Repository
+package cache
+
+import (
+	"time"
+
+	"github.com/gegaryfa/tavern/domain/customer"
+	"github.com/gegaryfa/tavern/internal/lru"
+	"github.com/google/uuid"
+)
+
+// Stats holds the hit and miss counters of the cache
+type Stats = lru.Stats
+
+// Repository wraps another customer.Repository and keeps recently read customers in memory.
+// Writes go straight to the wrapped repository and invalidate the affected entry.
+type Repository struct {
+	next      customer.Repository
+	customers *lru.Cache[uuid.UUID, customer.Customer]
+}
+
+// New wraps next with a cache holding at most size customers for ttl
+func New(next customer.Repository, size int, ttl time.Duration) *Repository {
+	return &Repository{
+		next:      next,
+		customers: lru.New[uuid.UUID, customer.Customer](size, ttl),
+	}
+}
+
+func (r *Repository) Get(id uuid.UUID) (customer.Customer, error) {
+	if c, ok := r.customers.Get(id); ok {
+		return c, nil
+	}
+
+	c, err := r.next.Get(id)
+	if err != nil {
+		return customer.Customer{}, err
+	}
+	r.customers.Add(id, c)
+	return c, nil
+}
+
+func (r *Repository) Add(c customer.Customer) error {
+	return r.next.Add(c)
+}
+
+func (r *Repository) Update(c customer.Customer) error {
+	defer r.customers.Remove(c.GetID())
+	return r.next.Update(c)
+}
+
+// List is not cached, pages depend on the whole collection and would have to be invalidated on every write
+func (r *Repository) List(query customer.ListQuery) (customer.Page, error) {
+	return r.next.List(query)
+}
+
+// Stats returns the cache counters
+func (r *Repository) Stats() Stats {
+	return r.customers.Stats()
+}
@@ -0,0 +1,41 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gegaryfa/tavern/domain/customer"
+	"github.com/gegaryfa/tavern/domain/customer/memory"
+)
+
+func TestRepository_Get(t *testing.T) {
+	percy, err := customer.NewCustomer("Percy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := New(memory.New(), 10, time.Minute)
+	if err := repo.Add(percy); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := repo.Get(percy.GetID()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if stats := repo.Stats(); stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("Expected 1 hit and 1 miss, got %+v", stats)
+	}
+
+	percy.SetName("George")
+	if err := repo.Update(percy); err != nil {
+		t.Fatal(err)
+	}
+	found, err := repo.Get(percy.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.GetName() != "George" {
+		t.Errorf("Expected the updated name George, got %v", found.GetName())
+	}
+}
@@ -3,6 +3,7 @@ package customer
 
 import (
 	"errors"
+	"time"
 
 	"github.com/gegaryfa/tavern"
 	"github.com/google/uuid"
@@ -11,8 +12,13 @@ import (
 var (
 	// ErrInvalidName is returned when the name is not valid in the NewCustomer factory
 	ErrInvalidName = errors.New("a customer has to have an valid name")
+	// ErrInvalidDateOfBirth is returned when the date of birth is in the future
+	ErrInvalidDateOfBirth = errors.New("the date of birth can not be in the future")
 )
 
+// CustomerConfiguration is an alias for a function that sets an optional property of a Customer
+type CustomerConfiguration func(c *Customer) error
+
 // Customer is an aggregate that combines all entities needed to represent a customer
 // Note: I set all the entities as pointers, this is because an entity can change state and I want that to reflect
 // across all instances of the runtime that has access to it. The value objects are held as nonpointers though since they cannot change state.
@@ -24,11 +30,15 @@ type Customer struct {
 	products []*tavern.Item
 	// a customer can perform many transactions
 	transactions []tavern.Transaction
+	// points is the ledger of the loyalty points of the customer, oldest first
+	points []PointsEntry
+	// revision counts the updates of the customer, a copy read before the last update can not be stored
+	revision int
 }
 
 // NewCustomer is a factory to create a new Customer aggregate
-// It will validate that the name is not empty
-func NewCustomer(name string) (Customer, error) {
+// It will validate that the name is not empty, the configurations set the optional properties
+func NewCustomer(name string, cfgs ...CustomerConfiguration) (Customer, error) {
 	if name == "" {
 		return Customer{}, ErrInvalidName
 	}
@@ -40,11 +50,30 @@ func NewCustomer(name string) (Customer, error) {
 	}
 
 	// Create a customer object and initialize all the values to avoid nil pointer exceptions
-	return Customer{
+	c := Customer{
 		person:       person,
 		products:     make([]*tavern.Item, 0),
 		transactions: make([]tavern.Transaction, 0),
-	}, nil
+		points:       make([]PointsEntry, 0),
+	}
+	for _, cfg := range cfgs {
+		if err := cfg(&c); err != nil {
+			return Customer{}, err
+		}
+	}
+	return c, nil
+}
+
+// WithDateOfBirth records the date of birth of the customer, a customer with a date of birth
+// has a verified age
+func WithDateOfBirth(dob time.Time) CustomerConfiguration {
+	return func(c *Customer) error {
+		if dob.After(time.Now()) {
+			return ErrInvalidDateOfBirth
+		}
+		c.SetDateOfBirth(dob)
+		return nil
+	}
 }
 
 func (c Customer) GetID() uuid.UUID {
@@ -68,3 +97,64 @@ func (c *Customer) SetName(name string) {
 func (c Customer) GetName() string {
 	return c.person.Name
 }
+
+func (c *Customer) SetAge(age int) {
+	if c.person == nil {
+		c.person = &tavern.Person{}
+	}
+	c.person.Age = age
+}
+
+// GetAge returns the current age of the customer, computed from the date of birth when it is known
+func (c Customer) GetAge() int {
+	if age, ok := c.AgeAt(time.Now()); ok {
+		return age
+	}
+	return c.person.Age
+}
+
+func (c *Customer) SetDateOfBirth(dob time.Time) {
+	if c.person == nil {
+		c.person = &tavern.Person{}
+	}
+	c.person.DateOfBirth = dob
+}
+
+// GetDateOfBirth returns the zero time when the date of birth is unknown
+func (c Customer) GetDateOfBirth() time.Time {
+	return c.person.DateOfBirth
+}
+
+func (c Customer) GetRevision() int {
+	return c.revision
+}
+
+func (c *Customer) SetRevision(revision int) {
+	c.revision = revision
+}
+
+// GetProducts returns the items the customer holds
+func (c Customer) GetProducts() []*tavern.Item {
+	return c.products
+}
+
+func (c *Customer) SetProducts(products []*tavern.Item) {
+	c.products = products
+}
+
+// GetTransactions returns the transactions the customer has performed
+func (c Customer) GetTransactions() []tavern.Transaction {
+	return c.transactions
+}
+
+func (c *Customer) SetTransactions(transactions []tavern.Transaction) {
+	c.transactions = transactions
+}
+
+// AddTransaction records a transaction the customer took part in
+func (c *Customer) AddTransaction(t tavern.Transaction) {
+	// Copy on write so that copies of the customer handed out earlier are not changed
+	transactions := make([]tavern.Transaction, len(c.transactions), len(c.transactions)+1)
+	copy(transactions, c.transactions)
+	c.transactions = append(transactions, t)
+}
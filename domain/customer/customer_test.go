@@ -2,6 +2,9 @@ package customer
 
 import (
 	"testing"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 func TestNewCustomer(t *testing.T) {
@@ -37,3 +40,95 @@ func TestNewCustomer(t *testing.T) {
 		})
 	}
 }
+
+func TestCustomer_CheckLegalAge(t *testing.T) {
+	now := time.Date(2022, 11, 1, 18, 0, 0, 0, time.UTC)
+
+	type testCase struct {
+		test        string
+		cfgs        []CustomerConfiguration
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{
+			test:        "Unverified",
+			expectedErr: ErrAgeNotVerified,
+		}, {
+			test:        "Underage",
+			cfgs:        []CustomerConfiguration{WithDateOfBirth(time.Date(2004, 11, 2, 0, 0, 0, 0, time.UTC))},
+			expectedErr: ErrUnderage,
+		}, {
+			test:        "Birthday today",
+			cfgs:        []CustomerConfiguration{WithDateOfBirth(time.Date(2004, 11, 1, 0, 0, 0, 0, time.UTC))},
+			expectedErr: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			c, err := NewCustomer("Percy", tc.cfgs...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := c.CheckLegalAge(18, now); err != tc.expectedErr {
+				t.Errorf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+
+	if _, err := NewCustomer("Percy", WithDateOfBirth(time.Now().Add(24*time.Hour))); err != ErrInvalidDateOfBirth {
+		t.Errorf("Expected error %v, got %v", ErrInvalidDateOfBirth, err)
+	}
+}
+
+// racingRepository holds one customer and changes it behind the back of the caller on the first updates
+type racingRepository struct {
+	Repository
+	stored Customer
+	races  int
+}
+
+func (r *racingRepository) Get(uuid.UUID) (Customer, error) {
+	return r.stored, nil
+}
+
+func (r *racingRepository) Update(c Customer) error {
+	if r.races > 0 {
+		r.races--
+		if err := r.stored.TopUp(1, time.Now()); err != nil {
+			return err
+		}
+		r.stored.SetRevision(r.stored.GetRevision() + 1)
+	}
+	if c.GetRevision() != r.stored.GetRevision() {
+		return ErrCustomerChanged
+	}
+	c.SetRevision(c.GetRevision() + 1)
+	r.stored = c
+	return nil
+}
+
+func TestChange(t *testing.T) {
+	c, err := NewCustomer("Percy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	topUp := func(c *Customer) error { return c.TopUp(2, time.Now()) }
+
+	// The change is made again on the customer topped up concurrently, nothing is lost
+	repo := &racingRepository{stored: c, races: 2}
+	changed, err := Change(repo, c.GetID(), topUp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed.Balance() != 4 || changed.GetRevision() != 3 || repo.stored.Balance() != 4 {
+		t.Errorf("Expected balance 4 at revision 3, got %v at %d", changed.Balance(), changed.GetRevision())
+	}
+
+	// It gives up when the customer keeps changing
+	repo = &racingRepository{stored: c, races: changeAttempts}
+	if _, err := Change(repo, c.GetID(), topUp); err != ErrCustomerChanged {
+		t.Errorf("Expected error %v, got %v", ErrCustomerChanged, err)
+	}
+}
@@ -0,0 +1,91 @@
+package customer
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// DefaultListLimit is the page size used when a ListQuery has no limit
+const DefaultListLimit = 50
+
+// ErrInvalidCursor is returned when a ListQuery holds a cursor that was not returned by the repository
+var ErrInvalidCursor = errors.New("the list cursor is not valid")
+
+// SortOrder is the order customers are listed in, customers are always sorted by name
+type SortOrder int
+
+const (
+	// SortByNameAsc lists customers from A to Z
+	SortByNameAsc SortOrder = iota
+	// SortByNameDesc lists customers from Z to A
+	SortByNameDesc
+)
+
+// ListQuery describes which customers to list and how
+type ListQuery struct {
+	// NamePrefix only keeps customers whose name starts with it, case insensitive
+	NamePrefix string
+	// NameContains only keeps customers whose name contains it, case insensitive
+	NameContains string
+	// Sort is the order of the results
+	Sort SortOrder
+	// Limit is the maximum amount of customers in a page, DefaultListLimit is used when it is not set
+	Limit int
+	// Cursor is the NextCursor of the previous page, empty for the first page
+	Cursor string
+}
+
+// Page is a single page of customers
+type Page struct {
+	Customers []Customer
+	// NextCursor is passed in the next ListQuery to get the following page, it is empty on the last page
+	NextCursor string
+}
+
+// GetLimit returns the page size of the query
+func (q ListQuery) GetLimit() int {
+	if q.Limit <= 0 {
+		return DefaultListLimit
+	}
+	return q.Limit
+}
+
+// Matches reports whether the customer passes the name filters of the query
+func (q ListQuery) Matches(c Customer) bool {
+	name := strings.ToLower(c.GetName())
+	if q.NamePrefix != "" && !strings.HasPrefix(name, strings.ToLower(q.NamePrefix)) {
+		return false
+	}
+	if q.NameContains != "" && !strings.Contains(name, strings.ToLower(q.NameContains)) {
+		return false
+	}
+	return true
+}
+
+// Cursor is the position of the last customer of a page.
+// ID is whatever tie breaker the repository sorts customers with the same name by.
+type Cursor struct {
+	Name string `json:"n"`
+	ID   string `json:"i"`
+}
+
+// Encode returns the opaque representation handed out in Page.NextCursor
+func (c Cursor) Encode() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// ParseCursor decodes a cursor created by Cursor.Encode
+func ParseCursor(s string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
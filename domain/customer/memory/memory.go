@@ -2,6 +2,7 @@ package memory
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/gegaryfa/tavern/domain/customer"
@@ -17,32 +18,93 @@ func New() *repository {
 	return &repository{customers: make(map[uuid.UUID]customer.Customer)}
 }
 
-func (r repository) Get(uuid uuid.UUID) (customer.Customer, error) {
+func (r *repository) Get(uuid uuid.UUID) (customer.Customer, error) {
+	r.Lock()
+	defer r.Unlock()
+
 	if customer, ok := r.customers[uuid]; ok {
 		return customer, nil
 	}
 	return customer.Customer{}, customer.ErrCustomerNotFound
 }
 
-func (r repository) Add(c customer.Customer) error {
+func (r *repository) Add(c customer.Customer) error {
+	r.Lock()
+	defer r.Unlock()
+
 	if _, ok := r.customers[c.GetID()]; ok {
 		return fmt.Errorf("customer already exists: %w", customer.ErrFailedToAddCustomer)
 	}
-
-	r.Mutex.Lock()
 	r.customers[c.GetID()] = c
-	r.Mutex.Unlock()
 
 	return nil
 }
 
-func (r repository) Update(c customer.Customer) error {
+func (r *repository) Update(c customer.Customer) error {
+	r.Lock()
+	defer r.Unlock()
+
 	// Make sure Customer is in the repository
-	if _, ok := r.customers[c.GetID()]; !ok {
+	stored, ok := r.customers[c.GetID()]
+	if !ok {
 		return customer.ErrCustomerNotFound
 	}
-	r.Lock()
+	if stored.GetRevision() != c.GetRevision() {
+		return customer.ErrCustomerChanged
+	}
+	c.SetRevision(c.GetRevision() + 1)
 	r.customers[c.GetID()] = c
-	r.Unlock()
 	return nil
 }
+
+// List filters and sorts all the customers, the id is used as tie breaker between equal names
+func (r *repository) List(query customer.ListQuery) (customer.Page, error) {
+	var after *customer.Cursor
+	if query.Cursor != "" {
+		cursor, err := customer.ParseCursor(query.Cursor)
+		if err != nil {
+			return customer.Page{}, err
+		}
+		after = &cursor
+	}
+
+	r.Lock()
+	matches := make([]customer.Customer, 0)
+	for _, c := range r.customers {
+		if query.Matches(c) {
+			matches = append(matches, c)
+		}
+	}
+	r.Unlock()
+
+	less := func(a, b customer.Cursor) bool {
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.ID < b.ID
+	}
+	if query.Sort == customer.SortByNameDesc {
+		asc := less
+		less = func(a, b customer.Cursor) bool { return asc(b, a) }
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return less(cursorOf(matches[i]), cursorOf(matches[j]))
+	})
+
+	page := customer.Page{Customers: make([]customer.Customer, 0)}
+	for _, c := range matches {
+		if after != nil && !less(*after, cursorOf(c)) {
+			continue
+		}
+		if len(page.Customers) == query.GetLimit() {
+			page.NextCursor = cursorOf(page.Customers[len(page.Customers)-1]).Encode()
+			break
+		}
+		page.Customers = append(page.Customers, c)
+	}
+	return page, nil
+}
+
+func cursorOf(c customer.Customer) customer.Cursor {
+	return customer.Cursor{Name: c.GetName(), ID: c.GetID().String()}
+}
@@ -126,6 +126,11 @@ func Test_Update(t *testing.T) {
 			id:          id,
 			newName:     "George",
 			expectedErr: nil,
+		}, {
+			name:        "Customer changed since it was read",
+			id:          id,
+			newName:     "Anna",
+			expectedErr: customer.ErrCustomerChanged,
 		},
 	}
 
@@ -146,3 +151,116 @@ func Test_Update(t *testing.T) {
 		})
 	}
 }
+
+func Test_List(t *testing.T) {
+	repo := New()
+	for _, name := range []string{"Percy", "George", "Peter", "Anna"} {
+		c, err := customer.NewCustomer(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.Add(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	type testCase struct {
+		name     string
+		query    customer.ListQuery
+		expected []string
+	}
+
+	testCases := []testCase{
+		{
+			name:     "All customers sorted by name",
+			query:    customer.ListQuery{},
+			expected: []string{"Anna", "George", "Percy", "Peter"},
+		}, {
+			name:     "Name prefix is case insensitive",
+			query:    customer.ListQuery{NamePrefix: "pe"},
+			expected: []string{"Percy", "Peter"},
+		}, {
+			name:     "Name substring",
+			query:    customer.ListQuery{NameContains: "OR"},
+			expected: []string{"George"},
+		}, {
+			name:     "Descending",
+			query:    customer.ListQuery{Sort: customer.SortByNameDesc},
+			expected: []string{"Peter", "Percy", "George", "Anna"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			page, err := repo.List(tc.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := names(page.Customers); !equal(got, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, got)
+			}
+			if page.NextCursor != "" {
+				t.Errorf("Expected no next page, got cursor %q", page.NextCursor)
+			}
+		})
+	}
+}
+
+func Test_ListPagination(t *testing.T) {
+	repo := New()
+	for _, name := range []string{"Percy", "George", "Peter", "Anna", "Percy"} {
+		c, err := customer.NewCustomer(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.Add(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got []string
+	query := customer.ListQuery{Limit: 2}
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatalf("Expected 3 pages, kept on paginating")
+		}
+		page, err := repo.List(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, names(page.Customers)...)
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+
+	expected := []string{"Anna", "George", "Percy", "Percy", "Peter"}
+	if !equal(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+
+	if _, err := repo.List(customer.ListQuery{Cursor: "not a cursor"}); err != customer.ErrInvalidCursor {
+		t.Errorf("Expected error %v, got %v", customer.ErrInvalidCursor, err)
+	}
+}
+
+func names(customers []customer.Customer) []string {
+	names := make([]string, 0, len(customers))
+	for _, c := range customers {
+		names = append(names, c.GetName())
+	}
+	return names
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -2,11 +2,16 @@ package mongo
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"regexp"
 	"time"
 
+	"github.com/gegaryfa/tavern"
 	"github.com/gegaryfa/tavern/domain/customer"
 	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -16,31 +21,149 @@ type Repository struct {
 	customers *mongo.Collection
 }
 
+// currentSchemaVersion is the version written by NewFromCustomer.
+// Version 1 documents (written before the version field existed) only hold the id and the name.
+// Version 2 documents have no date of birth, their customers are unverified.
+// Version 3 documents have no loyalty points.
+const currentSchemaVersion = 4
+
+// ErrUnsupportedSchemaVersion is returned when a stored document is newer than this code understands
+var ErrUnsupportedSchemaVersion = errors.New("unsupported customer document schema version")
+
 // mongoCustomer is an internal type that is used to store a CustomerAggregate
 // we make an internal struct for this to avoid coupling this mongo implementation to the customers aggregate.
 // Mongo uses bson so we add tags for that
 type mongoCustomer struct {
-	ID   uuid.UUID `bson:"id"`
-	Name string    `bson:"name"`
+	SchemaVersion int                `bson:"schema_version"`
+	ID            uuid.UUID          `bson:"id"`
+	Name          string             `bson:"name"`
+	Age           int                `bson:"age"`
+	DateOfBirth   time.Time          `bson:"date_of_birth,omitempty"`
+	Products      []mongoItem        `bson:"products"`
+	Transactions  []mongoTransaction `bson:"transactions"`
+	Points        []mongoPointsEntry `bson:"points,omitempty"`
+	// Revision counts the updates of the customer, documents written before it existed are at revision 0
+	Revision int `bson:"revision"`
+}
+
+// mongoItem is the embedded representation of a tavern.Item held by the customer
+type mongoItem struct {
+	ID          uuid.UUID `bson:"id"`
+	Name        string    `bson:"name"`
+	Description string    `bson:"description"`
+}
+
+// mongoTransaction is the embedded representation of a tavern.Transaction
+type mongoTransaction struct {
+	Amount    int       `bson:"amount"`
+	From      uuid.UUID `bson:"from"`
+	To        uuid.UUID `bson:"to"`
+	CreatedAt time.Time `bson:"created_at"`
+	Reference string    `bson:"reference,omitempty"`
+	Kind      string    `bson:"kind,omitempty"`
+}
+
+// mongoPointsEntry is the embedded representation of a customer.PointsEntry
+type mongoPointsEntry struct {
+	Kind      string    `bson:"kind"`
+	Points    int       `bson:"points"`
+	Reference string    `bson:"reference,omitempty"`
+	At        time.Time `bson:"at"`
+	ExpiresAt time.Time `bson:"expires_at,omitempty"`
 }
 
 func NewFromCustomer(c customer.Customer) mongoCustomer {
+	products := make([]mongoItem, 0, len(c.GetProducts()))
+	for _, item := range c.GetProducts() {
+		products = append(products, mongoItem{
+			ID:          item.ID,
+			Name:        item.Name,
+			Description: item.Description,
+		})
+	}
+
+	transactions := make([]mongoTransaction, 0, len(c.GetTransactions()))
+	for _, t := range c.GetTransactions() {
+		transactions = append(transactions, mongoTransaction{
+			Amount:    t.GetAmount(),
+			From:      t.GetFrom(),
+			To:        t.GetTo(),
+			CreatedAt: t.GetCreatedAt(),
+			Reference: t.GetReference(),
+			Kind:      string(t.GetKind()),
+		})
+	}
+
+	points := make([]mongoPointsEntry, 0, len(c.GetPoints()))
+	for _, e := range c.GetPoints() {
+		points = append(points, mongoPointsEntry{
+			Kind:      string(e.Kind),
+			Points:    e.Points,
+			Reference: e.Reference,
+			At:        e.At,
+			ExpiresAt: e.ExpiresAt,
+		})
+	}
+
 	return mongoCustomer{
-		ID:   c.GetID(),
-		Name: c.GetName(),
+		SchemaVersion: currentSchemaVersion,
+		ID:            c.GetID(),
+		Name:          c.GetName(),
+		Age:           c.GetAge(),
+		DateOfBirth:   c.GetDateOfBirth(),
+		Products:      products,
+		Transactions:  transactions,
+		Points:        points,
+		Revision:      c.GetRevision(),
 	}
 }
 
 // ToAggregate converts into a aggregate.Customer
-// this could validate all values present etc
-func (m mongoCustomer) ToAggregate() customer.Customer {
+// Older documents are upgraded on the fly, fields they do not have are left at their zero values
+func (m mongoCustomer) ToAggregate() (customer.Customer, error) {
+	if m.SchemaVersion > currentSchemaVersion {
+		return customer.Customer{}, fmt.Errorf("%w: %d", ErrUnsupportedSchemaVersion, m.SchemaVersion)
+	}
+
 	c := customer.Customer{}
 
 	c.SetID(m.ID)
 	c.SetName(m.Name)
+	c.SetAge(m.Age)
+	c.SetDateOfBirth(m.DateOfBirth)
+	c.SetRevision(m.Revision)
+
+	products := make([]*tavern.Item, 0, len(m.Products))
+	for _, item := range m.Products {
+		products = append(products, &tavern.Item{
+			ID:          item.ID,
+			Name:        item.Name,
+			Description: item.Description,
+		})
+	}
+	c.SetProducts(products)
 
-	return c
+	transactions := make([]tavern.Transaction, 0, len(m.Transactions))
+	for _, t := range m.Transactions {
+		transactions = append(transactions, tavern.NewTransaction(t.Amount, t.From, t.To, t.CreatedAt).
+			WithReference(t.Reference).
+			WithKind(tavern.TransactionKind(t.Kind)))
+	}
+	c.SetTransactions(transactions)
+
+	points := make([]customer.PointsEntry, 0, len(m.Points))
+	for _, e := range m.Points {
+		points = append(points, customer.PointsEntry{
+			Kind:      customer.PointsKind(e.Kind),
+			Points:    e.Points,
+			Reference: e.Reference,
+			At:        e.At,
+			ExpiresAt: e.ExpiresAt,
+		})
+	}
+	c.SetPoints(points)
 
+	return c, nil
 }
 
 // New creates a new mongodb repository
@@ -54,6 +177,19 @@ func New(ctx context.Context, connectionString string) (*Repository, error) {
 	db := client.Database("ddd")
 	customers := db.Collection("customers")
 
+	// id is the identity of the aggregate, name and id back the sorted and paginated listing
+	_, err = customers.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		}, {
+			Keys: bson.D{{Key: "name", Value: 1}, {Key: "id", Value: 1}},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	return &Repository{
 		db:        db,
 		customers: customers,
@@ -68,11 +204,14 @@ func (r *Repository) Get(id uuid.UUID) (customer.Customer, error) {
 
 	var c mongoCustomer
 	err := result.Decode(&c)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return customer.Customer{}, customer.ErrCustomerNotFound
+	}
 	if err != nil {
 		return customer.Customer{}, err
 	}
 	// Convert to aggregate
-	return c.ToAggregate(), nil
+	return c.ToAggregate()
 }
 
 func (r *Repository) Add(c customer.Customer) error {
@@ -81,6 +220,9 @@ func (r *Repository) Add(c customer.Customer) error {
 
 	internal := NewFromCustomer(c)
 	_, err := r.customers.InsertOne(ctx, internal)
+	if mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("customer already exists: %w", customer.ErrFailedToAddCustomer)
+	}
 	if err != nil {
 		return err
 	}
@@ -88,5 +230,98 @@ func (r *Repository) Add(c customer.Customer) error {
 }
 
 func (r *Repository) Update(c customer.Customer) error {
-	panic("to implement")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Documents written before the revision existed have no revision field, they are at revision 0
+	var revision interface{} = c.GetRevision()
+	if c.GetRevision() == 0 {
+		revision = bson.M{"$in": bson.A{0, nil}}
+	}
+	next := NewFromCustomer(c)
+	next.Revision++
+	result, err := r.customers.ReplaceOne(ctx, bson.M{"id": c.GetID(), "revision": revision}, next)
+	if err != nil {
+		return fmt.Errorf("%v: %w", err, customer.ErrUpdateCustomer)
+	}
+	if result.MatchedCount == 0 {
+		// Nothing matched either because the customer does not exist or because it has another revision
+		count, err := r.customers.CountDocuments(ctx, bson.M{"id": c.GetID()})
+		if err != nil {
+			return fmt.Errorf("%v: %w", err, customer.ErrUpdateCustomer)
+		}
+		if count == 0 {
+			return customer.ErrCustomerNotFound
+		}
+		return customer.ErrCustomerChanged
+	}
+	return nil
+}
+
+// List uses keyset pagination on (name, id) so that pages stay stable while customers are added
+func (r *Repository) List(query customer.ListQuery) (customer.Page, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filters := bson.A{}
+	if query.NamePrefix != "" {
+		filters = append(filters, bson.M{"name": primitive.Regex{Pattern: "^" + regexp.QuoteMeta(query.NamePrefix), Options: "i"}})
+	}
+	if query.NameContains != "" {
+		filters = append(filters, bson.M{"name": primitive.Regex{Pattern: regexp.QuoteMeta(query.NameContains), Options: "i"}})
+	}
+
+	direction, after := 1, "$gt"
+	if query.Sort == customer.SortByNameDesc {
+		direction, after = -1, "$lt"
+	}
+	if query.Cursor != "" {
+		cursor, err := customer.ParseCursor(query.Cursor)
+		if err != nil {
+			return customer.Page{}, err
+		}
+		id, err := uuid.Parse(cursor.ID)
+		if err != nil {
+			return customer.Page{}, customer.ErrInvalidCursor
+		}
+		filters = append(filters, bson.M{"$or": bson.A{
+			bson.M{"name": bson.M{after: cursor.Name}},
+			bson.M{"name": cursor.Name, "id": bson.M{after: id}},
+		}})
+	}
+
+	filter := bson.M{}
+	if len(filters) > 0 {
+		filter = bson.M{"$and": filters}
+	}
+
+	limit := query.GetLimit()
+	opts := options.Find().
+		SetSort(bson.D{{Key: "name", Value: direction}, {Key: "id", Value: direction}}).
+		// Fetch one more than needed to know if there is a next page
+		SetLimit(int64(limit + 1))
+
+	result, err := r.customers.Find(ctx, filter, opts)
+	if err != nil {
+		return customer.Page{}, err
+	}
+	var internal []mongoCustomer
+	if err := result.All(ctx, &internal); err != nil {
+		return customer.Page{}, err
+	}
+
+	page := customer.Page{Customers: make([]customer.Customer, 0, len(internal))}
+	for i, m := range internal {
+		if i == limit {
+			last := internal[i-1]
+			page.NextCursor = customer.Cursor{Name: last.Name, ID: last.ID.String()}.Encode()
+			break
+		}
+		c, err := m.ToAggregate()
+		if err != nil {
+			return customer.Page{}, err
+		}
+		page.Customers = append(page.Customers, c)
+	}
+	return page, nil
 }
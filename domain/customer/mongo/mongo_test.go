@@ -0,0 +1,119 @@
+package mongo
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gegaryfa/tavern"
+	"github.com/gegaryfa/tavern/domain/customer"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestMongoCustomer_RoundTrip(t *testing.T) {
+	c, err := customer.NewCustomer("Percy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetAge(42)
+	c.SetDateOfBirth(time.Date(1980, 5, 17, 0, 0, 0, 0, time.UTC))
+	c.SetProducts([]*tavern.Item{{ID: uuid.New(), Name: "Beer", Description: "Healthy Beverage"}})
+	createdAt := time.Date(2022, 11, 1, 20, 0, 0, 0, time.UTC)
+	c.SetTransactions([]tavern.Transaction{tavern.NewTransaction(199, c.GetID(), uuid.New(), createdAt).WithReference("order").WithKind(tavern.TransactionTip)})
+	if err := c.EarnPoints(120, "order", createdAt, createdAt.AddDate(1, 0, 0)); err != nil {
+		t.Fatal(err)
+	}
+	// Go through bson as well to make sure the tags round-trip
+	raw, err := bson.Marshal(NewFromCustomer(c))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stored mongoCustomer
+	if err := bson.Unmarshal(raw, &stored); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := stored.ToAggregate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.GetID() != c.GetID() || got.GetName() != c.GetName() || got.GetAge() != c.GetAge() {
+		t.Errorf("Expected person %v %v %v, got %v %v %v", c.GetID(), c.GetName(), c.GetAge(), got.GetID(), got.GetName(), got.GetAge())
+	}
+	if !got.GetDateOfBirth().Equal(c.GetDateOfBirth()) {
+		t.Errorf("Expected date of birth %v, got %v", c.GetDateOfBirth(), got.GetDateOfBirth())
+	}
+	if len(got.GetProducts()) != 1 || *got.GetProducts()[0] != *c.GetProducts()[0] {
+		t.Errorf("Expected products %v, got %v", c.GetProducts(), got.GetProducts())
+	}
+	if len(got.GetTransactions()) != 1 {
+		t.Fatalf("Expected 1 transaction, got %d", len(got.GetTransactions()))
+	}
+	tr := got.GetTransactions()[0]
+	if tr.GetAmount() != 199 || tr.GetFrom() != c.GetID() || !tr.GetCreatedAt().Equal(createdAt) || tr.GetReference() != "order" || tr.GetKind() != tavern.TransactionTip {
+		t.Errorf("Expected transaction %v, got %v", c.GetTransactions()[0], tr)
+	}
+	if len(got.GetPoints()) != 1 {
+		t.Fatalf("Expected 1 points entry, got %d", len(got.GetPoints()))
+	}
+	e := got.GetPoints()[0]
+	if e.Kind != customer.PointsEarned || e.Points != 120 || e.Reference != "order" || !e.At.Equal(createdAt) || !e.ExpiresAt.Equal(createdAt.AddDate(1, 0, 0)) {
+		t.Errorf("Expected points entry %v, got %v", c.GetPoints()[0], e)
+	}
+}
+
+func TestMongoCustomer_SchemaVersions(t *testing.T) {
+	id := uuid.New()
+
+	type testCase struct {
+		name        string
+		document    interface{}
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{
+			name: "Legacy document without version",
+			document: struct {
+				ID   uuid.UUID `bson:"id"`
+				Name string    `bson:"name"`
+			}{ID: id, Name: "Percy"},
+			expectedErr: nil,
+		}, {
+			name: "Document from the future",
+			document: struct {
+				SchemaVersion int       `bson:"schema_version"`
+				ID            uuid.UUID `bson:"id"`
+			}{SchemaVersion: currentSchemaVersion + 1, ID: id},
+			expectedErr: ErrUnsupportedSchemaVersion,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			raw, err := bson.Marshal(tc.document)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var stored mongoCustomer
+			if err := bson.Unmarshal(raw, &stored); err != nil {
+				t.Fatal(err)
+			}
+
+			c, err := stored.ToAggregate()
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if c.GetID() != id {
+				t.Errorf("Expected id %v, got %v", id, c.GetID())
+			}
+			if c.GetProducts() == nil || c.GetTransactions() == nil {
+				t.Errorf("Expected initialized slices for a legacy document")
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package customer
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+var (
+	// ErrInvalidPoints is returned when earning or redeeming zero or negative points
+	ErrInvalidPoints = errors.New("the points have to be positive")
+	// ErrInsufficientPoints is returned when redeeming more points than the customer holds
+	ErrInsufficientPoints = errors.New("the customer does not hold enough points")
+)
+
+// PointsKind tells why the loyalty points of a customer changed
+type PointsKind string
+
+const (
+	// PointsEarned are granted for a paid order
+	PointsEarned PointsKind = "earned"
+	// PointsRedeemed are spent on products
+	PointsRedeemed PointsKind = "redeemed"
+	// PointsExpired were not redeemed before their expiry
+	PointsExpired PointsKind = "expired"
+	// PointsReturned were redeemed on something that was called off, they can be redeemed again
+	PointsReturned PointsKind = "returned"
+	// PointsReversed were earned on something that was called off, they are taken back
+	PointsReversed PointsKind = "reversed"
+)
+
+// PointsEntry is a value object holding a change of the loyalty points of a customer
+type PointsEntry struct {
+	Kind PointsKind
+	// Points is positive when earned or returned and negative when redeemed, expired or reversed
+	Points int
+	// Reference describes what the points were earned or redeemed for
+	Reference string
+	At        time.Time
+	// ExpiresAt is when earned points expire, the zero time never expires
+	ExpiresAt time.Time
+}
+
+// grant is what is left of earned points while replaying the ledger
+type grant struct {
+	points    int
+	expiresAt time.Time
+}
+
+func (g grant) expiredAt(at time.Time) bool {
+	return !g.expiresAt.IsZero() && !at.Before(g.expiresAt)
+}
+
+// GetPoints returns the points ledger of the customer, oldest first
+func (c Customer) GetPoints() []PointsEntry {
+	return c.points
+}
+
+// HasPoints reports whether the ledger holds an entry of the kind for the reference, such as points already
+// reversed for a cancelled order
+func (c Customer) HasPoints(kind PointsKind, reference string) bool {
+	for _, e := range c.points {
+		if e.Kind == kind && e.Reference == reference {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Customer) SetPoints(points []PointsEntry) {
+	c.points = points
+}
+
+// grants replays the ledger and returns what is left of every earned entry. Redeemed points are taken
+// from the grants expiring first, expired entries remove every grant that expired by then.
+func (c Customer) grants() []grant {
+	var grants []grant
+	for _, e := range c.points {
+		switch e.Kind {
+		case PointsEarned, PointsReturned:
+			grants = append(grants, grant{points: e.Points, expiresAt: e.ExpiresAt})
+		case PointsExpired:
+			for i := range grants {
+				if grants[i].expiredAt(e.At) {
+					grants[i].points = 0
+				}
+			}
+		case PointsRedeemed, PointsReversed:
+			consume(grants, -e.Points, e.At)
+		}
+	}
+	return grants
+}
+
+// consume takes points from the grants that are still valid at the given instant, the ones expiring first go first
+func consume(grants []grant, points int, at time.Time) {
+	order := make([]int, 0, len(grants))
+	for i, g := range grants {
+		if g.points > 0 && !g.expiredAt(at) {
+			order = append(order, i)
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		ea, eb := grants[order[a]].expiresAt, grants[order[b]].expiresAt
+		if ea.IsZero() || eb.IsZero() {
+			return !ea.IsZero() && eb.IsZero()
+		}
+		return ea.Before(eb)
+	})
+	for _, i := range order {
+		if points == 0 {
+			return
+		}
+		taken := grants[i].points
+		if taken > points {
+			taken = points
+		}
+		grants[i].points -= taken
+		points -= taken
+	}
+}
+
+// PointsBalance returns the points the customer can redeem at the given instant
+func (c Customer) PointsBalance(at time.Time) int {
+	var balance int
+	for _, g := range c.grants() {
+		if !g.expiredAt(at) {
+			balance += g.points
+		}
+	}
+	return balance
+}
+
+// LifetimePoints returns all the points the customer ever earned and kept, it decides the loyalty tier
+func (c Customer) LifetimePoints() int {
+	var points int
+	for _, e := range c.points {
+		if e.Kind == PointsEarned || e.Kind == PointsReversed {
+			points += e.Points
+		}
+	}
+	return points
+}
+
+// EarnPoints grants points to the customer, they can be redeemed until expiresAt unless it is the zero time
+func (c *Customer) EarnPoints(points int, reference string, at, expiresAt time.Time) error {
+	if points <= 0 {
+		return ErrInvalidPoints
+	}
+	c.addPoints(PointsEntry{Kind: PointsEarned, Points: points, Reference: reference, At: at, ExpiresAt: expiresAt})
+	return nil
+}
+
+// RedeemPoints spends points of the customer, the points expiring first are spent first
+func (c *Customer) RedeemPoints(points int, reference string, at time.Time) error {
+	if points <= 0 {
+		return ErrInvalidPoints
+	}
+	c.ExpirePoints(at)
+	if points > c.PointsBalance(at) {
+		return ErrInsufficientPoints
+	}
+	c.addPoints(PointsEntry{Kind: PointsRedeemed, Points: -points, Reference: reference, At: at})
+	return nil
+}
+
+// ReturnPoints gives back points that were redeemed on something that was called off, they can be redeemed
+// until expiresAt unless it is the zero time. Returned points do not count towards the lifetime points.
+func (c *Customer) ReturnPoints(points int, reference string, at, expiresAt time.Time) error {
+	if points <= 0 {
+		return ErrInvalidPoints
+	}
+	c.addPoints(PointsEntry{Kind: PointsReturned, Points: points, Reference: reference, At: at, ExpiresAt: expiresAt})
+	return nil
+}
+
+// ReversePoints takes back points earned on something that was called off. Points the customer already spent
+// can not be taken back, they still no longer count towards the lifetime points.
+func (c *Customer) ReversePoints(points int, reference string, at time.Time) error {
+	if points <= 0 {
+		return ErrInvalidPoints
+	}
+	c.addPoints(PointsEntry{Kind: PointsReversed, Points: -points, Reference: reference, At: at})
+	return nil
+}
+
+// ExpirePoints records the expiry of the points that were not redeemed in time and returns how many expired
+func (c *Customer) ExpirePoints(at time.Time) int {
+	var expired int
+	for _, g := range c.grants() {
+		if g.expiredAt(at) {
+			expired += g.points
+		}
+	}
+	if expired > 0 {
+		c.addPoints(PointsEntry{Kind: PointsExpired, Points: -expired, At: at})
+	}
+	return expired
+}
+
+func (c *Customer) addPoints(e PointsEntry) {
+	// Copy on write so that copies of the customer handed out earlier are not changed
+	points := make([]PointsEntry, len(c.points), len(c.points)+1)
+	copy(points, c.points)
+	c.points = append(points, e)
+}
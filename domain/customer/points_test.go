@@ -0,0 +1,99 @@
+package customer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCustomer_Points(t *testing.T) {
+	jan := time.Date(2022, 1, 10, 18, 0, 0, 0, time.UTC)
+	mar := time.Date(2022, 3, 10, 18, 0, 0, 0, time.UTC)
+	c, err := NewCustomer("Percy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type testCase struct {
+		test            string
+		operation       func() error
+		at              time.Time
+		expectedErr     error
+		expectedBalance int
+	}
+
+	testCases := []testCase{
+		{
+			test:            "Earn points expiring in June",
+			operation:       func() error { return c.EarnPoints(100, "order 1", jan, jan.AddDate(0, 5, 0)) },
+			at:              jan,
+			expectedBalance: 100,
+		}, {
+			test:            "Earn points expiring in March",
+			operation:       func() error { return c.EarnPoints(50, "order 2", jan, mar) },
+			at:              jan,
+			expectedBalance: 150,
+		}, {
+			test:            "Earn nothing",
+			operation:       func() error { return c.EarnPoints(0, "order 3", jan, mar) },
+			at:              jan,
+			expectedErr:     ErrInvalidPoints,
+			expectedBalance: 150,
+		}, {
+			test:            "Redeem the points expiring first",
+			operation:       func() error { return c.RedeemPoints(30, "reward", jan) },
+			at:              jan,
+			expectedBalance: 120,
+		}, {
+			test:            "Unredeemed points expire",
+			operation:       func() error { return nil },
+			at:              mar,
+			expectedBalance: 100,
+		}, {
+			test:            "Redeem more than the balance",
+			operation:       func() error { return c.RedeemPoints(101, "reward", mar) },
+			at:              mar,
+			expectedErr:     ErrInsufficientPoints,
+			expectedBalance: 100,
+		}, {
+			test:            "Redeem the whole balance",
+			operation:       func() error { return c.RedeemPoints(100, "reward", mar) },
+			at:              mar,
+			expectedBalance: 0,
+		}, {
+			test:            "Return the points of a called off reward",
+			operation:       func() error { return c.ReturnPoints(40, "reward", mar, time.Time{}) },
+			at:              mar,
+			expectedBalance: 40,
+		}, {
+			test:            "Reverse the points of a cancelled order",
+			operation:       func() error { return c.ReversePoints(50, "order 2", mar) },
+			at:              mar,
+			expectedBalance: 0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			if err := tc.operation(); err != tc.expectedErr {
+				t.Errorf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+			if got := c.PointsBalance(tc.at); got != tc.expectedBalance {
+				t.Errorf("Expected balance %d, got %d", tc.expectedBalance, got)
+			}
+		})
+	}
+
+	if got := c.LifetimePoints(); got != 100 {
+		t.Errorf("Expected 100 lifetime points, got %d", got)
+	}
+	// The 20 points left of the March grant expired when redeeming in March
+	var expired int
+	for _, e := range c.GetPoints() {
+		if e.Kind == PointsExpired {
+			expired -= e.Points
+		}
+	}
+	if expired != 20 {
+		t.Errorf("Expected 20 expired points, got %d", expired)
+	}
+}
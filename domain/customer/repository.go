@@ -14,6 +14,8 @@ var (
 	ErrFailedToAddCustomer = errors.New("failed to add the customer to the repository")
 	// ErrUpdateCustomer is returned when the customer could not be updated in the repository.
 	ErrUpdateCustomer = errors.New("failed to update the customer in the repository")
+	// ErrCustomerChanged is returned when updating a customer that was updated since it was read.
+	ErrCustomerChanged = errors.New("the customer was changed since it was read")
 )
 
 // Repository is an interface that defines the rules around what a customer repository
@@ -21,5 +23,35 @@ var (
 type Repository interface {
 	Get(uuid.UUID) (Customer, error)
 	Add(Customer) error
+	// Update stores the customer and bumps its revision, it fails with ErrCustomerChanged when the stored
+	// customer has another revision than the one given
 	Update(Customer) error
+	// List returns a page of customers matching the query
+	List(ListQuery) (Page, error)
+}
+
+// changeAttempts is how many times Change reads the customer when it keeps being changed concurrently
+const changeAttempts = 5
+
+// Change reads the customer, applies change to it and stores it. When the customer was changed concurrently
+// change is applied again to a fresh copy, so that concurrent changes are never lost.
+func Change(r Repository, id uuid.UUID, change func(c *Customer) error) (Customer, error) {
+	for attempt := 1; ; attempt++ {
+		c, err := r.Get(id)
+		if err != nil {
+			return Customer{}, err
+		}
+		if err := change(&c); err != nil {
+			return Customer{}, err
+		}
+		err = r.Update(c)
+		if errors.Is(err, ErrCustomerChanged) && attempt < changeAttempts {
+			continue
+		}
+		if err != nil {
+			return Customer{}, err
+		}
+		c.SetRevision(c.GetRevision() + 1)
+		return c, nil
+	}
 }
@@ -0,0 +1,4 @@
+CREATE TABLE IF NOT EXISTS customers (
+    id   TEXT PRIMARY KEY,
+    name TEXT NOT NULL
+);
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS customers_name_id ON customers (name, id);
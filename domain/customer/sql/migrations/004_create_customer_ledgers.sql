@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS customer_transactions (
+    customer_id TEXT    NOT NULL REFERENCES customers (id),
+    position    INTEGER NOT NULL,
+    amount      BIGINT  NOT NULL,
+    from_id     TEXT    NOT NULL,
+    to_id       TEXT    NOT NULL,
+    created_at  BIGINT  NOT NULL,
+    reference   TEXT    NOT NULL,
+    kind        TEXT    NOT NULL,
+    PRIMARY KEY (customer_id, position)
+);
+CREATE TABLE IF NOT EXISTS customer_points (
+    customer_id TEXT    NOT NULL REFERENCES customers (id),
+    position    INTEGER NOT NULL,
+    kind        TEXT    NOT NULL,
+    points      INTEGER NOT NULL,
+    reference   TEXT    NOT NULL,
+    at          BIGINT  NOT NULL,
+    expires_at  BIGINT  NOT NULL,
+    PRIMARY KEY (customer_id, position)
+);
+CREATE TABLE IF NOT EXISTS customer_items (
+    customer_id TEXT    NOT NULL REFERENCES customers (id),
+    position    INTEGER NOT NULL,
+    id          TEXT    NOT NULL,
+    name        TEXT    NOT NULL,
+    description TEXT    NOT NULL,
+    PRIMARY KEY (customer_id, position)
+);
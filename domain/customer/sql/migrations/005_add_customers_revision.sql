@@ -0,0 +1 @@
+ALTER TABLE customers ADD COLUMN revision BIGINT NOT NULL DEFAULT 0;
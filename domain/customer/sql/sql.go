@@ -0,0 +1,413 @@
+// Package sql is a database/sql implementation of the customer repository.
+// It works with PostgreSQL and SQLite, the driver has to be registered by the caller.
+package sql
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"time"
+
+	"github.com/gegaryfa/tavern"
+	"github.com/gegaryfa/tavern/domain/customer"
+	"github.com/gegaryfa/tavern/internal/sqldb"
+	"github.com/google/uuid"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+type Repository struct {
+	db     *sql.DB
+	driver string
+}
+
+// New opens a connection using the given driver and applies the customer schema migrations
+func New(driverName, dataSourceName string) (*Repository, error) {
+	db, err := sql.Open(driverName, dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+	return NewFromDB(db, driverName)
+}
+
+// NewFromDB creates a repository on top of an already opened database and applies the customer schema migrations
+func NewFromDB(db *sql.DB, driverName string) (*Repository, error) {
+	sub, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	if err := sqldb.Migrate(db, driverName, "customer", sub); err != nil {
+		return nil, err
+	}
+	return &Repository{db: db, driver: driverName}, nil
+}
+
+// dateLayout is the format of the date of birth column, it is stored as text to work on every database
+const dateLayout = "2006-01-02"
+
+func (r *Repository) Get(id uuid.UUID) (customer.Customer, error) {
+	var (
+		name     string
+		dob      sql.NullString
+		revision int
+	)
+	err := r.db.QueryRow(r.rebind(`SELECT name, date_of_birth, revision FROM customers WHERE id = ?`), id.String()).
+		Scan(&name, &dob, &revision)
+	if errors.Is(err, sql.ErrNoRows) {
+		return customer.Customer{}, customer.ErrCustomerNotFound
+	}
+	if err != nil {
+		return customer.Customer{}, err
+	}
+	c, err := toCustomer(id, name, dob, revision)
+	if err != nil {
+		return customer.Customer{}, err
+	}
+	customers := []customer.Customer{c}
+	if err := r.loadLedgers(customers); err != nil {
+		return customer.Customer{}, err
+	}
+	return customers[0], nil
+}
+
+// toCustomer creates the aggregate out of a row
+func toCustomer(id uuid.UUID, name string, dob sql.NullString, revision int) (customer.Customer, error) {
+	c := customer.Customer{}
+	c.SetID(id)
+	c.SetName(name)
+	c.SetRevision(revision)
+	if dob.Valid {
+		t, err := time.Parse(dateLayout, dob.String)
+		if err != nil {
+			return customer.Customer{}, err
+		}
+		c.SetDateOfBirth(t)
+	}
+	return c, nil
+}
+
+// fromDateOfBirth is NULL when the date of birth is unknown
+func fromDateOfBirth(c customer.Customer) sql.NullString {
+	if c.GetDateOfBirth().IsZero() {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: c.GetDateOfBirth().Format(dateLayout), Valid: true}
+}
+
+func (r *Repository) Add(c customer.Customer) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%v: %w", err, customer.ErrFailedToAddCustomer)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		r.rebind(`INSERT INTO customers (id, name, date_of_birth) VALUES (?, ?, ?) ON CONFLICT (id) DO NOTHING`),
+		c.GetID().String(), c.GetName(), fromDateOfBirth(c),
+	)
+	if err != nil {
+		return fmt.Errorf("%v: %w", err, customer.ErrFailedToAddCustomer)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("customer already exists: %w", customer.ErrFailedToAddCustomer)
+	}
+	if err := r.saveLedgers(tx, c); err != nil {
+		return fmt.Errorf("%v: %w", err, customer.ErrFailedToAddCustomer)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%v: %w", err, customer.ErrFailedToAddCustomer)
+	}
+	return nil
+}
+
+func (r *Repository) Update(c customer.Customer) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%v: %w", err, customer.ErrUpdateCustomer)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		r.rebind(`UPDATE customers SET name = ?, date_of_birth = ?, revision = revision + 1 WHERE id = ? AND revision = ?`),
+		c.GetName(), fromDateOfBirth(c), c.GetID().String(), c.GetRevision(),
+	)
+	if err != nil {
+		return fmt.Errorf("%v: %w", err, customer.ErrUpdateCustomer)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		// Nothing matched either because the customer does not exist or because it has another revision
+		var count int
+		if err := tx.QueryRow(r.rebind(`SELECT COUNT(*) FROM customers WHERE id = ?`), c.GetID().String()).Scan(&count); err != nil {
+			return err
+		}
+		if count == 0 {
+			return customer.ErrCustomerNotFound
+		}
+		return customer.ErrCustomerChanged
+	}
+	if err := r.saveLedgers(tx, c); err != nil {
+		return fmt.Errorf("%v: %w", err, customer.ErrUpdateCustomer)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%v: %w", err, customer.ErrUpdateCustomer)
+	}
+	return nil
+}
+
+// saveLedgers replaces the items, transactions and points of the customer within the transaction that
+// writes the customer row, so that the balances are always derived from what was stored with it
+func (r *Repository) saveLedgers(tx *sql.Tx, c customer.Customer) error {
+	id := c.GetID().String()
+	for _, table := range []string{"customer_items", "customer_transactions", "customer_points"} {
+		if _, err := tx.Exec(r.rebind(`DELETE FROM `+table+` WHERE customer_id = ?`), id); err != nil {
+			return err
+		}
+	}
+	for i, item := range c.GetProducts() {
+		_, err := tx.Exec(
+			r.rebind(`INSERT INTO customer_items (customer_id, position, id, name, description) VALUES (?, ?, ?, ?, ?)`),
+			id, i, item.ID.String(), item.Name, item.Description,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	for i, t := range c.GetTransactions() {
+		_, err := tx.Exec(
+			r.rebind(`INSERT INTO customer_transactions (customer_id, position, amount, from_id, to_id, created_at, reference, kind)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?)`),
+			id, i, t.GetAmount(), t.GetFrom().String(), t.GetTo().String(), toNanos(t.GetCreatedAt()), t.GetReference(), string(t.GetKind()),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	for i, e := range c.GetPoints() {
+		_, err := tx.Exec(
+			r.rebind(`INSERT INTO customer_points (customer_id, position, kind, points, reference, at, expires_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?)`),
+			id, i, string(e.Kind), e.Points, e.Reference, toNanos(e.At), toNanos(e.ExpiresAt),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadLedgers reads the items, transactions and points of the customers, oldest first. Every table is read with
+// a single query for all the customers, so that listing a page does not query the database per customer.
+func (r *Repository) loadLedgers(customers []customer.Customer) error {
+	if len(customers) == 0 {
+		return nil
+	}
+	ids := make([]interface{}, len(customers))
+	index := make(map[string]int, len(customers))
+	for i, c := range customers {
+		ids[i] = c.GetID().String()
+		index[c.GetID().String()] = i
+	}
+	in := `customer_id IN (?` + strings.Repeat(`, ?`, len(ids)-1) + `)`
+
+	items := make([][]*tavern.Item, len(customers))
+	transactions := make([][]tavern.Transaction, len(customers))
+	points := make([][]customer.PointsEntry, len(customers))
+	for i := range customers {
+		items[i] = make([]*tavern.Item, 0)
+		transactions[i] = make([]tavern.Transaction, 0)
+		points[i] = make([]customer.PointsEntry, 0)
+	}
+
+	rows, err := r.db.Query(r.rebind(`SELECT customer_id, id, name, description FROM customer_items
+		WHERE `+in+` ORDER BY customer_id, position`), ids...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var customerID, itemID string
+		item := &tavern.Item{}
+		if err := rows.Scan(&customerID, &itemID, &item.Name, &item.Description); err != nil {
+			return err
+		}
+		if item.ID, err = uuid.Parse(itemID); err != nil {
+			return err
+		}
+		i := index[customerID]
+		items[i] = append(items[i], item)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = r.db.Query(r.rebind(`SELECT customer_id, amount, from_id, to_id, created_at, reference, kind
+		FROM customer_transactions WHERE `+in+` ORDER BY customer_id, position`), ids...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			customerID      string
+			amount          int
+			from, to        string
+			createdAt       int64
+			reference, kind string
+		)
+		if err := rows.Scan(&customerID, &amount, &from, &to, &createdAt, &reference, &kind); err != nil {
+			return err
+		}
+		fromID, err := uuid.Parse(from)
+		if err != nil {
+			return err
+		}
+		toID, err := uuid.Parse(to)
+		if err != nil {
+			return err
+		}
+		i := index[customerID]
+		transactions[i] = append(transactions[i], tavern.NewTransaction(amount, fromID, toID, fromNanos(createdAt)).
+			WithReference(reference).
+			WithKind(tavern.TransactionKind(kind)))
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = r.db.Query(r.rebind(`SELECT customer_id, kind, points, reference, at, expires_at
+		FROM customer_points WHERE `+in+` ORDER BY customer_id, position`), ids...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			customerID    string
+			e             customer.PointsEntry
+			kind          string
+			at, expiresAt int64
+		)
+		if err := rows.Scan(&customerID, &kind, &e.Points, &e.Reference, &at, &expiresAt); err != nil {
+			return err
+		}
+		e.Kind = customer.PointsKind(kind)
+		e.At, e.ExpiresAt = fromNanos(at), fromNanos(expiresAt)
+		i := index[customerID]
+		points[i] = append(points[i], e)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range customers {
+		customers[i].SetProducts(items[i])
+		customers[i].SetTransactions(transactions[i])
+		customers[i].SetPoints(points[i])
+	}
+	return nil
+}
+
+// toNanos stores the zero time as 0, it is out of range of unix nanoseconds
+func toNanos(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func fromNanos(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n).UTC()
+}
+
+// List uses keyset pagination on (name, id) so that pages stay stable while customers are added
+func (r *Repository) List(query customer.ListQuery) (customer.Page, error) {
+	var (
+		where []string
+		args  []interface{}
+	)
+	if query.NamePrefix != "" {
+		where = append(where, `LOWER(name) LIKE ? ESCAPE '\'`)
+		args = append(args, sqldb.EscapeLike(strings.ToLower(query.NamePrefix))+"%")
+	}
+	if query.NameContains != "" {
+		where = append(where, `LOWER(name) LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+sqldb.EscapeLike(strings.ToLower(query.NameContains))+"%")
+	}
+
+	direction, after := "ASC", ">"
+	if query.Sort == customer.SortByNameDesc {
+		direction, after = "DESC", "<"
+	}
+	if query.Cursor != "" {
+		cursor, err := customer.ParseCursor(query.Cursor)
+		if err != nil {
+			return customer.Page{}, err
+		}
+		where = append(where, fmt.Sprintf(`(name %[1]s ? OR (name = ? AND id %[1]s ?))`, after))
+		args = append(args, cursor.Name, cursor.Name, cursor.ID)
+	}
+
+	stmt := `SELECT id, name, date_of_birth, revision FROM customers`
+	if len(where) > 0 {
+		stmt += ` WHERE ` + strings.Join(where, ` AND `)
+	}
+	// Fetch one more than needed to know if there is a next page
+	limit := query.GetLimit()
+	stmt += fmt.Sprintf(` ORDER BY name %[1]s, id %[1]s LIMIT %d`, direction, limit+1)
+
+	rows, err := r.db.Query(r.rebind(stmt), args...)
+	if err != nil {
+		return customer.Page{}, err
+	}
+	defer rows.Close()
+
+	page := customer.Page{Customers: make([]customer.Customer, 0)}
+	for rows.Next() {
+		var (
+			id, name string
+			dob      sql.NullString
+			revision int
+		)
+		if err := rows.Scan(&id, &name, &dob, &revision); err != nil {
+			return customer.Page{}, err
+		}
+		if len(page.Customers) == limit {
+			last := page.Customers[limit-1]
+			page.NextCursor = customer.Cursor{Name: last.GetName(), ID: last.GetID().String()}.Encode()
+			break
+		}
+		uid, err := uuid.Parse(id)
+		if err != nil {
+			return customer.Page{}, err
+		}
+		c, err := toCustomer(uid, name, dob, revision)
+		if err != nil {
+			return customer.Page{}, err
+		}
+		page.Customers = append(page.Customers, c)
+	}
+	if err := rows.Err(); err != nil {
+		return customer.Page{}, err
+	}
+	rows.Close()
+
+	if err := r.loadLedgers(page.Customers); err != nil {
+		return customer.Page{}, err
+	}
+	return page, nil
+}
+
+func (r *Repository) rebind(query string) string {
+	return sqldb.Rebind(r.driver, query)
+}
@@ -0,0 +1,301 @@
+package sql
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gegaryfa/tavern"
+	"github.com/gegaryfa/tavern/domain/customer"
+	"github.com/google/uuid"
+	_ "modernc.org/sqlite"
+)
+
+func newTestRepository(t *testing.T) *Repository {
+	repo, err := New("sqlite", filepath.Join(t.TempDir(), "tavern.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repo
+}
+
+func TestRepository_AddGet(t *testing.T) {
+	repo := newTestRepository(t)
+	c, err := customer.NewCustomer("Percy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.Add(c); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Add(c); !errors.Is(err, customer.ErrFailedToAddCustomer) {
+		t.Errorf("Expected error %v, got %v", customer.ErrFailedToAddCustomer, err)
+	}
+
+	found, err := repo.Get(c.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.GetID() != c.GetID() || found.GetName() != c.GetName() {
+		t.Errorf("Expected %v %v, got %v %v", c.GetID(), c.GetName(), found.GetID(), found.GetName())
+	}
+
+	if _, err := repo.Get(uuid.New()); err != customer.ErrCustomerNotFound {
+		t.Errorf("Expected error %v, got %v", customer.ErrCustomerNotFound, err)
+	}
+}
+
+func TestRepository_Update(t *testing.T) {
+	repo := newTestRepository(t)
+	c, err := customer.NewCustomer("Percy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Add(c); err != nil {
+		t.Fatal(err)
+	}
+
+	type testCase struct {
+		name        string
+		id          uuid.UUID
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{
+			name:        "Customer does not exist",
+			id:          uuid.New(),
+			expectedErr: customer.ErrCustomerNotFound,
+		}, {
+			name:        "Update customer name",
+			id:          c.GetID(),
+			expectedErr: nil,
+		}, {
+			name:        "Customer changed since it was read",
+			id:          c.GetID(),
+			expectedErr: customer.ErrCustomerChanged,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			update := customer.Customer{}
+			update.SetID(tc.id)
+			update.SetName("George")
+
+			err := repo.Update(update)
+			if err != tc.expectedErr {
+				t.Errorf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestRepository_List(t *testing.T) {
+	repo := newTestRepository(t)
+	for _, name := range []string{"Percy", "George", "Peter", "Anna", "Pe_ter"} {
+		c, err := customer.NewCustomer(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.Add(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	type testCase struct {
+		name     string
+		query    customer.ListQuery
+		expected []string
+	}
+
+	testCases := []testCase{
+		{
+			name:     "Name prefix is case insensitive",
+			query:    customer.ListQuery{NamePrefix: "pe"},
+			expected: []string{"Pe_ter", "Percy", "Peter"},
+		}, {
+			name:     "Wildcards are matched literally",
+			query:    customer.ListQuery{NameContains: "_"},
+			expected: []string{"Pe_ter"},
+		}, {
+			name:     "Descending",
+			query:    customer.ListQuery{Sort: customer.SortByNameDesc, NamePrefix: "p"},
+			expected: []string{"Peter", "Percy", "Pe_ter"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			page, err := repo.List(tc.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, c := range page.Customers {
+				got = append(got, c.GetName())
+			}
+			if strings.Join(got, ",") != strings.Join(tc.expected, ",") {
+				t.Errorf("Expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+
+	// Walk through all customers two at a time
+	var got []string
+	query := customer.ListQuery{Limit: 2}
+	for {
+		page, err := repo.List(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range page.Customers {
+			got = append(got, c.GetName())
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+	if expected := "Anna,George,Pe_ter,Percy,Peter"; strings.Join(got, ",") != expected {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestRepository_DateOfBirth(t *testing.T) {
+	repo := newTestRepository(t)
+	dob := time.Date(1980, 5, 17, 0, 0, 0, 0, time.UTC)
+	verified, err := customer.NewCustomer("Percy", customer.WithDateOfBirth(dob))
+	if err != nil {
+		t.Fatal(err)
+	}
+	unverified, err := customer.NewCustomer("Anna")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []customer.Customer{verified, unverified} {
+		if err := repo.Add(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	found, err := repo.Get(verified.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found.GetDateOfBirth().Equal(dob) {
+		t.Errorf("Expected date of birth %v, got %v", dob, found.GetDateOfBirth())
+	}
+	found, err = repo.Get(unverified.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found.GetDateOfBirth().IsZero() {
+		t.Errorf("Expected no date of birth, got %v", found.GetDateOfBirth())
+	}
+
+	// The date of birth can be recorded later on, such as when the staff checks an ID
+	found.SetDateOfBirth(dob)
+	if err := repo.Update(found); err != nil {
+		t.Fatal(err)
+	}
+	page, err := repo.List(customer.ListQuery{NamePrefix: "Anna"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Customers) != 1 || !page.Customers[0].GetDateOfBirth().Equal(dob) {
+		t.Errorf("Expected Anna to be verified, got %v", page.Customers)
+	}
+}
+
+func TestRepository_Ledgers(t *testing.T) {
+	repo := newTestRepository(t)
+	c, err := customer.NewCustomer("Percy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 3, 1, 18, 0, 0, 0, time.UTC)
+	if err := c.TopUp(20, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Add(c); err != nil {
+		t.Fatal(err)
+	}
+
+	// The wallet is charged and points are earned after the customer was added
+	found, err := repo.Get(c.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := found.ChargeWallet(4.5, uuid.New(), tavern.TransactionPayment, "order", now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := found.EarnPoints(45, "order", now.Add(time.Hour), time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Update(found); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err = repo.Get(c.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.Balance() != 15.5 {
+		t.Errorf("Expected balance 15.5, got %v", found.Balance())
+	}
+	got := found.GetTransactions()
+	if len(got) != 2 || got[1].GetKind() != tavern.TransactionPayment || got[1].GetReference() != "order" ||
+		!got[0].GetCreatedAt().Equal(now) {
+		t.Errorf("Expected the top up and the payment, got %v", got)
+	}
+	if found.PointsBalance(now.Add(time.Hour)) != 45 || !found.GetPoints()[0].ExpiresAt.IsZero() {
+		t.Errorf("Expected 45 points that never expire, got %v", found.GetPoints())
+	}
+
+	page, err := repo.List(customer.ListQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Customers) != 1 || page.Customers[0].Balance() != 15.5 {
+		t.Errorf("Expected the listed customer to hold 15.5, got %v", page.Customers)
+	}
+
+	// The ledgers of a page are loaded together, every customer gets their own
+	anna, err := customer.NewCustomer("Anna")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := anna.TopUp(5, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Add(anna); err != nil {
+		t.Fatal(err)
+	}
+	george, err := customer.NewCustomer("George")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Add(george); err != nil {
+		t.Fatal(err)
+	}
+	page, err = repo.List(customer.ListQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]float64{"Anna": 5, "George": 0, "Percy": 15.5}
+	if len(page.Customers) != len(expected) {
+		t.Fatalf("Expected %d customers, got %v", len(expected), page.Customers)
+	}
+	for _, c := range page.Customers {
+		if c.Balance() != expected[c.GetName()] || c.GetTransactions() == nil {
+			t.Errorf("Expected %s to hold %v, got %v", c.GetName(), expected[c.GetName()], c.GetTransactions())
+		}
+	}
+	if points := page.Customers[2].GetPoints(); len(points) != 1 || len(page.Customers[0].GetPoints()) != 0 {
+		t.Errorf("Expected only Percy to have points, got %v", points)
+	}
+}
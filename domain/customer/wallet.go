@@ -0,0 +1,79 @@
+package customer
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gegaryfa/tavern"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidAmount is returned when moving a zero or negative amount in or out of a wallet
+	ErrInvalidAmount = errors.New("the amount has to be positive")
+	// ErrInsufficientFunds is returned when charging a wallet more than its balance
+	ErrInsufficientFunds = errors.New("the wallet does not hold enough money")
+)
+
+// walletNamespace is used to derive the wallet ID from the customer ID
+var walletNamespace = uuid.MustParse("5b0c8a9e-4c57-4a36-9d7e-0f3c1e1f6a21")
+
+// WalletID returns the identifier of the prepaid wallet of the customer, it is the party of the wallet transactions.
+// Money moves in the wallet with transactions to the wallet ID and out with transactions from it.
+func (c Customer) WalletID() uuid.UUID {
+	id := c.GetID()
+	return uuid.NewSHA1(walletNamespace, id[:])
+}
+
+// Balance returns the money left in the wallet, it is derived from the transactions of the customer
+func (c Customer) Balance() float64 {
+	return float64(c.balance()) / 100
+}
+
+// balance returns the money left in the wallet in cents
+func (c Customer) balance() int {
+	wallet := c.WalletID()
+	var cents int
+	for _, t := range c.transactions {
+		if t.GetTo() == wallet {
+			cents += t.GetAmount()
+		}
+		if t.GetFrom() == wallet {
+			cents -= t.GetAmount()
+		}
+	}
+	return cents
+}
+
+// TopUp puts money from the customer in the wallet
+func (c *Customer) TopUp(amount float64, at time.Time) error {
+	cents := tavern.Cents(amount)
+	if cents <= 0 {
+		return ErrInvalidAmount
+	}
+	c.AddTransaction(tavern.NewTransaction(cents, c.GetID(), c.WalletID(), at).WithKind(tavern.TransactionTopUp))
+	return nil
+}
+
+// ChargeWallet pays the amount out of the wallet to the payee, the kind and reference tell what is paid for
+func (c *Customer) ChargeWallet(amount float64, payee uuid.UUID, kind tavern.TransactionKind, reference string, at time.Time) error {
+	cents := tavern.Cents(amount)
+	if cents <= 0 {
+		return ErrInvalidAmount
+	}
+	if cents > c.balance() {
+		return ErrInsufficientFunds
+	}
+	c.AddTransaction(tavern.NewTransaction(cents, c.WalletID(), payee, at).WithKind(kind).WithReference(reference))
+	return nil
+}
+
+// RefundWallet puts money paid to the payer back in the wallet
+func (c *Customer) RefundWallet(amount float64, payer uuid.UUID, reference string, at time.Time) error {
+	cents := tavern.Cents(amount)
+	if cents <= 0 {
+		return ErrInvalidAmount
+	}
+	c.AddTransaction(tavern.NewTransaction(cents, payer, c.WalletID(), at).WithKind(tavern.TransactionRefund).WithReference(reference))
+	return nil
+}
@@ -0,0 +1,72 @@
+package customer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gegaryfa/tavern"
+	"github.com/google/uuid"
+)
+
+func TestCustomer_Wallet(t *testing.T) {
+	now := time.Date(2022, 11, 1, 18, 0, 0, 0, time.UTC)
+	tavernID := uuid.New()
+	c, err := NewCustomer("Percy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type testCase struct {
+		test            string
+		operation       func() error
+		expectedErr     error
+		expectedBalance float64
+	}
+
+	testCases := []testCase{
+		{
+			test:            "Top up",
+			operation:       func() error { return c.TopUp(20, now) },
+			expectedBalance: 20,
+		}, {
+			test:            "Top up nothing",
+			operation:       func() error { return c.TopUp(0, now) },
+			expectedErr:     ErrInvalidAmount,
+			expectedBalance: 20,
+		}, {
+			test: "Charge",
+			operation: func() error {
+				return c.ChargeWallet(12.5, tavernID, tavern.TransactionPayment, "order", now)
+			},
+			expectedBalance: 7.5,
+		}, {
+			test: "Charge more than the balance",
+			operation: func() error {
+				return c.ChargeWallet(7.51, tavernID, tavern.TransactionPayment, "order", now)
+			},
+			expectedErr:     ErrInsufficientFunds,
+			expectedBalance: 7.5,
+		}, {
+			test:            "Refund",
+			operation:       func() error { return c.RefundWallet(2.5, tavernID, "order", now) },
+			expectedBalance: 10,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			if err := tc.operation(); err != tc.expectedErr {
+				t.Errorf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+			if c.Balance() != tc.expectedBalance {
+				t.Errorf("Expected balance %v, got %v", tc.expectedBalance, c.Balance())
+			}
+		})
+	}
+
+	// Payments made outside of the wallet do not change the balance
+	c.AddTransaction(tavern.NewTransaction(500, c.GetID(), tavernID, now).WithKind(tavern.TransactionPayment))
+	if c.Balance() != 10 {
+		t.Errorf("Expected balance 10, got %v", c.Balance())
+	}
+}
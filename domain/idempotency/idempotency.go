@@ -0,0 +1,168 @@
+// Package idempotency remembers the outcome of requests by the key the client sent them with, so that a
+// request retried after a timeout returns the original outcome instead of being performed twice
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInProgress is returned when a request is retried while the first one is still being performed
+	ErrInProgress = errors.New("a request with the same idempotency key is in progress")
+	// ErrKeyReused is returned when a key is sent with another request than the one it was first used for
+	ErrKeyReused = errors.New("the idempotency key was used for another request")
+	// ErrKeyNotFound is returned when completing a key that was not claimed or has expired
+	ErrKeyNotFound = errors.New("the idempotency key was not found")
+	// ErrNotCompleted is returned together with the result of a request that succeeded but could not be stored,
+	// a retry of that request will be performed again
+	ErrNotCompleted = errors.New("the result of the request was not stored")
+	// ErrRequestFailed is returned to the retries of a request that failed after it persisted its result, such as
+	// an order that was placed but could not be paid and was undone
+	ErrRequestFailed = errors.New("the request failed")
+)
+
+// ClaimLease is how long a request holds its key while it is performed. A request that does not complete by
+// then, such as when the process crashed, gives the key up to the retries.
+const ClaimLease = time.Minute
+
+// Record is a value object holding the outcome of the request made with a key
+type Record struct {
+	Key string
+	// Fingerprint identifies the request, a key can only be used for a single request
+	Fingerprint string
+	// Result identifies the outcome of the request, such as the placed order, it is set once completed
+	Result string
+	// Failure describes why a request that persisted its result failed, it is empty for requests that succeeded
+	Failure   string
+	Completed bool
+	// ExpiresAt is when the key is forgotten, a claim that is not completed by then can be taken over
+	ExpiresAt time.Time
+}
+
+// Expired reports whether the record is forgotten at the given instant
+func (r Record) Expired(at time.Time) bool {
+	return !at.Before(r.ExpiresAt)
+}
+
+// Store is the interface to fulfill to remember the outcome of requests
+type Store interface {
+	// Begin claims the key of r for a new request and returns true. When the key was already used for a completed
+	// request it returns its record and false instead, ErrInProgress when that request is still being performed
+	// and ErrKeyReused when the key was used for another request. Expired records are replaced.
+	Begin(r Record, at time.Time) (Record, bool, error)
+	// Complete stores the result of the request made with the key, and its failure if any, until expiresAt
+	Complete(key, result, failure string, expiresAt time.Time) error
+	// Abandon releases the claim of a request that failed so that it can be retried, completed keys are kept
+	Abandon(key string) error
+}
+
+// Fingerprint hashes the parts of a request into a fingerprint
+func Fingerprint(parts ...interface{}) string {
+	h := sha256.New()
+	for _, p := range parts {
+		fmt.Fprintf(h, "%v\x00", p)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Check returns the outcome of beginning r when the key is already held by existing at the given instant
+func Check(existing, r Record, at time.Time) (Record, bool, error) {
+	if existing.Expired(at) {
+		return r, true, nil
+	}
+	if existing.Fingerprint != r.Fingerprint {
+		return Record{}, false, ErrKeyReused
+	}
+	if !existing.Completed {
+		return Record{}, false, ErrInProgress
+	}
+	return existing, false, nil
+}
+
+// Do performs a request once per key. The first request claims the key for ClaimLease, runs perform and stores
+// its result until ttl passed, retries get that result back, together with true, without running it. A request
+// failing before it persisted anything returns no result and releases the key so that it can be retried. A
+// request failing after it did returns its result with the error, which is stored all the same so that retries
+// do not persist it twice. They get the result back with ErrRequestFailed.
+func Do(s Store, key, fingerprint string, at time.Time, ttl time.Duration, perform func() (string, error)) (string, bool, error) {
+	lease := ClaimLease
+	if ttl < lease {
+		lease = ttl
+	}
+	r, claimed, err := s.Begin(Record{Key: key, Fingerprint: fingerprint, ExpiresAt: at.Add(lease)}, at)
+	if err != nil {
+		return "", false, err
+	}
+	if !claimed {
+		if r.Failure != "" {
+			return r.Result, true, fmt.Errorf("%w: %s", ErrRequestFailed, r.Failure)
+		}
+		return r.Result, true, nil
+	}
+
+	result, err := perform()
+	if err != nil && result == "" {
+		// A claim that can not be released expires with the lease
+		_ = s.Abandon(key)
+		return "", false, err
+	}
+	var failure string
+	if err != nil {
+		failure = err.Error()
+	}
+	if cerr := s.Complete(key, result, failure, at.Add(ttl)); cerr != nil && err == nil {
+		return result, false, fmt.Errorf("%w: %v", ErrNotCompleted, cerr)
+	}
+	return result, false, err
+}
+
+// Once performs a request creating an entity, such as an order, once per key, see Do. create returns what it
+// created and its ID, also together with its error when it failed after creating it, retries then get that
+// error back with ErrRequestFailed instead of creating it again. What was created is returned even when the key could not be stored,
+// which is logged.
+func Once[T any](ctx context.Context, logger *slog.Logger, s Store, key, fingerprint string, at time.Time, ttl time.Duration,
+	create func() (T, uuid.UUID, error), get func(uuid.UUID) (T, error)) (T, error) {
+	var created, none T
+	result, replayed, err := Do(s, key, fingerprint, at, ttl, func() (string, error) {
+		var (
+			id  uuid.UUID
+			err error
+		)
+		created, id, err = create()
+		if id == uuid.Nil {
+			return "", err
+		}
+		return id.String(), err
+	})
+	switch {
+	case errors.Is(err, ErrNotCompleted):
+		logger.WarnContext(ctx, "idempotency key not stored",
+			slog.String("key", key),
+			slog.String("result", result),
+			slog.String("error", err.Error()),
+		)
+		return created, nil
+	case err != nil:
+		return none, err
+	case !replayed:
+		return created, nil
+	}
+
+	logger.InfoContext(ctx, "request replayed",
+		slog.String("key", key),
+		slog.String("result", result),
+	)
+	id, err := uuid.Parse(result)
+	if err != nil {
+		return none, err
+	}
+	return get(id)
+}
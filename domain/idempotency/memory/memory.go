@@ -0,0 +1,64 @@
+package memory
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gegaryfa/tavern/domain/idempotency"
+)
+
+// Store fulfills the idempotency.Store interface, expired records are dropped when their key is used again
+type Store struct {
+	records map[string]idempotency.Record
+	sync.Mutex
+}
+
+// New is a factory function to generate a new store of idempotency keys
+func New() *Store {
+	return &Store{
+		records: make(map[string]idempotency.Record),
+	}
+}
+
+func (s *Store) Begin(r idempotency.Record, at time.Time) (idempotency.Record, bool, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if existing, ok := s.records[r.Key]; ok {
+		found, claimed, err := idempotency.Check(existing, r, at)
+		if !claimed {
+			return found, false, err
+		}
+	}
+	r.Completed = false
+	r.Result = ""
+	r.Failure = ""
+	s.records[r.Key] = r
+	return r, true, nil
+}
+
+func (s *Store) Complete(key, result, failure string, expiresAt time.Time) error {
+	s.Lock()
+	defer s.Unlock()
+
+	r, ok := s.records[key]
+	if !ok {
+		return idempotency.ErrKeyNotFound
+	}
+	r.Result = result
+	r.Failure = failure
+	r.Completed = true
+	r.ExpiresAt = expiresAt
+	s.records[key] = r
+	return nil
+}
+
+func (s *Store) Abandon(key string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if r, ok := s.records[key]; ok && !r.Completed {
+		delete(s.records, key)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package memory
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gegaryfa/tavern/domain/idempotency"
+)
+
+func TestMemoryStore(t *testing.T) {
+	now := time.Date(2022, 11, 1, 18, 0, 0, 0, time.UTC)
+	s := New()
+	request := idempotency.Record{Key: "pos-1", Fingerprint: idempotency.Fingerprint("percy", "beer"), ExpiresAt: now.Add(time.Minute)}
+
+	type testCase struct {
+		test            string
+		record          idempotency.Record
+		at              time.Time
+		expectedClaimed bool
+		expectedResult  string
+		expectedErr     error
+	}
+
+	testCases := []testCase{
+		{test: "First request", record: request, at: now, expectedClaimed: true},
+		{test: "Retry while in progress", record: request, at: now, expectedErr: idempotency.ErrInProgress},
+		{test: "Abandoned claims expire", record: request, at: now.Add(time.Minute), expectedClaimed: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			_, claimed, err := s.Begin(tc.record, tc.at)
+			if err != tc.expectedErr {
+				t.Errorf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+			if claimed != tc.expectedClaimed {
+				t.Errorf("Expected claimed %v, got %v", tc.expectedClaimed, claimed)
+			}
+		})
+	}
+
+	if err := s.Complete("pos-1", "order-1", "", now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	found, claimed, err := s.Begin(request, now.Add(time.Minute))
+	if err != nil || claimed || !found.Completed || found.Result != "order-1" {
+		t.Errorf("Expected the completed result order-1, got %v %v %v", found, claimed, err)
+	}
+	other := request
+	other.Fingerprint = idempotency.Fingerprint("percy", "wine")
+	if _, _, err := s.Begin(other, now); err != idempotency.ErrKeyReused {
+		t.Errorf("Expected error %v, got %v", idempotency.ErrKeyReused, err)
+	}
+	// Completed keys survive abandoning and are forgotten once expired
+	if err := s.Abandon("pos-1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, claimed, _ := s.Begin(request, now.Add(time.Minute)); claimed {
+		t.Errorf("Expected the completed key to be kept")
+	}
+	if _, claimed, err := s.Begin(other, now.Add(time.Hour)); err != nil || !claimed {
+		t.Errorf("Expected the expired key to be claimed again, got %v %v", claimed, err)
+	}
+
+	// The failure of a request that persisted its result is kept for its retries
+	failed := idempotency.Record{Key: "pos-2", Fingerprint: request.Fingerprint, ExpiresAt: now.Add(time.Minute)}
+	if _, _, err := s.Begin(failed, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Complete("pos-2", "order-2", "not paid", now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if found, _, err := s.Begin(failed, now); err != nil || found.Result != "order-2" || found.Failure != "not paid" {
+		t.Errorf("Expected order-2 failed with not paid, got %v %v", found, err)
+	}
+
+	if err := s.Complete("unknown", "order-2", "", now); err != idempotency.ErrKeyNotFound {
+		t.Errorf("Expected error %v, got %v", idempotency.ErrKeyNotFound, err)
+	}
+}
+
+func TestDo(t *testing.T) {
+	now := time.Date(2022, 11, 1, 18, 0, 0, 0, time.UTC)
+	s := New()
+	errFailed := errors.New("failed")
+	var performed int
+	succeed := func() (string, error) {
+		performed++
+		return "order-1", nil
+	}
+	fail := func() (string, error) {
+		performed++
+		return "", errFailed
+	}
+	failPlaced := func() (string, error) {
+		performed++
+		return "order-2", errFailed
+	}
+
+	type testCase struct {
+		test             string
+		key              string
+		perform          func() (string, error)
+		expectedResult   string
+		expectedReplayed bool
+		expectedErr      error
+	}
+
+	testCases := []testCase{
+		{test: "Failing request", key: "pos-1", perform: fail, expectedErr: errFailed},
+		{test: "Retry of a failed request", key: "pos-1", perform: succeed, expectedResult: "order-1"},
+		{test: "Retry of a succeeded request", key: "pos-1", perform: fail, expectedResult: "order-1", expectedReplayed: true},
+		{test: "Request failing once persisted", key: "pos-2", perform: failPlaced, expectedResult: "order-2", expectedErr: errFailed},
+		{test: "Retry of a request failed once persisted", key: "pos-2", perform: succeed, expectedResult: "order-2", expectedReplayed: true, expectedErr: idempotency.ErrRequestFailed},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			result, replayed, err := idempotency.Do(s, tc.key, "percy", now, time.Hour, tc.perform)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+			if result != tc.expectedResult || replayed != tc.expectedReplayed {
+				t.Errorf("Expected %q replayed %v, got %q replayed %v", tc.expectedResult, tc.expectedReplayed, result, replayed)
+			}
+		})
+	}
+	if performed != 3 {
+		t.Errorf("Expected the request to be performed 3 times, got %d", performed)
+	}
+
+	// A request that never completes, such as when the process crashed, only holds the key for the lease
+	crashed := func() (string, error) {
+		retry, replayed, err := idempotency.Do(s, "pos-3", "percy", now.Add(idempotency.ClaimLease), time.Hour, succeed)
+		if err != nil || replayed || retry != "order-1" {
+			t.Errorf("Expected the retry to take the key over, got %q replayed %v %v", retry, replayed, err)
+		}
+		return "", errFailed
+	}
+	if _, _, err := idempotency.Do(s, "pos-3", "percy", now, time.Hour, crashed); err != errFailed {
+		t.Errorf("Expected error %v, got %v", errFailed, err)
+	}
+}
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    idempotency_key TEXT PRIMARY KEY,
+    fingerprint     TEXT NOT NULL,
+    result          TEXT NOT NULL DEFAULT '',
+    completed       BOOLEAN NOT NULL DEFAULT FALSE,
+    -- expires_at is in unix nanoseconds so that it compares the same on every database
+    expires_at      BIGINT NOT NULL
+);
@@ -0,0 +1 @@
+ALTER TABLE idempotency_keys ADD COLUMN failure TEXT NOT NULL DEFAULT '';
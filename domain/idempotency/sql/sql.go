@@ -0,0 +1,115 @@
+// Package sql is a database/sql implementation of the idempotency key store.
+// It works with PostgreSQL and SQLite, the driver has to be registered by the caller.
+package sql
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"io/fs"
+	"time"
+
+	"github.com/gegaryfa/tavern/domain/idempotency"
+	"github.com/gegaryfa/tavern/internal/sqldb"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// Store fulfills the idempotency.Store interface, the keys survive restarts of the tavern
+type Store struct {
+	db     *sql.DB
+	driver string
+}
+
+// New opens a connection using the given driver and applies the idempotency schema migrations
+func New(driverName, dataSourceName string) (*Store, error) {
+	db, err := sql.Open(driverName, dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+	return NewFromDB(db, driverName)
+}
+
+// NewFromDB creates a store on top of an already opened database and applies the idempotency schema migrations
+func NewFromDB(db *sql.DB, driverName string) (*Store, error) {
+	sub, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	if err := sqldb.Migrate(db, driverName, "idempotency", sub); err != nil {
+		return nil, err
+	}
+	return &Store{db: db, driver: driverName}, nil
+}
+
+// Begin relies on the primary key so that only one of concurrent requests claims the key
+func (s *Store) Begin(r idempotency.Record, at time.Time) (idempotency.Record, bool, error) {
+	_, err := s.db.Exec(s.rebind(`DELETE FROM idempotency_keys WHERE idempotency_key = ? AND expires_at <= ?`),
+		r.Key, at.UnixNano())
+	if err != nil {
+		return idempotency.Record{}, false, err
+	}
+	result, err := s.db.Exec(
+		s.rebind(`INSERT INTO idempotency_keys (idempotency_key, fingerprint, expires_at) VALUES (?, ?, ?) ON CONFLICT (idempotency_key) DO NOTHING`),
+		r.Key, r.Fingerprint, r.ExpiresAt.UnixNano(),
+	)
+	if err != nil {
+		return idempotency.Record{}, false, err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return idempotency.Record{}, false, err
+	} else if n == 1 {
+		r.Completed = false
+		r.Result = ""
+		r.Failure = ""
+		return r, true, nil
+	}
+
+	existing := idempotency.Record{Key: r.Key}
+	var expiresAt int64
+	err = s.db.QueryRow(
+		s.rebind(`SELECT fingerprint, result, failure, completed, expires_at FROM idempotency_keys WHERE idempotency_key = ?`), r.Key,
+	).Scan(&existing.Fingerprint, &existing.Result, &existing.Failure, &existing.Completed, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		// The request holding the key was abandoned in the meantime, it can be retried
+		return idempotency.Record{}, false, idempotency.ErrInProgress
+	}
+	if err != nil {
+		return idempotency.Record{}, false, err
+	}
+	existing.ExpiresAt = time.Unix(0, expiresAt)
+	found, claimed, err := idempotency.Check(existing, r, at)
+	if claimed {
+		// The record expired after the stale ones were deleted, the request can be retried
+		return idempotency.Record{}, false, idempotency.ErrInProgress
+	}
+	return found, false, err
+}
+
+func (s *Store) Complete(key, result, failure string, expiresAt time.Time) error {
+	res, err := s.db.Exec(
+		s.rebind(`UPDATE idempotency_keys SET result = ?, failure = ?, completed = ?, expires_at = ? WHERE idempotency_key = ?`),
+		result, failure, true, expiresAt.UnixNano(), key,
+	)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return idempotency.ErrKeyNotFound
+	}
+	return nil
+}
+
+func (s *Store) Abandon(key string) error {
+	_, err := s.db.Exec(s.rebind(`DELETE FROM idempotency_keys WHERE idempotency_key = ? AND completed = ?`), key, false)
+	return err
+}
+
+func (s *Store) rebind(query string) string {
+	return sqldb.Rebind(s.driver, query)
+}
@@ -0,0 +1,90 @@
+package sql
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gegaryfa/tavern/domain/idempotency"
+	_ "modernc.org/sqlite"
+)
+
+func newTestStore(t *testing.T) *Store {
+	s, err := New("sqlite", filepath.Join(t.TempDir(), "tavern.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestSQLStore(t *testing.T) {
+	now := time.Date(2022, 11, 1, 18, 0, 0, 0, time.UTC)
+	s := newTestStore(t)
+	request := idempotency.Record{Key: "pos-1", Fingerprint: idempotency.Fingerprint("percy", "beer"), ExpiresAt: now.Add(time.Minute)}
+
+	type testCase struct {
+		test            string
+		record          idempotency.Record
+		at              time.Time
+		expectedClaimed bool
+		expectedResult  string
+		expectedErr     error
+	}
+
+	testCases := []testCase{
+		{test: "First request", record: request, at: now, expectedClaimed: true},
+		{test: "Retry while in progress", record: request, at: now, expectedErr: idempotency.ErrInProgress},
+		{test: "Abandoned claims expire", record: request, at: now.Add(time.Minute), expectedClaimed: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			_, claimed, err := s.Begin(tc.record, tc.at)
+			if err != tc.expectedErr {
+				t.Errorf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+			if claimed != tc.expectedClaimed {
+				t.Errorf("Expected claimed %v, got %v", tc.expectedClaimed, claimed)
+			}
+		})
+	}
+
+	if err := s.Complete("pos-1", "order-1", "", now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	found, claimed, err := s.Begin(request, now.Add(time.Minute))
+	if err != nil || claimed || !found.Completed || found.Result != "order-1" {
+		t.Errorf("Expected the completed result order-1, got %v %v %v", found, claimed, err)
+	}
+	other := request
+	other.Fingerprint = idempotency.Fingerprint("percy", "wine")
+	if _, _, err := s.Begin(other, now); err != idempotency.ErrKeyReused {
+		t.Errorf("Expected error %v, got %v", idempotency.ErrKeyReused, err)
+	}
+	// Completed keys survive abandoning and are forgotten once expired
+	if err := s.Abandon("pos-1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, claimed, _ := s.Begin(request, now.Add(time.Minute)); claimed {
+		t.Errorf("Expected the completed key to be kept")
+	}
+	if _, claimed, err := s.Begin(other, now.Add(time.Hour)); err != nil || !claimed {
+		t.Errorf("Expected the expired key to be claimed again, got %v %v", claimed, err)
+	}
+
+	// The failure of a request that persisted its result is kept for its retries
+	failed := idempotency.Record{Key: "pos-2", Fingerprint: request.Fingerprint, ExpiresAt: now.Add(time.Minute)}
+	if _, _, err := s.Begin(failed, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Complete("pos-2", "order-2", "not paid", now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if found, _, err := s.Begin(failed, now); err != nil || found.Result != "order-2" || found.Failure != "not paid" {
+		t.Errorf("Expected order-2 failed with not paid, got %v %v", found, err)
+	}
+
+	if err := s.Complete("unknown", "order-2", "", now); err != idempotency.ErrKeyNotFound {
+		t.Errorf("Expected error %v, got %v", idempotency.ErrKeyNotFound, err)
+	}
+}
@@ -0,0 +1,23 @@
+package ledger
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrEntryAlreadyRecorded is returned when recording an entry twice
+	ErrEntryAlreadyRecorded = errors.New("the journal entry was already recorded")
+)
+
+// Journal is the append only record of the entries of the ledger, recorded entries are never changed
+type Journal interface {
+	// Record appends the entry to the journal
+	Record(Entry) error
+	// Entries returns the entries made in [from, to), in the order they were made
+	Entries(from, to time.Time) ([]Entry, error)
+	// Balance returns the balance of the account in cents at the given instant
+	Balance(account uuid.UUID, at time.Time) (int, error)
+}
@@ -0,0 +1,144 @@
+// Package ledger holds the double-entry journal of the money moving through the tavern
+package ledger
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gegaryfa/tavern"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrNoTransactions is returned when an entry is created without any transactions
+	ErrNoTransactions = errors.New("a journal entry has to hold at least one transaction")
+	// ErrInvalidTransaction is returned when a transaction moves no money, or moves it from or to a missing account
+	ErrInvalidTransaction = errors.New("a transaction has to move a positive amount between two accounts")
+)
+
+// AccountType tells what an account of the ledger holds
+type AccountType string
+
+const (
+	// AccountCustomer is the money customers pay with
+	AccountCustomer AccountType = "customer"
+	// AccountWallet is the money customers prepaid in their wallet
+	AccountWallet AccountType = "wallet"
+	// AccountRevenue is what the tavern earns from orders and service charges
+	AccountRevenue AccountType = "revenue"
+	// AccountTips is what the staff is owed in tips
+	AccountTips AccountType = "tips"
+	// AccountTaxPayable is the tax the tavern collected and owes
+	AccountTaxPayable AccountType = "tax_payable"
+)
+
+// Account is a value object identifying an account of the ledger
+type Account struct {
+	ID   uuid.UUID
+	Type AccountType
+}
+
+// accountNamespace derives the identifiers of the accounts of the tavern
+var accountNamespace = uuid.MustParse("8d2f5f0e-4a3b-4c61-9a7e-1f4b2d6c9e10")
+
+// The accounts of the tavern itself, their identifiers are stable between runs
+var (
+	Revenue    = Account{ID: uuid.NewSHA1(accountNamespace, []byte(AccountRevenue)), Type: AccountRevenue}
+	Tips       = Account{ID: uuid.NewSHA1(accountNamespace, []byte(AccountTips)), Type: AccountTips}
+	TaxPayable = Account{ID: uuid.NewSHA1(accountNamespace, []byte(AccountTaxPayable)), Type: AccountTaxPayable}
+)
+
+// CustomerAccount returns the account of the customer, it has the identifier of the customer
+func CustomerAccount(customerID uuid.UUID) Account {
+	return Account{ID: customerID, Type: AccountCustomer}
+}
+
+// WalletAccount returns the account of a customer wallet, it has the identifier of the wallet
+func WalletAccount(walletID uuid.UUID) Account {
+	return Account{ID: walletID, Type: AccountWallet}
+}
+
+// Posting is a value object holding the change of the balance of an account, in cents.
+// Money moving into the account is positive, money moving out of it is negative.
+type Posting struct {
+	Account uuid.UUID
+	Amount  int
+}
+
+// Entry is a value object holding a journal entry, the transactions that make up a single business event.
+// Every transaction debits the account it moves money to and credits the one it moves money from,
+// so the postings of an entry always balance.
+type Entry struct {
+	id           uuid.UUID
+	reference    string
+	at           time.Time
+	transactions []tavern.Transaction
+}
+
+// NewEntry creates an Entry from the transactions, the entry is recorded at the given instant
+func NewEntry(reference string, at time.Time, transactions ...tavern.Transaction) (Entry, error) {
+	if len(transactions) == 0 {
+		return Entry{}, ErrNoTransactions
+	}
+	for _, t := range transactions {
+		if t.GetAmount() <= 0 || t.GetFrom() == uuid.Nil || t.GetTo() == uuid.Nil || t.GetFrom() == t.GetTo() {
+			return Entry{}, ErrInvalidTransaction
+		}
+	}
+	return Entry{
+		id:           uuid.New(),
+		reference:    reference,
+		at:           at,
+		transactions: append([]tavern.Transaction(nil), transactions...),
+	}, nil
+}
+
+func (e Entry) GetID() uuid.UUID {
+	return e.id
+}
+
+// SetID is used when loading stored entries, the journal identifies an entry by its ID
+func (e *Entry) SetID(id uuid.UUID) {
+	e.id = id
+}
+
+func (e Entry) GetReference() string {
+	return e.reference
+}
+
+func (e Entry) GetAt() time.Time {
+	return e.at
+}
+
+// GetTransactions returns a copy of the transactions, an entry can not be changed once created
+func (e Entry) GetTransactions() []tavern.Transaction {
+	return append([]tavern.Transaction(nil), e.transactions...)
+}
+
+// Postings returns the two postings of every transaction of the entry
+func (e Entry) Postings() []Posting {
+	postings := make([]Posting, 0, 2*len(e.transactions))
+	for _, t := range e.transactions {
+		postings = append(postings,
+			Posting{Account: t.GetFrom(), Amount: -t.GetAmount()},
+			Posting{Account: t.GetTo(), Amount: t.GetAmount()},
+		)
+	}
+	return postings
+}
+
+// Balance returns the balance of the account after the entries made until the given instant, inclusive
+func Balance(entries []Entry, account uuid.UUID, at time.Time) int {
+	var balance int
+	for _, e := range entries {
+		if e.at.After(at) {
+			continue
+		}
+		for _, p := range e.Postings() {
+			if p.Account == account {
+				balance += p.Amount
+			}
+		}
+	}
+	return balance
+}
@@ -0,0 +1,96 @@
+package ledger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gegaryfa/tavern"
+	"github.com/google/uuid"
+)
+
+func TestNewEntry(t *testing.T) {
+	now := time.Date(2022, 11, 1, 18, 0, 0, 0, time.UTC)
+	customerID := uuid.New()
+
+	type testCase struct {
+		test         string
+		transactions []tavern.Transaction
+		expectedErr  error
+	}
+
+	testCases := []testCase{
+		{
+			test:         "No transactions",
+			transactions: nil,
+			expectedErr:  ErrNoTransactions,
+		}, {
+			test:         "Nothing moved",
+			transactions: []tavern.Transaction{tavern.NewTransaction(0, customerID, Revenue.ID, now)},
+			expectedErr:  ErrInvalidTransaction,
+		}, {
+			test:         "Missing account",
+			transactions: []tavern.Transaction{tavern.NewTransaction(100, uuid.Nil, Revenue.ID, now)},
+			expectedErr:  ErrInvalidTransaction,
+		}, {
+			test:         "Same account",
+			transactions: []tavern.Transaction{tavern.NewTransaction(100, Revenue.ID, Revenue.ID, now)},
+			expectedErr:  ErrInvalidTransaction,
+		}, {
+			test: "Payment with tax",
+			transactions: []tavern.Transaction{
+				tavern.NewTransaction(1000, customerID, Revenue.ID, now),
+				tavern.NewTransaction(240, customerID, TaxPayable.ID, now),
+			},
+			expectedErr: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			_, err := NewEntry("order", now, tc.transactions...)
+			if err != tc.expectedErr {
+				t.Errorf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestBalance(t *testing.T) {
+	nov, dec := time.Date(2022, 11, 1, 18, 0, 0, 0, time.UTC), time.Date(2022, 12, 1, 18, 0, 0, 0, time.UTC)
+	customerID := CustomerAccount(uuid.New()).ID
+
+	paid, err := NewEntry("order", nov,
+		tavern.NewTransaction(1000, customerID, Revenue.ID, nov),
+		tavern.NewTransaction(240, customerID, TaxPayable.ID, nov),
+		tavern.NewTransaction(150, customerID, Tips.ID, nov),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	refunded, err := NewEntry("refund", dec, tavern.NewTransaction(500, Revenue.ID, customerID, dec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := []Entry{paid, refunded}
+
+	type testCase struct {
+		account  uuid.UUID
+		at       time.Time
+		expected int
+	}
+
+	testCases := []testCase{
+		{account: customerID, at: nov.Add(-time.Second), expected: 0},
+		{account: customerID, at: nov, expected: -1390},
+		{account: customerID, at: dec, expected: -890},
+		{account: Revenue.ID, at: dec, expected: 500},
+		{account: TaxPayable.ID, at: dec, expected: 240},
+		{account: Tips.ID, at: dec, expected: 150},
+	}
+
+	for _, tc := range testCases {
+		if got := Balance(entries, tc.account, tc.at); got != tc.expected {
+			t.Errorf("Expected balance %d of %v at %v, got %d", tc.expected, tc.account, tc.at, got)
+		}
+	}
+}
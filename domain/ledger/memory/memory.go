@@ -0,0 +1,66 @@
+package memory
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gegaryfa/tavern/domain/ledger"
+	"github.com/google/uuid"
+)
+
+// Journal fulfills the ledger.Journal interface. The entries are only held in memory and are lost when the
+// tavern stops, the sql journal keeps them.
+type Journal struct {
+	entries  []ledger.Entry
+	recorded map[uuid.UUID]struct{}
+	sync.Mutex
+}
+
+// New is a factory function to generate a new journal
+func New() *Journal {
+	return &Journal{
+		recorded: make(map[uuid.UUID]struct{}),
+	}
+}
+
+// Record appends the entry, the entries are kept sorted by the instant they were made
+func (j *Journal) Record(e ledger.Entry) error {
+	j.Lock()
+	defer j.Unlock()
+
+	if _, ok := j.recorded[e.GetID()]; ok {
+		return ledger.ErrEntryAlreadyRecorded
+	}
+	// Entries are usually recorded in order, a late one is moved back to its place
+	i := sort.Search(len(j.entries), func(i int) bool {
+		return j.entries[i].GetAt().After(e.GetAt())
+	})
+	j.entries = append(j.entries, ledger.Entry{})
+	copy(j.entries[i+1:], j.entries[i:])
+	j.entries[i] = e
+	j.recorded[e.GetID()] = struct{}{}
+	return nil
+}
+
+// Entries returns the entries made in [from, to)
+func (j *Journal) Entries(from, to time.Time) ([]ledger.Entry, error) {
+	j.Lock()
+	defer j.Unlock()
+
+	var entries []ledger.Entry
+	for _, e := range j.entries {
+		if !e.GetAt().Before(from) && e.GetAt().Before(to) {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+// Balance returns the balance of the account at the given instant
+func (j *Journal) Balance(account uuid.UUID, at time.Time) (int, error) {
+	j.Lock()
+	defer j.Unlock()
+
+	return ledger.Balance(j.entries, account, at), nil
+}
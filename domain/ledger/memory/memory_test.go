@@ -0,0 +1,56 @@
+package memory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gegaryfa/tavern"
+	"github.com/gegaryfa/tavern/domain/ledger"
+	"github.com/google/uuid"
+)
+
+func TestJournal(t *testing.T) {
+	nov, dec := time.Date(2022, 11, 1, 18, 0, 0, 0, time.UTC), time.Date(2022, 12, 1, 18, 0, 0, 0, time.UTC)
+	customerID := uuid.New()
+	j := New()
+
+	late, err := ledger.NewEntry("december", dec, tavern.NewTransaction(300, customerID, ledger.Revenue.ID, dec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	early, err := ledger.NewEntry("november", nov, tavern.NewTransaction(200, customerID, ledger.Revenue.ID, nov))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []ledger.Entry{late, early} {
+		if err := j.Record(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := j.Record(late); err != ledger.ErrEntryAlreadyRecorded {
+		t.Errorf("Expected error %v, got %v", ledger.ErrEntryAlreadyRecorded, err)
+	}
+
+	entries, err := j.Entries(nov, dec.Add(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].GetID() != early.GetID() || entries[1].GetID() != late.GetID() {
+		t.Errorf("Expected the entries in the order they were made, got %v", entries)
+	}
+
+	balance, err := j.Balance(ledger.Revenue.ID, nov)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance != 200 {
+		t.Errorf("Expected a revenue of 200 in november, got %d", balance)
+	}
+	balance, err = j.Balance(customerID, dec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance != -500 {
+		t.Errorf("Expected a customer balance of -500 in december, got %d", balance)
+	}
+}
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS journal_entries (
+    id        TEXT PRIMARY KEY,
+    reference TEXT   NOT NULL,
+    -- at is in unix nanoseconds so that it compares the same on every database
+    at        BIGINT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS journal_entries_at ON journal_entries (at);
+CREATE TABLE IF NOT EXISTS journal_transactions (
+    entry_id   TEXT    NOT NULL REFERENCES journal_entries (id),
+    position   INTEGER NOT NULL,
+    amount     BIGINT  NOT NULL,
+    from_id    TEXT    NOT NULL,
+    to_id      TEXT    NOT NULL,
+    created_at BIGINT  NOT NULL,
+    reference  TEXT    NOT NULL,
+    kind       TEXT    NOT NULL,
+    PRIMARY KEY (entry_id, position)
+);
+CREATE INDEX IF NOT EXISTS journal_transactions_from ON journal_transactions (from_id);
+CREATE INDEX IF NOT EXISTS journal_transactions_to ON journal_transactions (to_id);
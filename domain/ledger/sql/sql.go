@@ -0,0 +1,166 @@
+// Package sql is a database/sql implementation of the ledger journal.
+// It works with PostgreSQL and SQLite, the driver has to be registered by the caller.
+package sql
+
+import (
+	"database/sql"
+	"embed"
+	"io/fs"
+	"time"
+
+	"github.com/gegaryfa/tavern"
+	"github.com/gegaryfa/tavern/domain/ledger"
+	"github.com/gegaryfa/tavern/internal/sqldb"
+	"github.com/google/uuid"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// Journal fulfills the ledger.Journal interface, the entries survive restarts of the tavern
+type Journal struct {
+	db     *sql.DB
+	driver string
+}
+
+// New opens a connection using the given driver and applies the journal schema migrations
+func New(driverName, dataSourceName string) (*Journal, error) {
+	db, err := sql.Open(driverName, dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+	return NewFromDB(db, driverName)
+}
+
+// NewFromDB creates a journal on top of an already opened database and applies the journal schema migrations
+func NewFromDB(db *sql.DB, driverName string) (*Journal, error) {
+	sub, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	if err := sqldb.Migrate(db, driverName, "ledger", sub); err != nil {
+		return nil, err
+	}
+	return &Journal{db: db, driver: driverName}, nil
+}
+
+// Record writes the entry and its transactions in a single database transaction
+func (j *Journal) Record(e ledger.Entry) error {
+	tx, err := j.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		j.rebind(`INSERT INTO journal_entries (id, reference, at) VALUES (?, ?, ?) ON CONFLICT (id) DO NOTHING`),
+		e.GetID().String(), e.GetReference(), e.GetAt().UnixNano(),
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ledger.ErrEntryAlreadyRecorded
+	}
+	for i, t := range e.GetTransactions() {
+		_, err := tx.Exec(
+			j.rebind(`INSERT INTO journal_transactions (entry_id, position, amount, from_id, to_id, created_at, reference, kind)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?)`),
+			e.GetID().String(), i, t.GetAmount(), t.GetFrom().String(), t.GetTo().String(), t.GetCreatedAt().UnixNano(),
+			t.GetReference(), string(t.GetKind()),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Entries returns the entries made in [from, to), entries made at the same instant are ordered by their ID
+func (j *Journal) Entries(from, to time.Time) ([]ledger.Entry, error) {
+	rows, err := j.db.Query(j.rebind(`SELECT e.id, e.reference, e.at, t.amount, t.from_id, t.to_id, t.created_at, t.reference, t.kind
+		FROM journal_entries e JOIN journal_transactions t ON t.entry_id = e.id
+		WHERE e.at >= ? AND e.at < ? ORDER BY e.at, e.id, t.position`), from.UnixNano(), to.UnixNano())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		entries      []ledger.Entry
+		current      string
+		reference    string
+		at           int64
+		transactions []tavern.Transaction
+	)
+	flush := func() error {
+		if current == "" {
+			return nil
+		}
+		id, err := uuid.Parse(current)
+		if err != nil {
+			return err
+		}
+		e, err := ledger.NewEntry(reference, time.Unix(0, at), transactions...)
+		if err != nil {
+			return err
+		}
+		e.SetID(id)
+		entries = append(entries, e)
+		return nil
+	}
+	for rows.Next() {
+		var (
+			entryID, entryReference string
+			entryAt, createdAt      int64
+			amount                  int
+			fromID, toID, ref, kind string
+		)
+		if err := rows.Scan(&entryID, &entryReference, &entryAt, &amount, &fromID, &toID, &createdAt, &ref, &kind); err != nil {
+			return nil, err
+		}
+		if entryID != current {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			current, reference, at, transactions = entryID, entryReference, entryAt, nil
+		}
+		fromUUID, err := uuid.Parse(fromID)
+		if err != nil {
+			return nil, err
+		}
+		toUUID, err := uuid.Parse(toID)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, tavern.NewTransaction(amount, fromUUID, toUUID, time.Unix(0, createdAt)).
+			WithReference(ref).
+			WithKind(tavern.TransactionKind(kind)))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Balance sums the postings of the account in the database
+func (j *Journal) Balance(account uuid.UUID, at time.Time) (int, error) {
+	id := account.String()
+	var balance int
+	err := j.db.QueryRow(j.rebind(`SELECT COALESCE(SUM(CASE WHEN t.to_id = ? THEN t.amount ELSE -t.amount END), 0)
+		FROM journal_transactions t JOIN journal_entries e ON e.id = t.entry_id
+		WHERE e.at <= ? AND (t.from_id = ? OR t.to_id = ?)`), id, at.UnixNano(), id, id).Scan(&balance)
+	if err != nil {
+		return 0, err
+	}
+	return balance, nil
+}
+
+func (j *Journal) rebind(query string) string {
+	return sqldb.Rebind(j.driver, query)
+}
@@ -0,0 +1,80 @@
+package sql
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gegaryfa/tavern"
+	"github.com/gegaryfa/tavern/domain/ledger"
+	"github.com/google/uuid"
+	_ "modernc.org/sqlite"
+)
+
+func TestSQLJournal(t *testing.T) {
+	nov, dec := time.Date(2022, 11, 1, 18, 0, 0, 0, time.UTC), time.Date(2022, 12, 1, 18, 0, 0, 0, time.UTC)
+	customerID := uuid.New()
+	path := filepath.Join(t.TempDir(), "tavern.db")
+	j, err := New("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	late, err := ledger.NewEntry("december", dec, tavern.NewTransaction(300, customerID, ledger.Revenue.ID, dec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	early, err := ledger.NewEntry("november", nov,
+		tavern.NewTransaction(150, customerID, ledger.Revenue.ID, nov).WithKind(tavern.TransactionPayment),
+		tavern.NewTransaction(50, customerID, ledger.TaxPayable.ID, nov).WithKind(tavern.TransactionPayment),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []ledger.Entry{late, early} {
+		if err := j.Record(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := j.Record(late); err != ledger.ErrEntryAlreadyRecorded {
+		t.Errorf("Expected error %v, got %v", ledger.ErrEntryAlreadyRecorded, err)
+	}
+
+	// The entries are kept when the journal is opened again
+	j, err = New("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := j.Entries(nov, dec.Add(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].GetID() != early.GetID() || entries[1].GetID() != late.GetID() {
+		t.Fatalf("Expected the entries in the order they were made, got %v", entries)
+	}
+	if got := entries[0].GetTransactions(); len(got) != 2 || got[1].GetAmount() != 50 || got[1].GetTo() != ledger.TaxPayable.ID {
+		t.Errorf("Expected the transactions of the entry, got %v", got)
+	}
+
+	type testCase struct {
+		account  uuid.UUID
+		at       time.Time
+		expected int
+	}
+
+	testCases := []testCase{
+		{account: ledger.Revenue.ID, at: nov, expected: 150},
+		{account: customerID, at: nov.Add(-time.Second), expected: 0},
+		{account: customerID, at: dec, expected: -500},
+	}
+
+	for _, tc := range testCases {
+		got, err := j.Balance(tc.account, tc.at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.expected {
+			t.Errorf("Expected balance %d of %v at %v, got %d", tc.expected, tc.account, tc.at, got)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+// Package loyalty holds the rules customers earn and redeem loyalty points by
+package loyalty
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/gegaryfa/tavern/domain/order"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidRate is returned when points are earned at a negative rate or multiplier
+	ErrInvalidRate = errors.New("points can not be earned at a negative rate")
+	// ErrInvalidCost is returned when a reward does not cost a positive amount of points
+	ErrInvalidCost = errors.New("a reward has to cost a positive amount of points")
+	// ErrInvalidThresholds is returned when the tiers do not need an increasing amount of points
+	ErrInvalidThresholds = errors.New("the gold tier has to need more points than the silver tier")
+	// ErrNotAReward is returned when redeeming points for a product that is not a reward
+	ErrNotAReward = errors.New("the product can not be redeemed for points")
+)
+
+// Tier is the loyalty level of a customer, it is decided by the points they ever earned
+type Tier string
+
+const (
+	TierBronze Tier = "bronze"
+	TierSilver Tier = "silver"
+	TierGold   Tier = "gold"
+)
+
+// Configuration is an alias for a function that configures a Programme
+type Configuration func(p *Programme) error
+
+// Programme holds the rules of the loyalty programme
+type Programme struct {
+	// pointsPerUnit are earned for every currency unit paid
+	pointsPerUnit float64
+	// bonus multiplies the points earned on the products of a category
+	bonus map[string]float64
+	// tierBonus multiplies the points earned by the customers of a tier
+	tierBonus map[Tier]float64
+	// validity is how long earned points can be redeemed, zero never expires them
+	validity time.Duration
+	// rewards holds the points every product that can be redeemed costs
+	rewards map[uuid.UUID]int
+	// silver and gold are the lifetime points a customer is promoted at
+	silver int
+	gold   int
+}
+
+// NewProgramme creates a Programme, by default a point is earned per currency unit and points never expire
+func NewProgramme(cfgs ...Configuration) (*Programme, error) {
+	p := &Programme{
+		pointsPerUnit: 1,
+		bonus:         make(map[string]float64),
+		tierBonus:     make(map[Tier]float64),
+		rewards:       make(map[uuid.UUID]int),
+		silver:        500,
+		gold:          2000,
+	}
+	for _, cfg := range cfgs {
+		if err := cfg(p); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// WithPointsPerUnit earns points for every currency unit paid
+func WithPointsPerUnit(points float64) Configuration {
+	return func(p *Programme) error {
+		if points < 0 {
+			return ErrInvalidRate
+		}
+		p.pointsPerUnit = points
+		return nil
+	}
+}
+
+// WithBonusCategory multiplies the points earned on the products of the category, 2 earns double points
+func WithBonusCategory(category string, multiplier float64) Configuration {
+	return func(p *Programme) error {
+		if multiplier < 0 {
+			return ErrInvalidRate
+		}
+		p.bonus[category] = multiplier
+		return nil
+	}
+}
+
+// WithTierBonus multiplies the points earned by the customers of the tier
+func WithTierBonus(tier Tier, multiplier float64) Configuration {
+	return func(p *Programme) error {
+		if multiplier < 0 {
+			return ErrInvalidRate
+		}
+		p.tierBonus[tier] = multiplier
+		return nil
+	}
+}
+
+// WithExpiry lets earned points be redeemed for validity, after that they expire
+func WithExpiry(validity time.Duration) Configuration {
+	return func(p *Programme) error {
+		p.validity = validity
+		return nil
+	}
+}
+
+// WithReward lets customers redeem points for the product
+func WithReward(productID uuid.UUID, points int) Configuration {
+	return func(p *Programme) error {
+		if points <= 0 {
+			return ErrInvalidCost
+		}
+		p.rewards[productID] = points
+		return nil
+	}
+}
+
+// WithTierThresholds promotes customers to silver and gold once they ever earned that many points
+func WithTierThresholds(silver, gold int) Configuration {
+	return func(p *Programme) error {
+		if silver <= 0 || gold <= silver {
+			return ErrInvalidThresholds
+		}
+		p.silver = silver
+		p.gold = gold
+		return nil
+	}
+}
+
+// Tier returns the tier of a customer that ever earned lifetimePoints
+func (p *Programme) Tier(lifetimePoints int) Tier {
+	switch {
+	case lifetimePoints >= p.gold:
+		return TierGold
+	case lifetimePoints >= p.silver:
+		return TierSilver
+	default:
+		return TierBronze
+	}
+}
+
+// Earn returns the points a customer of the tier earns for paying amount for the lines. The amount is spread
+// over the lines by their price, so that bonus categories only count what was actually paid for them.
+func (p *Programme) Earn(lines []order.Line, amount float64, tier Tier) int {
+	var subtotal, weighted float64
+	for _, l := range lines {
+		multiplier, ok := p.bonus[l.Category]
+		if !ok {
+			multiplier = 1
+		}
+		subtotal += l.Total()
+		weighted += l.Total() * multiplier
+	}
+	if subtotal <= 0 || amount <= 0 {
+		return 0
+	}
+	multiplier, ok := p.tierBonus[tier]
+	if !ok {
+		multiplier = 1
+	}
+	// The epsilon keeps float errors from costing a point on round amounts
+	return int(math.Floor(amount/subtotal*weighted*p.pointsPerUnit*multiplier + 1e-9))
+}
+
+// ExpiresAt returns when points earned at the instant expire, the zero time when they never do
+func (p *Programme) ExpiresAt(at time.Time) time.Time {
+	if p.validity <= 0 {
+		return time.Time{}
+	}
+	return at.Add(p.validity)
+}
+
+// Cost returns the points the product costs when redeemed
+func (p *Programme) Cost(productID uuid.UUID) (int, error) {
+	points, ok := p.rewards[productID]
+	if !ok {
+		return 0, ErrNotAReward
+	}
+	return points, nil
+}
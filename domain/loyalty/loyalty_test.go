@@ -0,0 +1,103 @@
+package loyalty
+
+import (
+	"testing"
+
+	"github.com/gegaryfa/tavern/domain/order"
+	"github.com/google/uuid"
+)
+
+func TestProgramme_Earn(t *testing.T) {
+	p, err := NewProgramme(
+		WithPointsPerUnit(10),
+		WithBonusCategory("snacks", 2),
+		WithTierBonus(TierGold, 1.5),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	beer := order.Line{ProductID: uuid.New(), Name: "Beer", Category: "drinks", Quantity: 2, UnitPrice: 5}
+	nuts := order.Line{ProductID: uuid.New(), Name: "Peenuts", Category: "snacks", Quantity: 1, UnitPrice: 2.5}
+
+	type testCase struct {
+		test     string
+		lines    []order.Line
+		amount   float64
+		tier     Tier
+		expected int
+	}
+
+	testCases := []testCase{
+		{
+			test:     "Points per currency unit",
+			lines:    []order.Line{beer},
+			amount:   10,
+			tier:     TierBronze,
+			expected: 100,
+		}, {
+			test:     "Bonus category",
+			lines:    []order.Line{beer, nuts},
+			amount:   12.5,
+			tier:     TierBronze,
+			expected: 150,
+		}, {
+			test:     "Discounted order earns on what was paid",
+			lines:    []order.Line{beer},
+			amount:   7.99,
+			tier:     TierBronze,
+			expected: 79,
+		}, {
+			test:     "Tier bonus",
+			lines:    []order.Line{beer},
+			amount:   10,
+			tier:     TierGold,
+			expected: 150,
+		}, {
+			test:     "Nothing paid",
+			lines:    []order.Line{beer},
+			amount:   0,
+			tier:     TierBronze,
+			expected: 0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			if got := p.Earn(tc.lines, tc.amount, tc.tier); got != tc.expected {
+				t.Errorf("Expected %d points, got %d", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestProgramme_Tier(t *testing.T) {
+	p, err := NewProgramme(WithTierThresholds(100, 300))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type testCase struct {
+		points   int
+		expected Tier
+	}
+
+	testCases := []testCase{
+		{points: 0, expected: TierBronze},
+		{points: 99, expected: TierBronze},
+		{points: 100, expected: TierSilver},
+		{points: 300, expected: TierGold},
+	}
+
+	for _, tc := range testCases {
+		if got := p.Tier(tc.points); got != tc.expected {
+			t.Errorf("Expected tier %s for %d points, got %s", tc.expected, tc.points, got)
+		}
+	}
+
+	if _, err := NewProgramme(WithTierThresholds(300, 100)); err != ErrInvalidThresholds {
+		t.Errorf("Expected error %v, got %v", ErrInvalidThresholds, err)
+	}
+	if _, err := p.Cost(uuid.New()); err != ErrNotAReward {
+		t.Errorf("Expected error %v, got %v", ErrNotAReward, err)
+	}
+}
@@ -0,0 +1,65 @@
+package order
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Invoice is a value object holding everything printed on the bill of an order
+type Invoice struct {
+	OrderID      uuid.UUID
+	CustomerID   uuid.UUID
+	IssuedAt     time.Time
+	Lines        []Line
+	Subtotal     float64
+	Discounts    []Discount
+	Taxes        []Tax
+	TaxInclusive bool
+	Total        float64
+}
+
+// Invoice returns the invoice of the order
+func (o Order) Invoice() Invoice {
+	return Invoice{
+		OrderID:      o.id,
+		CustomerID:   o.customerID,
+		IssuedAt:     o.createdAt,
+		Lines:        o.lines,
+		Subtotal:     o.Subtotal(),
+		Discounts:    o.discounts,
+		Taxes:        o.taxes,
+		TaxInclusive: o.taxInclusive,
+		Total:        o.Total(),
+	}
+}
+
+// String renders the invoice as plain text, one entry per line
+func (i Invoice) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Invoice %s\n", i.OrderID)
+	fmt.Fprintf(&b, "Customer %s\n", i.CustomerID)
+	fmt.Fprintf(&b, "Issued %s\n", i.IssuedAt.Format(time.RFC3339))
+	for _, l := range i.Lines {
+		fmt.Fprintf(&b, "%d x %s @ %.2f = %.2f\n", l.Quantity, l.Name, l.UnitPrice, l.Total())
+	}
+	fmt.Fprintf(&b, "Subtotal %.2f\n", i.Subtotal)
+	for _, d := range i.Discounts {
+		fmt.Fprintf(&b, "Discount %s -%.2f\n", d.Description, d.Amount)
+	}
+	for _, t := range i.Taxes {
+		label := "Tax"
+		if i.TaxInclusive {
+			label = "Included tax"
+		}
+		taxed := t.Category
+		if t.Tag != "" {
+			taxed = fmt.Sprintf("%s (%s)", t.Category, t.Tag)
+		}
+		fmt.Fprintf(&b, "%s %s %v%% on %.2f = %.2f\n", label, taxed, t.Rate, t.Base, t.Amount)
+	}
+	fmt.Fprintf(&b, "Total %.2f\n", i.Total)
+	return b.String()
+}
@@ -0,0 +1,56 @@
+package memory
+
+import (
+	"sync"
+
+	"github.com/gegaryfa/tavern/domain/order"
+	"github.com/google/uuid"
+)
+
+type Repository struct {
+	orders map[uuid.UUID]order.Order
+	sync.Mutex
+}
+
+func New() *Repository {
+	return &Repository{
+		orders: make(map[uuid.UUID]order.Order),
+	}
+}
+
+func (r *Repository) Get(id uuid.UUID) (order.Order, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if o, ok := r.orders[id]; ok {
+		return o, nil
+	}
+	return order.Order{}, order.ErrOrderNotFound
+}
+
+func (r *Repository) Add(o order.Order) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if _, ok := r.orders[o.GetID()]; ok {
+		return order.ErrOrderAlreadyExist
+	}
+	r.orders[o.GetID()] = o
+	return nil
+}
+
+func (r *Repository) Update(o order.Order) error {
+	r.Lock()
+	defer r.Unlock()
+
+	stored, ok := r.orders[o.GetID()]
+	if !ok {
+		return order.ErrOrderNotFound
+	}
+	if stored.GetRevision() != o.GetRevision() {
+		return order.ErrOrderChanged
+	}
+	o.SetRevision(o.GetRevision() + 1)
+	r.orders[o.GetID()] = o
+	return nil
+}
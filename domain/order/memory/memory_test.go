@@ -0,0 +1,51 @@
+package memory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gegaryfa/tavern/domain/order"
+	"github.com/google/uuid"
+)
+
+func TestRepository(t *testing.T) {
+	repo := New()
+	o, err := order.NewOrder(uuid.New(), []order.Line{{ProductID: uuid.New(), Name: "Beer", Quantity: 1, UnitPrice: 1.99}}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.Update(o); err != order.ErrOrderNotFound {
+		t.Errorf("Expected error %v, got %v", order.ErrOrderNotFound, err)
+	}
+	if err := repo.Add(o); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Add(o); err != order.ErrOrderAlreadyExist {
+		t.Errorf("Expected error %v, got %v", order.ErrOrderAlreadyExist, err)
+	}
+
+	found, err := repo.Get(o.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.GetID() != o.GetID() || found.Total() != o.Total() {
+		t.Errorf("Expected %v, got %v", o, found)
+	}
+	// A copy read before the last update can not be stored
+	if err := found.StartPreparing(); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Update(found); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Cancel(); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Update(o); err != order.ErrOrderChanged {
+		t.Errorf("Expected error %v, got %v", order.ErrOrderChanged, err)
+	}
+	if _, err := repo.Get(uuid.New()); err != order.ErrOrderNotFound {
+		t.Errorf("Expected error %v, got %v", order.ErrOrderNotFound, err)
+	}
+}
@@ -0,0 +1,227 @@
+// Package order holds the order aggregate, the record of what a customer ordered and what was charged
+package order
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrMissingCustomer is returned when an order is created without a customer
+	ErrMissingCustomer = errors.New("an order has to belong to a customer")
+	// ErrNoLines is returned when an order is created without any products
+	ErrNoLines = errors.New("an order has to contain at least one product")
+	// ErrInvalidQuantity is returned when a line does not hold a positive quantity
+	ErrInvalidQuantity = errors.New("the quantity of a line has to be positive")
+	// ErrInvalidDiscount is returned when a discount is not positive or would make the order negative
+	ErrInvalidDiscount = errors.New("the discount is not valid for the order")
+)
+
+// Line is a value object holding a snapshot of an ordered product at the time of the order,
+// later changes to the product do not change what was ordered or charged
+type Line struct {
+	ProductID uuid.UUID
+	Name      string
+	// Category is the menu category of the product, such as drinks
+	Category string
+	// Tags are the labels of the product, such as alcoholic, some are taxed apart from their category
+	Tags     []string
+	Quantity int
+	// UnitPrice is the price that was effective when the order was placed
+	UnitPrice float64
+}
+
+// Total returns the price of the line
+func (l Line) Total() float64 {
+	return l.UnitPrice * float64(l.Quantity)
+}
+
+// Discount is a value object holding a promotion applied to the order
+type Discount struct {
+	// Rule is the name of the promotion that granted the discount
+	Rule string
+	// Description explains what the discount was granted for
+	Description string
+	// Amount is subtracted from the subtotal of the order
+	Amount float64
+	// Voucher is the code of the redeemed voucher that granted the discount, empty for promotions
+	Voucher string
+	// Points is what the customer paid for the discount in loyalty points, such as for a reward
+	Points int
+}
+
+// Tax is a value object holding the tax charged on the lines of a category
+type Tax struct {
+	Category string
+	// Tag is the product tag the lines were taxed for, such as alcoholic, empty when they were charged the rate
+	// of the category
+	Tag string
+	// Rate is the percentage charged, 24 means 24%
+	Rate float64
+	// Base is the amount the tax is charged on, after discounts and without the tax
+	Base float64
+	// Amount is the tax charged
+	Amount float64
+}
+
+// Order is the aggregate holding a single order of a customer
+type Order struct {
+	// id is the identifier of the order
+	id uuid.UUID
+	// customerID is the customer that placed the order
+	customerID uuid.UUID
+	// lines holds the ordered products
+	lines []Line
+	// createdAt is the instant the order was placed, the prices of the lines were effective at that instant
+	createdAt time.Time
+	// discounts holds the promotions applied to the order
+	discounts []Discount
+	// taxes holds the tax breakdown per category
+	taxes []Tax
+	// taxInclusive is set when the prices of the lines already contain the taxes
+	taxInclusive bool
+	// status is the stage of the lifecycle of the order
+	status Status
+	// refunds holds the money given back for the order
+	refunds []Refund
+	// stockReserved is set when the ordered products were taken out of stock for the order
+	stockReserved bool
+	// payers holds who paid the bill of the order, refunds are shared between them
+	payers []Payer
+	// revision counts the updates of the order, a copy read before the last update can not be stored
+	revision int
+}
+
+// NewOrder is a factory to create a new Order aggregate
+// It will validate that the order belongs to a customer and holds at least one line
+func NewOrder(customerID uuid.UUID, lines []Line, createdAt time.Time) (Order, error) {
+	if customerID == uuid.Nil {
+		return Order{}, ErrMissingCustomer
+	}
+	if len(lines) == 0 {
+		return Order{}, ErrNoLines
+	}
+	for _, l := range lines {
+		if l.Quantity <= 0 {
+			return Order{}, ErrInvalidQuantity
+		}
+	}
+
+	return Order{
+		id:         uuid.New(),
+		customerID: customerID,
+		lines:      lines,
+		createdAt:  createdAt,
+		status:     StatusPlaced,
+	}, nil
+}
+
+func (o Order) GetID() uuid.UUID {
+	return o.id
+}
+
+func (o *Order) SetID(id uuid.UUID) {
+	o.id = id
+}
+
+func (o Order) GetCustomerID() uuid.UUID {
+	return o.customerID
+}
+
+func (o *Order) SetCustomerID(id uuid.UUID) {
+	o.customerID = id
+}
+
+func (o Order) GetLines() []Line {
+	return o.lines
+}
+
+func (o *Order) SetLines(lines []Line) {
+	o.lines = lines
+}
+
+func (o Order) GetCreatedAt() time.Time {
+	return o.createdAt
+}
+
+func (o *Order) SetCreatedAt(createdAt time.Time) {
+	o.createdAt = createdAt
+}
+
+// ApplyDiscount subtracts a promotion from the order, the discounts can not exceed the subtotal
+func (o *Order) ApplyDiscount(d Discount) error {
+	// Amounts are compared in cents so that discounting exactly the net is not lost to float errors
+	if math.Round(d.Amount*100) <= 0 || math.Round(d.Amount*100) > math.Round(o.Net()*100) {
+		return ErrInvalidDiscount
+	}
+	discounts := make([]Discount, len(o.discounts), len(o.discounts)+1)
+	copy(discounts, o.discounts)
+	o.discounts = append(discounts, d)
+	return nil
+}
+
+func (o Order) GetDiscounts() []Discount {
+	return o.discounts
+}
+
+func (o *Order) SetDiscounts(discounts []Discount) {
+	o.discounts = discounts
+}
+
+// Subtotal returns the sum of all the lines
+func (o Order) Subtotal() float64 {
+	var subtotal float64
+	for _, l := range o.lines {
+		subtotal += l.Total()
+	}
+	return subtotal
+}
+
+// Discount returns the sum of all the applied discounts
+func (o Order) Discount() float64 {
+	var discount float64
+	for _, d := range o.discounts {
+		discount += d.Amount
+	}
+	return discount
+}
+
+// Net returns the subtotal minus the discounts, it contains the taxes only when the prices are tax inclusive
+func (o Order) Net() float64 {
+	return o.Subtotal() - o.Discount()
+}
+
+// ApplyTax replaces the tax breakdown of the order, inclusive tells whether the prices already contain the taxes
+func (o *Order) ApplyTax(taxes []Tax, inclusive bool) {
+	o.taxes = taxes
+	o.taxInclusive = inclusive
+}
+
+func (o Order) GetTaxes() []Tax {
+	return o.taxes
+}
+
+func (o Order) IsTaxInclusive() bool {
+	return o.taxInclusive
+}
+
+// Tax returns the sum of the taxes of the order
+func (o Order) Tax() float64 {
+	var tax float64
+	for _, t := range o.taxes {
+		tax += t.Amount
+	}
+	return tax
+}
+
+// Total returns what the customer has to pay, the subtotal minus the discounts plus the taxes
+// when they are not included in the prices
+func (o Order) Total() float64 {
+	if o.taxInclusive {
+		return o.Net()
+	}
+	return o.Net() + o.Tax()
+}
@@ -0,0 +1,133 @@
+package order
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestNewOrder(t *testing.T) {
+	beer := Line{ProductID: uuid.New(), Name: "Beer", Quantity: 2, UnitPrice: 1.5}
+
+	type testCase struct {
+		test        string
+		customerID  uuid.UUID
+		lines       []Line
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{
+			test:        "Missing customer",
+			customerID:  uuid.Nil,
+			lines:       []Line{beer},
+			expectedErr: ErrMissingCustomer,
+		}, {
+			test:        "No lines",
+			customerID:  uuid.New(),
+			expectedErr: ErrNoLines,
+		}, {
+			test:        "Empty line",
+			customerID:  uuid.New(),
+			lines:       []Line{{ProductID: uuid.New(), Name: "Wine"}},
+			expectedErr: ErrInvalidQuantity,
+		}, {
+			test:        "Valid order",
+			customerID:  uuid.New(),
+			lines:       []Line{beer},
+			expectedErr: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			_, err := NewOrder(tc.customerID, tc.lines, time.Now())
+			if err != tc.expectedErr {
+				t.Errorf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestOrder_Total(t *testing.T) {
+	o, err := NewOrder(uuid.New(), []Line{
+		{ProductID: uuid.New(), Name: "Beer", Quantity: 2, UnitPrice: 1.5},
+		{ProductID: uuid.New(), Name: "Peenuts", Quantity: 1, UnitPrice: 0.5},
+	}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.Total() != 3.5 {
+		t.Errorf("Expected total 3.5, got %v", o.Total())
+	}
+}
+
+func TestOrder_ApplyDiscount(t *testing.T) {
+	o, err := NewOrder(uuid.New(), []Line{
+		{ProductID: uuid.New(), Name: "Beer", Quantity: 2, UnitPrice: 1.5},
+	}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := o.ApplyDiscount(Discount{Rule: "happy hour", Amount: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.ApplyDiscount(Discount{Rule: "too generous", Amount: 2.5}); err != ErrInvalidDiscount {
+		t.Errorf("Expected error %v, got %v", ErrInvalidDiscount, err)
+	}
+	if err := o.ApplyDiscount(Discount{Rule: "negative", Amount: -1}); err != ErrInvalidDiscount {
+		t.Errorf("Expected error %v, got %v", ErrInvalidDiscount, err)
+	}
+	if o.Subtotal() != 3 || o.Discount() != 1 || o.Total() != 2 {
+		t.Errorf("Expected 3 - 1 = 2, got %v - %v = %v", o.Subtotal(), o.Discount(), o.Total())
+	}
+
+	// Copies of an order do not share the discounts they apply, even when the slice has room to spare
+	o.SetDiscounts(append(make([]Discount, 0, 4), o.GetDiscounts()...))
+	first, second := o, o
+	if err := first.ApplyDiscount(Discount{Rule: "first", Amount: 0.5}); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.ApplyDiscount(Discount{Rule: "second", Amount: 0.5}); err != nil {
+		t.Fatal(err)
+	}
+	if got := first.GetDiscounts(); len(got) != 2 || got[1].Rule != "first" {
+		t.Errorf("Expected the discount of the first copy to be kept, got %v", got)
+	}
+
+	// The lines add up to 0.7999999999999999, a 100% discount rounded to 0.8 takes all of it
+	all, err := NewOrder(uuid.New(), []Line{
+		{ProductID: uuid.New(), Name: "Crisps", Quantity: 1, UnitPrice: 0.7},
+		{ProductID: uuid.New(), Name: "Nuts", Quantity: 1, UnitPrice: 0.1},
+	}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := all.ApplyDiscount(Discount{Rule: "on the house", Amount: 0.8}); err != nil {
+		t.Errorf("Expected the whole net to be discountable, got %v", err)
+	}
+}
+
+func TestOrder_Invoice(t *testing.T) {
+	o, err := NewOrder(uuid.New(), []Line{
+		{ProductID: uuid.New(), Name: "Beer", Category: "drinks", Quantity: 2, UnitPrice: 5},
+	}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	o.ApplyTax([]Tax{{Category: "drinks", Rate: 24, Base: 10, Amount: 2.4}}, false)
+
+	invoice := o.Invoice()
+	if invoice.Total != 12.4 {
+		t.Errorf("Expected total 12.4, got %v", invoice.Total)
+	}
+	text := invoice.String()
+	for _, expected := range []string{"2 x Beer @ 5.00 = 10.00", "Tax drinks 24% on 10.00 = 2.40", "Total 12.40"} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("Expected %q in the invoice, got %s", expected, text)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package order
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrOrderNotFound is returned when an order is not found
+	ErrOrderNotFound = errors.New("the order was not found")
+	// ErrOrderAlreadyExist is returned when trying to add an order that already exists
+	ErrOrderAlreadyExist = errors.New("the order already exists")
+	// ErrOrderChanged is returned when updating an order that was updated since it was read
+	ErrOrderChanged = errors.New("the order was changed since it was read")
+)
+
+// Repository is the repository interface to fulfill to use the order aggregate
+type Repository interface {
+	Get(id uuid.UUID) (Order, error)
+	Add(order Order) error
+	// Update stores the order and bumps its revision, it fails with ErrOrderChanged when the stored order has
+	// another revision than the one given
+	Update(order Order) error
+}
+
+// changeAttempts is how many times Change reads the order when it keeps being changed concurrently
+const changeAttempts = 5
+
+// Change reads the order, applies change to it and stores it. When the order was changed concurrently, such as
+// cancelled while the kitchen prepares it, change is applied again to a fresh copy so that it sees the other change.
+func Change(r Repository, id uuid.UUID, change func(o *Order) error) (Order, error) {
+	for attempt := 1; ; attempt++ {
+		o, err := r.Get(id)
+		if err != nil {
+			return Order{}, err
+		}
+		if err := change(&o); err != nil {
+			return Order{}, err
+		}
+		err = r.Update(o)
+		if errors.Is(err, ErrOrderChanged) && attempt < changeAttempts {
+			continue
+		}
+		if err != nil {
+			return Order{}, err
+		}
+		o.SetRevision(o.GetRevision() + 1)
+		return o, nil
+	}
+}
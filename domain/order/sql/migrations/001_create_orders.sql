@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS orders (
+    id             TEXT PRIMARY KEY,
+    customer_id    TEXT NOT NULL,
+    -- created_at is in unix nanoseconds so that it compares the same on every database
+    created_at     BIGINT NOT NULL,
+    -- lines, discounts, taxes and refunds are JSON arrays, they are only ever read with their order
+    lines          TEXT NOT NULL,
+    discounts      TEXT NOT NULL DEFAULT '[]',
+    taxes          TEXT NOT NULL DEFAULT '[]',
+    tax_inclusive  BOOLEAN NOT NULL DEFAULT FALSE,
+    status         TEXT NOT NULL,
+    refunds        TEXT NOT NULL DEFAULT '[]',
+    stock_reserved BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX IF NOT EXISTS orders_customer_id ON orders (customer_id);
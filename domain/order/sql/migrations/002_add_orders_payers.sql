@@ -0,0 +1 @@
+ALTER TABLE orders ADD COLUMN payers TEXT NOT NULL DEFAULT '[]';
@@ -0,0 +1 @@
+ALTER TABLE orders ADD COLUMN revision INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,284 @@
+// Package sql is a database/sql implementation of the order repository.
+// It works with PostgreSQL and SQLite, the driver has to be registered by the caller.
+package sql
+
+import (
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"time"
+
+	"github.com/gegaryfa/tavern/domain/order"
+	"github.com/gegaryfa/tavern/internal/sqldb"
+	"github.com/google/uuid"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+type Repository struct {
+	db     *sql.DB
+	driver string
+}
+
+// New opens a connection using the given driver and applies the order schema migrations
+func New(driverName, dataSourceName string) (*Repository, error) {
+	db, err := sql.Open(driverName, dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+	return NewFromDB(db, driverName)
+}
+
+// NewFromDB creates a repository on top of an already opened database and applies the order schema migrations
+func NewFromDB(db *sql.DB, driverName string) (*Repository, error) {
+	sub, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	if err := sqldb.Migrate(db, driverName, "order", sub); err != nil {
+		return nil, err
+	}
+	return &Repository{db: db, driver: driverName}, nil
+}
+
+func (r *Repository) Get(id uuid.UUID) (order.Order, error) {
+	var (
+		customerID, lines, discounts, taxes, status, refunds, payers string
+		createdAt                                                    int64
+		taxInclusive, stockReserved                                  bool
+		revision                                                     int
+	)
+	err := r.db.QueryRow(
+		r.rebind(`SELECT customer_id, created_at, lines, discounts, taxes, tax_inclusive, status, refunds, stock_reserved, payers, revision
+			FROM orders WHERE id = ?`),
+		id.String(),
+	).Scan(&customerID, &createdAt, &lines, &discounts, &taxes, &taxInclusive, &status, &refunds, &stockReserved, &payers, &revision)
+	if errors.Is(err, sql.ErrNoRows) {
+		return order.Order{}, order.ErrOrderNotFound
+	}
+	if err != nil {
+		return order.Order{}, err
+	}
+
+	customer, err := uuid.Parse(customerID)
+	if err != nil {
+		return order.Order{}, err
+	}
+	var (
+		o          order.Order
+		sqlLines   []sqlLine
+		sqlDiscs   []sqlDiscount
+		sqlTaxes   []sqlTax
+		sqlRefunds []sqlRefund
+		sqlPayers  []sqlPayer
+	)
+	for _, column := range []struct {
+		encoded string
+		into    interface{}
+	}{{lines, &sqlLines}, {discounts, &sqlDiscs}, {taxes, &sqlTaxes}, {refunds, &sqlRefunds}, {payers, &sqlPayers}} {
+		if err := json.Unmarshal([]byte(column.encoded), column.into); err != nil {
+			return order.Order{}, err
+		}
+	}
+	o.SetID(id)
+	o.SetCustomerID(customer)
+	o.SetCreatedAt(time.Unix(0, createdAt).UTC())
+	o.SetLines(decodeLines(sqlLines))
+	o.SetDiscounts(decodeDiscounts(sqlDiscs))
+	o.ApplyTax(decodeTaxes(sqlTaxes), taxInclusive)
+	o.SetStatus(order.Status(status))
+	o.SetRefunds(decodeRefunds(sqlRefunds))
+	o.SetStockReserved(stockReserved)
+	o.SetPayers(decodePayers(sqlPayers))
+	o.SetRevision(revision)
+	return o, nil
+}
+
+func (r *Repository) Add(o order.Order) error {
+	result, err := r.db.Exec(
+		r.rebind(`INSERT INTO orders (id, customer_id, created_at, lines, discounts, taxes, tax_inclusive, status, refunds, stock_reserved, payers)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT (id) DO NOTHING`),
+		o.GetID().String(), o.GetCustomerID().String(), o.GetCreatedAt().UnixNano(), encodeLines(o.GetLines()),
+		encodeDiscounts(o.GetDiscounts()), encodeTaxes(o.GetTaxes()), o.IsTaxInclusive(), string(o.GetStatus()),
+		encodeRefunds(o.GetRefunds()), o.HasReservedStock(), encodePayers(o.GetPayers()),
+	)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return order.ErrOrderAlreadyExist
+	}
+	return nil
+}
+
+// Update stores everything but the customer and the creation time, which do not change once the order is placed
+func (r *Repository) Update(o order.Order) error {
+	result, err := r.db.Exec(
+		r.rebind(`UPDATE orders SET lines = ?, discounts = ?, taxes = ?, tax_inclusive = ?, status = ?, refunds = ?, stock_reserved = ?, payers = ?,
+			revision = revision + 1 WHERE id = ? AND revision = ?`),
+		encodeLines(o.GetLines()), encodeDiscounts(o.GetDiscounts()), encodeTaxes(o.GetTaxes()), o.IsTaxInclusive(),
+		string(o.GetStatus()), encodeRefunds(o.GetRefunds()), o.HasReservedStock(), encodePayers(o.GetPayers()), o.GetID().String(),
+		o.GetRevision(),
+	)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		// Nothing matched either because the order does not exist or because it has another revision
+		var count int
+		if err := r.db.QueryRow(r.rebind(`SELECT COUNT(*) FROM orders WHERE id = ?`), o.GetID().String()).Scan(&count); err != nil {
+			return err
+		}
+		if count == 0 {
+			return order.ErrOrderNotFound
+		}
+		return order.ErrOrderChanged
+	}
+	return nil
+}
+
+// sqlLine is the JSON representation of an order.Line in the lines column
+type sqlLine struct {
+	ProductID string   `json:"product_id"`
+	Name      string   `json:"name"`
+	Category  string   `json:"category,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+	Quantity  int      `json:"quantity"`
+	UnitPrice float64  `json:"unit_price"`
+}
+
+func encodeLines(lines []order.Line) string {
+	encoded := make([]sqlLine, 0, len(lines))
+	for _, l := range lines {
+		encoded = append(encoded, sqlLine{ProductID: l.ProductID.String(), Name: l.Name, Category: l.Category, Tags: l.Tags, Quantity: l.Quantity, UnitPrice: l.UnitPrice})
+	}
+	raw, _ := json.Marshal(encoded)
+	return string(raw)
+}
+
+func decodeLines(encoded []sqlLine) []order.Line {
+	lines := make([]order.Line, 0, len(encoded))
+	for _, l := range encoded {
+		// The product IDs were written from uuid.UUID values, they always parse
+		id, _ := uuid.Parse(l.ProductID)
+		lines = append(lines, order.Line{ProductID: id, Name: l.Name, Category: l.Category, Tags: l.Tags, Quantity: l.Quantity, UnitPrice: l.UnitPrice})
+	}
+	return lines
+}
+
+// sqlDiscount is the JSON representation of an order.Discount in the discounts column
+type sqlDiscount struct {
+	Rule        string  `json:"rule"`
+	Description string  `json:"description,omitempty"`
+	Amount      float64 `json:"amount"`
+	Voucher     string  `json:"voucher,omitempty"`
+	Points      int     `json:"points,omitempty"`
+}
+
+func encodeDiscounts(discounts []order.Discount) string {
+	encoded := make([]sqlDiscount, 0, len(discounts))
+	for _, d := range discounts {
+		encoded = append(encoded, sqlDiscount{Rule: d.Rule, Description: d.Description, Amount: d.Amount, Voucher: d.Voucher, Points: d.Points})
+	}
+	raw, _ := json.Marshal(encoded)
+	return string(raw)
+}
+
+func decodeDiscounts(encoded []sqlDiscount) []order.Discount {
+	discounts := make([]order.Discount, 0, len(encoded))
+	for _, d := range encoded {
+		discounts = append(discounts, order.Discount{Rule: d.Rule, Description: d.Description, Amount: d.Amount, Voucher: d.Voucher, Points: d.Points})
+	}
+	return discounts
+}
+
+// sqlTax is the JSON representation of an order.Tax in the taxes column
+type sqlTax struct {
+	Category string  `json:"category"`
+	Tag      string  `json:"tag,omitempty"`
+	Rate     float64 `json:"rate"`
+	Base     float64 `json:"base"`
+	Amount   float64 `json:"amount"`
+}
+
+func encodeTaxes(taxes []order.Tax) string {
+	encoded := make([]sqlTax, 0, len(taxes))
+	for _, t := range taxes {
+		encoded = append(encoded, sqlTax{Category: t.Category, Tag: t.Tag, Rate: t.Rate, Base: t.Base, Amount: t.Amount})
+	}
+	raw, _ := json.Marshal(encoded)
+	return string(raw)
+}
+
+func decodeTaxes(encoded []sqlTax) []order.Tax {
+	taxes := make([]order.Tax, 0, len(encoded))
+	for _, t := range encoded {
+		taxes = append(taxes, order.Tax{Category: t.Category, Tag: t.Tag, Rate: t.Rate, Base: t.Base, Amount: t.Amount})
+	}
+	return taxes
+}
+
+// sqlRefund is the JSON representation of an order.Refund in the refunds column
+type sqlRefund struct {
+	Amount float64   `json:"amount"`
+	Reason string    `json:"reason,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+func encodeRefunds(refunds []order.Refund) string {
+	encoded := make([]sqlRefund, 0, len(refunds))
+	for _, r := range refunds {
+		encoded = append(encoded, sqlRefund{Amount: r.Amount, Reason: r.Reason, At: r.At})
+	}
+	raw, _ := json.Marshal(encoded)
+	return string(raw)
+}
+
+func decodeRefunds(encoded []sqlRefund) []order.Refund {
+	refunds := make([]order.Refund, 0, len(encoded))
+	for _, r := range encoded {
+		refunds = append(refunds, order.Refund{Amount: r.Amount, Reason: r.Reason, At: r.At})
+	}
+	return refunds
+}
+
+// sqlPayer is the JSON representation of an order.Payer in the payers column
+type sqlPayer struct {
+	CustomerID string  `json:"customer_id"`
+	Amount     float64 `json:"amount"`
+	Points     int     `json:"points,omitempty"`
+}
+
+func encodePayers(payers []order.Payer) string {
+	encoded := make([]sqlPayer, 0, len(payers))
+	for _, p := range payers {
+		encoded = append(encoded, sqlPayer{CustomerID: p.CustomerID.String(), Amount: p.Amount, Points: p.Points})
+	}
+	raw, _ := json.Marshal(encoded)
+	return string(raw)
+}
+
+func decodePayers(encoded []sqlPayer) []order.Payer {
+	payers := make([]order.Payer, 0, len(encoded))
+	for _, p := range encoded {
+		// The customer IDs were written from uuid.UUID values, they always parse
+		id, _ := uuid.Parse(p.CustomerID)
+		payers = append(payers, order.Payer{CustomerID: id, Amount: p.Amount, Points: p.Points})
+	}
+	return payers
+}
+
+func (r *Repository) rebind(query string) string {
+	return sqldb.Rebind(r.driver, query)
+}
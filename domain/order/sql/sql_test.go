@@ -0,0 +1,94 @@
+package sql
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gegaryfa/tavern/domain/order"
+	"github.com/google/uuid"
+	_ "modernc.org/sqlite"
+)
+
+func TestSQLRepository(t *testing.T) {
+	now := time.Date(2022, 11, 1, 18, 0, 0, 0, time.UTC)
+	repo, err := New("sqlite", filepath.Join(t.TempDir(), "tavern.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := order.NewOrder(uuid.New(), []order.Line{
+		{ProductID: uuid.New(), Name: "Beer", Category: "drinks", Tags: []string{"vegan", "alcoholic"}, Quantity: 2, UnitPrice: 5},
+	}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o.SetStockReserved(true)
+
+	if err := repo.Update(o); err != order.ErrOrderNotFound {
+		t.Errorf("Expected error %v, got %v", order.ErrOrderNotFound, err)
+	}
+	if err := repo.Add(o); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Add(o); err != order.ErrOrderAlreadyExist {
+		t.Errorf("Expected error %v, got %v", order.ErrOrderAlreadyExist, err)
+	}
+
+	// Everything recorded after the order was placed is stored by Update
+	if err := o.ApplyDiscount(order.Discount{Rule: "voucher", Amount: 2, Voucher: "WELCOME"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.ApplyDiscount(order.Discount{Rule: "loyalty", Amount: 1, Points: 100}); err != nil {
+		t.Fatal(err)
+	}
+	o.SetPayers([]order.Payer{{CustomerID: o.GetCustomerID(), Amount: 2.5, Points: 250}, {CustomerID: uuid.New(), Amount: 2.5}})
+	o.ApplyTax([]order.Tax{{Category: "drinks", Tag: "alcoholic", Rate: 24, Base: 6.45, Amount: 1.55}}, true)
+	if err := o.Refund(order.Refund{Amount: 3, Reason: "spilled", At: now.Add(time.Minute)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Cancel(); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Update(o); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := repo.Get(o.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.GetCustomerID() != o.GetCustomerID() || !got.GetCreatedAt().Equal(now) || got.GetStatus() != order.StatusCancelled ||
+		!got.HasReservedStock() || !got.IsTaxInclusive() {
+		t.Errorf("Expected %v, got %v", o, got)
+	}
+	for _, field := range []struct {
+		name             string
+		expected, actual interface{}
+	}{
+		{"lines", o.GetLines(), got.GetLines()},
+		{"discounts", o.GetDiscounts(), got.GetDiscounts()},
+		{"taxes", o.GetTaxes(), got.GetTaxes()},
+		{"refunds", o.GetRefunds(), got.GetRefunds()},
+		{"payers", o.GetPayers(), got.GetPayers()},
+	} {
+		if !reflect.DeepEqual(field.expected, field.actual) {
+			t.Errorf("Expected %s %v, got %v", field.name, field.expected, field.actual)
+		}
+	}
+	if got.Total() != o.Total() || got.Refundable() != o.Refundable() {
+		t.Errorf("Expected total %v and refundable %v, got %v and %v", o.Total(), o.Refundable(), got.Total(), got.Refundable())
+	}
+
+	// A copy read before the last update can not be stored
+	if err := repo.Update(o); err != order.ErrOrderChanged {
+		t.Errorf("Expected error %v, got %v", order.ErrOrderChanged, err)
+	}
+	if err := repo.Update(got); err != nil {
+		t.Errorf("Expected the order read last to be stored, got %v", err)
+	}
+
+	if _, err := repo.Get(uuid.New()); err != order.ErrOrderNotFound {
+		t.Errorf("Expected error %v, got %v", order.ErrOrderNotFound, err)
+	}
+}
@@ -0,0 +1,210 @@
+package order
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrOrderServed is returned when cancelling an order that was already served
+	ErrOrderServed = errors.New("the order was already served")
+	// ErrOrderCancelled is returned when serving, preparing or cancelling an order that was cancelled or is being
+	// cancelled
+	ErrOrderCancelled = errors.New("the order was cancelled")
+	// ErrInvalidRefund is returned when a refund is not positive or exceeds what is left to refund
+	ErrInvalidRefund = errors.New("the refund is not valid for the order")
+)
+
+// Status is the stage of the lifecycle of an order
+type Status string
+
+const (
+	// StatusPlaced orders are waiting to be served
+	StatusPlaced Status = "placed"
+	// StatusPreparing orders are being prepared by the kitchen or the bar
+	StatusPreparing Status = "preparing"
+	// StatusReady orders were prepared and are waiting to be served
+	StatusReady Status = "ready"
+	// StatusServed orders were brought to the customer, they can no longer be cancelled
+	StatusServed Status = "served"
+	// StatusCancelling orders are being called off, they are refunded and what was redeemed for them is given
+	// back. Nothing else happens to them until they are cancelled.
+	StatusCancelling Status = "cancelling"
+	// StatusCancelled orders were called off before being served
+	StatusCancelled Status = "cancelled"
+)
+
+// Refund is a value object holding money given back to the customer for an order
+type Refund struct {
+	Amount float64
+	// Reason explains why the money was given back, such as a cancellation
+	Reason string
+	At     time.Time
+}
+
+// Payer is a value object holding the part of the bill of the order a customer paid and the loyalty points they
+// earned with it. A bill paying for several orders, such as a tab, is recorded on each of them.
+type Payer struct {
+	CustomerID uuid.UUID
+	Amount     float64
+	Points     int
+}
+
+func (o Order) GetRevision() int {
+	return o.revision
+}
+
+func (o *Order) SetRevision(revision int) {
+	o.revision = revision
+}
+
+func (o Order) GetStatus() Status {
+	return o.status
+}
+
+func (o *Order) SetStatus(status Status) {
+	o.status = status
+}
+
+// HasReservedStock reports whether the ordered products were taken out of stock for the order, they have to be
+// put back when it is cancelled
+func (o Order) HasReservedStock() bool {
+	return o.stockReserved
+}
+
+func (o *Order) SetStockReserved(reserved bool) {
+	o.stockReserved = reserved
+}
+
+// IsCancelled reports whether the order was called off or is being called off
+func (o Order) IsCancelled() bool {
+	return o.status == StatusCancelled || o.status == StatusCancelling
+}
+
+// Serve marks the order as brought to the customer
+func (o *Order) Serve() error {
+	if o.IsCancelled() {
+		return ErrOrderCancelled
+	}
+	o.status = StatusServed
+	return nil
+}
+
+// StartPreparing marks the order as being prepared, orders that are further along are left as they are
+func (o *Order) StartPreparing() error {
+	switch o.status {
+	case StatusCancelled, StatusCancelling:
+		return ErrOrderCancelled
+	case StatusPlaced:
+		o.status = StatusPreparing
+	}
+	return nil
+}
+
+// MarkReady marks the order as prepared, once everything on it is done
+func (o *Order) MarkReady() error {
+	switch o.status {
+	case StatusCancelled, StatusCancelling:
+		return ErrOrderCancelled
+	case StatusServed:
+		return ErrOrderServed
+	}
+	o.status = StatusReady
+	return nil
+}
+
+// Cancel calls the order off, only orders that were not served yet can be cancelled
+func (o *Order) Cancel() error {
+	switch o.status {
+	case StatusServed:
+		return ErrOrderServed
+	case StatusCancelled:
+		return ErrOrderCancelled
+	}
+	o.status = StatusCancelled
+	return nil
+}
+
+// StartCancelling starts calling the order off, see StatusCancelling. Starting again an order that is being
+// cancelled resumes its cancellation.
+func (o *Order) StartCancelling() error {
+	switch o.status {
+	case StatusServed:
+		return ErrOrderServed
+	case StatusCancelled:
+		return ErrOrderCancelled
+	}
+	o.status = StatusCancelling
+	return nil
+}
+
+// AbortCancelling puts an order that is being cancelled back at the status it was cancelled from, such as when
+// it could not be refunded. Orders that are not being cancelled are left as they are.
+func (o *Order) AbortCancelling(previous Status) error {
+	switch o.status {
+	case StatusCancelled:
+		return ErrOrderCancelled
+	case StatusCancelling:
+		o.status = previous
+	}
+	return nil
+}
+
+// Refund records money given back for the order, the refunds can not exceed the total of the order
+func (o *Order) Refund(r Refund) error {
+	// Amounts are compared in cents so that refunding exactly what is left is not lost to float errors
+	if r.Amount <= 0 || math.Round(r.Amount*100) > math.Round(o.Refundable()*100) {
+		return ErrInvalidRefund
+	}
+	refunds := make([]Refund, len(o.refunds), len(o.refunds)+1)
+	copy(refunds, o.refunds)
+	o.refunds = append(refunds, r)
+	return nil
+}
+
+func (o Order) GetRefunds() []Refund {
+	return o.refunds
+}
+
+func (o *Order) SetRefunds(refunds []Refund) {
+	o.refunds = refunds
+}
+
+// RevertRefund takes back a refund that was recorded but could not be paid, so that it can be made again
+func (o *Order) RevertRefund(r Refund) error {
+	for i := len(o.refunds) - 1; i >= 0; i-- {
+		if o.refunds[i].Amount == r.Amount && o.refunds[i].Reason == r.Reason && o.refunds[i].At.Equal(r.At) {
+			refunds := make([]Refund, 0, len(o.refunds)-1)
+			refunds = append(append(refunds, o.refunds[:i]...), o.refunds[i+1:]...)
+			o.refunds = refunds
+			return nil
+		}
+	}
+	return ErrInvalidRefund
+}
+
+func (o Order) GetPayers() []Payer {
+	return o.payers
+}
+
+// SetPayers records who paid the bill of the order
+func (o *Order) SetPayers(payers []Payer) {
+	o.payers = payers
+}
+
+// Refunded returns the sum of the refunds of the order
+func (o Order) Refunded() float64 {
+	var refunded float64
+	for _, r := range o.refunds {
+		refunded += r.Amount
+	}
+	return refunded
+}
+
+// Refundable returns what is left to refund of the total of the order
+func (o Order) Refundable() float64 {
+	return o.Total() - o.Refunded()
+}
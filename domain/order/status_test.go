@@ -0,0 +1,170 @@
+package order
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestOrder_Lifecycle(t *testing.T) {
+	newOrder := func(t *testing.T) Order {
+		o, err := NewOrder(uuid.New(), []Line{
+			{ProductID: uuid.New(), Name: "Beer", Quantity: 2, UnitPrice: 1.5},
+		}, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return o
+	}
+
+	type testCase struct {
+		test           string
+		operations     []func(o *Order) error
+		expectedErr    error
+		expectedStatus Status
+	}
+
+	testCases := []testCase{
+		{
+			test:           "Cancel a placed order",
+			operations:     []func(o *Order) error{(*Order).Cancel},
+			expectedStatus: StatusCancelled,
+		}, {
+			test:           "Cancel a served order",
+			operations:     []func(o *Order) error{(*Order).Serve, (*Order).Cancel},
+			expectedErr:    ErrOrderServed,
+			expectedStatus: StatusServed,
+		}, {
+			test:           "Cancel twice",
+			operations:     []func(o *Order) error{(*Order).Cancel, (*Order).Cancel},
+			expectedErr:    ErrOrderCancelled,
+			expectedStatus: StatusCancelled,
+		}, {
+			test:           "Serve a cancelled order",
+			operations:     []func(o *Order) error{(*Order).Cancel, (*Order).Serve},
+			expectedErr:    ErrOrderCancelled,
+			expectedStatus: StatusCancelled,
+		}, {
+			test:           "Prepare and serve an order",
+			operations:     []func(o *Order) error{(*Order).StartPreparing, (*Order).MarkReady, (*Order).StartPreparing, (*Order).Serve},
+			expectedStatus: StatusServed,
+		}, {
+			test:           "Prepare a cancelled order",
+			operations:     []func(o *Order) error{(*Order).Cancel, (*Order).StartPreparing},
+			expectedErr:    ErrOrderCancelled,
+			expectedStatus: StatusCancelled,
+		}, {
+			test:           "Serve an order being cancelled",
+			operations:     []func(o *Order) error{(*Order).StartCancelling, (*Order).Serve},
+			expectedErr:    ErrOrderCancelled,
+			expectedStatus: StatusCancelling,
+		}, {
+			test:           "Resume and finish a cancellation",
+			operations:     []func(o *Order) error{(*Order).StartCancelling, (*Order).StartCancelling, (*Order).Cancel},
+			expectedStatus: StatusCancelled,
+		}, {
+			test:           "Abort a cancellation",
+			operations:     []func(o *Order) error{(*Order).StartPreparing, (*Order).StartCancelling, func(o *Order) error { return o.AbortCancelling(StatusPreparing) }},
+			expectedStatus: StatusPreparing,
+		}, {
+			test:           "Ready a served order",
+			operations:     []func(o *Order) error{(*Order).Serve, (*Order).MarkReady},
+			expectedErr:    ErrOrderServed,
+			expectedStatus: StatusServed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			o := newOrder(t)
+			if o.GetStatus() != StatusPlaced {
+				t.Fatalf("Expected status %s, got %s", StatusPlaced, o.GetStatus())
+			}
+			var err error
+			for _, op := range tc.operations {
+				err = op(&o)
+			}
+			if err != tc.expectedErr {
+				t.Errorf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+			if o.GetStatus() != tc.expectedStatus {
+				t.Errorf("Expected status %s, got %s", tc.expectedStatus, o.GetStatus())
+			}
+		})
+	}
+}
+
+func TestOrder_Refund(t *testing.T) {
+	o, err := NewOrder(uuid.New(), []Line{
+		{ProductID: uuid.New(), Name: "Beer", Quantity: 3, UnitPrice: 1.1},
+	}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := o.Refund(Refund{Amount: 1.1, Reason: "spilled"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Refund(Refund{Amount: 2.3, Reason: "too much"}); err != ErrInvalidRefund {
+		t.Errorf("Expected error %v, got %v", ErrInvalidRefund, err)
+	}
+	if err := o.Refund(Refund{Amount: 0, Reason: "nothing"}); err != ErrInvalidRefund {
+		t.Errorf("Expected error %v, got %v", ErrInvalidRefund, err)
+	}
+	// What is left is 3.3 - 1.1, which is not exactly 2.2 in floats
+	if err := o.Refund(Refund{Amount: 2.2, Reason: "cancelled"}); err != nil {
+		t.Errorf("Expected the rest to be refundable, got %v", err)
+	}
+	if len(o.GetRefunds()) != 2 {
+		t.Errorf("Expected 2 refunds, got %v", o.GetRefunds())
+	}
+}
+
+// racingRepository holds one order and cancels it behind the back of the caller on the first updates
+type racingRepository struct {
+	Repository
+	stored Order
+	races  int
+}
+
+func (r *racingRepository) Get(uuid.UUID) (Order, error) {
+	return r.stored, nil
+}
+
+func (r *racingRepository) Update(o Order) error {
+	if r.races > 0 {
+		r.races--
+		r.stored.SetStatus(StatusCancelled)
+		r.stored.SetRevision(r.stored.GetRevision() + 1)
+	}
+	if o.GetRevision() != r.stored.GetRevision() {
+		return ErrOrderChanged
+	}
+	o.SetRevision(o.GetRevision() + 1)
+	r.stored = o
+	return nil
+}
+
+func TestChange(t *testing.T) {
+	o, err := NewOrder(uuid.New(), []Line{{ProductID: uuid.New(), Name: "Beer", Quantity: 1, UnitPrice: 1.99}}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The kitchen starting the order sees that it was cancelled concurrently instead of overwriting it
+	repo := &racingRepository{stored: o, races: 1}
+	if _, err := Change(repo, o.GetID(), (*Order).StartPreparing); err != ErrOrderCancelled {
+		t.Errorf("Expected error %v, got %v", ErrOrderCancelled, err)
+	}
+	if repo.stored.GetStatus() != StatusCancelled {
+		t.Errorf("Expected the order to stay cancelled, got %s", repo.stored.GetStatus())
+	}
+
+	// It gives up when the order keeps changing
+	repo = &racingRepository{stored: o, races: changeAttempts}
+	refund := func(o *Order) error { return o.Refund(Refund{Amount: 1, Reason: "spilled"}) }
+	if _, err := Change(repo, o.GetID(), refund); err != ErrOrderChanged {
+		t.Errorf("Expected error %v, got %v", ErrOrderChanged, err)
+	}
+}
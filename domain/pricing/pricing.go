@@ -0,0 +1,141 @@
+// Package pricing holds the promotions engine used to price orders.
+// Rules are evaluated in the order they were given to the engine, every unit of a product can only be
+// discounted by one rule, so the first rule to claim a unit wins. This keeps the outcome deterministic
+// and lets the explanation list exactly which rule discounted what.
+package pricing
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/gegaryfa/tavern/domain/order"
+	"github.com/google/uuid"
+)
+
+// ErrInvalidPercent is returned when a rule takes nothing or more than everything off
+var ErrInvalidPercent = errors.New("a discount percentage has to be over 0 and at most 100")
+
+// Rule is a single promotion
+type Rule interface {
+	// Name identifies the rule in the explanation of a price
+	Name() string
+	// Apply claims the units of the basket it discounts and returns the discounts it grants
+	Apply(b *Basket) []order.Discount
+}
+
+// Validator is fulfilled by the rules whose settings can be wrong, such as a percentage over 100
+type Validator interface {
+	Validate() error
+}
+
+// Validate checks the settings of the rules that can be checked, the error names the first invalid rule
+func Validate(rules ...Rule) error {
+	for _, rule := range rules {
+		if v, ok := rule.(Validator); ok {
+			if err := v.Validate(); err != nil {
+				return fmt.Errorf("%s: %w", rule.Name(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// Basket is the set of lines being priced, together with the units not yet claimed by a rule
+type Basket struct {
+	// At is the instant the order is placed
+	At        time.Time
+	Lines     []order.Line
+	available map[uuid.UUID]int
+}
+
+// NewBasket creates a basket where every unit is still available for discounts
+func NewBasket(lines []order.Line, at time.Time) *Basket {
+	available := make(map[uuid.UUID]int, len(lines))
+	for _, l := range lines {
+		available[l.ProductID] += l.Quantity
+	}
+	return &Basket{At: at, Lines: lines, available: available}
+}
+
+// Available returns how many units of the product have not been claimed by a rule yet
+func (b *Basket) Available(productID uuid.UUID) int {
+	return b.available[productID]
+}
+
+// Claim marks n units of the product as discounted so that later rules skip them
+func (b *Basket) Claim(productID uuid.UUID, n int) {
+	b.available[productID] -= n
+}
+
+// line returns the line of the product, the basket only holds one line per product
+func (b *Basket) line(productID uuid.UUID) (order.Line, bool) {
+	for _, l := range b.Lines {
+		if l.ProductID == productID {
+			return l, true
+		}
+	}
+	return order.Line{}, false
+}
+
+// Result is the outcome of pricing a basket
+type Result struct {
+	Subtotal float64
+	// Discounts holds one entry per applied rule, in evaluation order, it is the explanation of the price
+	Discounts []order.Discount
+}
+
+// Discount returns the sum of all the discounts
+func (r Result) Discount() float64 {
+	var discount float64
+	for _, d := range r.Discounts {
+		discount += d.Amount
+	}
+	return discount
+}
+
+// Total returns the subtotal minus the discounts
+func (r Result) Total() float64 {
+	return Round(r.Subtotal - r.Discount())
+}
+
+// Engine evaluates promotion rules against orders
+type Engine struct {
+	rules []Rule
+}
+
+// NewEngine creates an engine evaluating the rules in the given order
+func NewEngine(rules ...Rule) *Engine {
+	return &Engine{rules: rules}
+}
+
+// Price evaluates all the rules against the lines, at is the instant the order is placed
+func (e *Engine) Price(lines []order.Line, at time.Time) Result {
+	b := NewBasket(lines, at)
+
+	result := Result{}
+	for _, l := range lines {
+		result.Subtotal += l.Total()
+	}
+	result.Subtotal = Round(result.Subtotal)
+
+	for _, rule := range e.rules {
+		for _, d := range rule.Apply(b) {
+			d.Amount = Round(d.Amount)
+			if d.Amount <= 0 {
+				continue
+			}
+			if d.Rule == "" {
+				d.Rule = rule.Name()
+			}
+			result.Discounts = append(result.Discounts, d)
+		}
+	}
+	return result
+}
+
+// Round rounds an amount of money to cents
+func Round(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
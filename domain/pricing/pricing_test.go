@@ -0,0 +1,178 @@
+package pricing
+
+import (
+	"errors"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/gegaryfa/tavern/domain/order"
+	"github.com/google/uuid"
+)
+
+var (
+	beerID    = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	peanutsID = uuid.MustParse("00000000-0000-0000-0000-000000000002")
+	stewID    = uuid.MustParse("00000000-0000-0000-0000-000000000003")
+)
+
+func lines(beers, peanuts, stews int) []order.Line {
+	var lines []order.Line
+	if beers > 0 {
+		lines = append(lines, order.Line{ProductID: beerID, Name: "Beer", Category: "drinks", Quantity: beers, UnitPrice: 2})
+	}
+	if peanuts > 0 {
+		lines = append(lines, order.Line{ProductID: peanutsID, Name: "Peenuts", Category: "snacks", Quantity: peanuts, UnitPrice: 1})
+	}
+	if stews > 0 {
+		lines = append(lines, order.Line{ProductID: stewID, Name: "Stew", Category: "food", Quantity: stews, UnitPrice: 8})
+	}
+	return lines
+}
+
+func TestEngine_Price(t *testing.T) {
+	evening := time.Date(2022, 11, 1, 18, 0, 0, 0, time.UTC)
+	noon := time.Date(2022, 11, 1, 12, 0, 0, 0, time.UTC)
+
+	lateNight := time.Date(2022, 11, 2, 1, 0, 0, 0, time.UTC)
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The clocks went forward at 01:00, only 16 hours and a half passed since midnight
+	clocksForward := time.Date(2022, 3, 27, 17, 30, 0, 0, london)
+	lastOrders := TimeWindowDiscount{Label: "last orders", Percent: 50, Start: 22 * time.Hour, End: 2 * time.Hour}
+	happyHour := TimeWindowDiscount{Label: "happy hour", Percent: 50, Start: 17 * time.Hour, End: 19 * time.Hour, Category: "drinks"}
+	threeForTwo := BuyNGetM{Label: "3 for 2 beers", ProductID: beerID, Buy: 2, Free: 1}
+	beerAndPeanuts := Bundle{Label: "beer and peanuts", ProductIDs: []uuid.UUID{beerID, peanutsID}, Price: 2.5}
+	foodWeek := CategoryDiscount{Label: "food week", Category: "food", Percent: 10}
+
+	type testCase struct {
+		test          string
+		rules         []Rule
+		lines         []order.Line
+		at            time.Time
+		expectedTotal float64
+		expectedRules []string
+	}
+
+	testCases := []testCase{
+		{
+			test:          "No rules",
+			lines:         lines(2, 1, 0),
+			at:            noon,
+			expectedTotal: 5,
+		}, {
+			test:          "Happy hour outside the window",
+			rules:         []Rule{happyHour},
+			lines:         lines(2, 1, 0),
+			at:            noon,
+			expectedTotal: 5,
+		}, {
+			test:          "Happy hour only discounts drinks",
+			rules:         []Rule{happyHour},
+			lines:         lines(2, 1, 0),
+			at:            evening,
+			expectedTotal: 3,
+			expectedRules: []string{"happy hour"},
+		}, {
+			test:          "Happy hour on the day the clocks change",
+			rules:         []Rule{happyHour},
+			lines:         lines(2, 1, 0),
+			at:            clocksForward,
+			expectedTotal: 3,
+			expectedRules: []string{"happy hour"},
+		}, {
+			test:          "Window past midnight",
+			rules:         []Rule{lastOrders},
+			lines:         lines(2, 1, 0),
+			at:            lateNight,
+			expectedTotal: 2.5,
+			expectedRules: []string{"last orders"},
+		}, {
+			test:          "Window past midnight outside the window",
+			rules:         []Rule{lastOrders},
+			lines:         lines(2, 1, 0),
+			at:            evening,
+			expectedTotal: 5,
+		}, {
+			test:          "Buy 2 get 1 free",
+			rules:         []Rule{threeForTwo},
+			lines:         lines(7, 0, 0),
+			at:            noon,
+			expectedTotal: 10,
+			expectedRules: []string{"3 for 2 beers"},
+		}, {
+			test:          "Bundle",
+			rules:         []Rule{beerAndPeanuts},
+			lines:         lines(3, 2, 0),
+			at:            noon,
+			expectedTotal: 7,
+			expectedRules: []string{"beer and peanuts"},
+		}, {
+			test:          "Category",
+			rules:         []Rule{foodWeek},
+			lines:         lines(1, 0, 2),
+			at:            noon,
+			expectedTotal: 16.4,
+			expectedRules: []string{"food week"},
+		}, {
+			test:  "A unit is only discounted by the first rule claiming it",
+			rules: []Rule{beerAndPeanuts, threeForTwo, happyHour},
+			lines: lines(4, 1, 0),
+			at:    evening,
+			// one bundle for 2.5, three beers for the price of two for 4, nothing left for the happy hour
+			expectedTotal: 6.5,
+			expectedRules: []string{"beer and peanuts", "3 for 2 beers"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			result := NewEngine(tc.rules...).Price(tc.lines, tc.at)
+			if result.Total() != tc.expectedTotal {
+				t.Errorf("Expected total %v, got %v", tc.expectedTotal, result.Total())
+			}
+			var applied []string
+			for _, d := range result.Discounts {
+				applied = append(applied, d.Rule)
+				if d.Description == "" {
+					t.Errorf("Expected an explanation for %s", d.Rule)
+				}
+			}
+			if len(applied) != len(tc.expectedRules) {
+				t.Fatalf("Expected rules %v, got %v", tc.expectedRules, applied)
+			}
+			for i := range applied {
+				if applied[i] != tc.expectedRules[i] {
+					t.Errorf("Expected rules %v, got %v", tc.expectedRules, applied)
+				}
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	type testCase struct {
+		test        string
+		rule        Rule
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{test: "Valid percentage", rule: CategoryDiscount{Label: "food week", Category: "food", Percent: 10}},
+		{test: "Everything off", rule: TimeWindowDiscount{Label: "free hour", Percent: 100}},
+		{test: "Nothing off", rule: CategoryDiscount{Label: "food week", Category: "food"}, expectedErr: ErrInvalidPercent},
+		{test: "Negative percentage", rule: TimeWindowDiscount{Label: "happy hour", Percent: -50}, expectedErr: ErrInvalidPercent},
+		{test: "Over everything", rule: CategoryDiscount{Label: "food week", Category: "food", Percent: 110}, expectedErr: ErrInvalidPercent},
+		{test: "Rules without settings to check", rule: BuyNGetM{Label: "3 for 2 beers", ProductID: beerID, Buy: 2, Free: 1}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			if err := Validate(tc.rule); !errors.Is(err, tc.expectedErr) {
+				t.Errorf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
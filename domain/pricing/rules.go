@@ -0,0 +1,172 @@
+package pricing
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gegaryfa/tavern/domain/order"
+	"github.com/google/uuid"
+)
+
+// TimeWindowDiscount takes a percentage off every unit ordered between two times of the day, such as a happy hour.
+// When Category is set only products of that category are discounted.
+type TimeWindowDiscount struct {
+	Label string
+	// Percent is the discount, 25 means a quarter off
+	Percent float64
+	// Start and End are times of the day on the wall clock of the location of the order time, as durations
+	// since midnight. A window ending before it starts runs past midnight, such as from 22:00 to 02:00.
+	Start, End time.Duration
+	Category   string
+}
+
+func (r TimeWindowDiscount) Name() string {
+	return r.Label
+}
+
+func (r TimeWindowDiscount) Apply(b *Basket) []order.Discount {
+	// The wall clock is read rather than the time elapsed since midnight, which is an hour off on the days the
+	// clocks change
+	hour, min, sec := b.At.Clock()
+	sinceMidnight := time.Duration(hour)*time.Hour + time.Duration(min)*time.Minute + time.Duration(sec)*time.Second +
+		time.Duration(b.At.Nanosecond())
+	inWindow := sinceMidnight >= r.Start && sinceMidnight < r.End
+	if r.Start > r.End {
+		inWindow = sinceMidnight >= r.Start || sinceMidnight < r.End
+	}
+	if !inWindow {
+		return nil
+	}
+	return percentOff(b, r.Percent, r.Category, fmt.Sprintf("%v%% off", r.Percent))
+}
+
+func (r TimeWindowDiscount) Validate() error {
+	return validPercent(r.Percent)
+}
+
+// CategoryDiscount takes a percentage off every unit of a category
+type CategoryDiscount struct {
+	Label    string
+	Category string
+	// Percent is the discount, 25 means a quarter off
+	Percent float64
+}
+
+func (r CategoryDiscount) Name() string {
+	return r.Label
+}
+
+func (r CategoryDiscount) Apply(b *Basket) []order.Discount {
+	return percentOff(b, r.Percent, r.Category, fmt.Sprintf("%v%% off %s", r.Percent, r.Category))
+}
+
+func (r CategoryDiscount) Validate() error {
+	return validPercent(r.Percent)
+}
+
+// validPercent checks that a percentage takes something off without taking off more than everything
+func validPercent(percent float64) error {
+	if percent <= 0 || percent > 100 {
+		return ErrInvalidPercent
+	}
+	return nil
+}
+
+// percentOff discounts and claims every available unit, of the category when it is set
+func percentOff(b *Basket, percent float64, category, description string) []order.Discount {
+	var amount float64
+	for _, l := range b.Lines {
+		if category != "" && l.Category != category {
+			continue
+		}
+		n := b.Available(l.ProductID)
+		if n <= 0 {
+			continue
+		}
+		amount += float64(n) * l.UnitPrice * percent / 100
+		b.Claim(l.ProductID, n)
+	}
+	if amount == 0 {
+		return nil
+	}
+	return []order.Discount{{Description: description, Amount: amount}}
+}
+
+// BuyNGetM gives M units of a product for free for every N units bought, such as buy 2 get 1 free
+type BuyNGetM struct {
+	Label     string
+	ProductID uuid.UUID
+	Buy, Free int
+}
+
+func (r BuyNGetM) Name() string {
+	return r.Label
+}
+
+func (r BuyNGetM) Apply(b *Basket) []order.Discount {
+	if r.Buy <= 0 || r.Free <= 0 {
+		return nil
+	}
+	l, ok := b.line(r.ProductID)
+	if !ok {
+		return nil
+	}
+	groups := b.Available(r.ProductID) / (r.Buy + r.Free)
+	if groups == 0 {
+		return nil
+	}
+	b.Claim(r.ProductID, groups*(r.Buy+r.Free))
+	return []order.Discount{{
+		Description: fmt.Sprintf("buy %d get %d free on %s, %d times", r.Buy, r.Free, l.Name, groups),
+		Amount:      float64(groups*r.Free) * l.UnitPrice,
+	}}
+}
+
+// Bundle sells a set of products together for a fixed price, such as a beer with peanuts
+type Bundle struct {
+	Label      string
+	ProductIDs []uuid.UUID
+	Price      float64
+}
+
+func (r Bundle) Name() string {
+	return r.Label
+}
+
+func (r Bundle) Apply(b *Basket) []order.Discount {
+	if len(r.ProductIDs) == 0 {
+		return nil
+	}
+
+	// How many units each product of the bundle needs, a bundle may hold the same product twice
+	needed := map[uuid.UUID]int{}
+	var regular float64
+	for _, id := range r.ProductIDs {
+		l, ok := b.line(id)
+		if !ok {
+			return nil
+		}
+		needed[id]++
+		regular += l.UnitPrice
+	}
+	if regular <= r.Price {
+		return nil
+	}
+
+	bundles := -1
+	for id, n := range needed {
+		if fit := b.Available(id) / n; bundles == -1 || fit < bundles {
+			bundles = fit
+		}
+	}
+	if bundles <= 0 {
+		return nil
+	}
+	for id, n := range needed {
+		b.Claim(id, bundles*n)
+	}
+	return []order.Discount{{
+		Description: fmt.Sprintf("%d bundles for %.2f each", bundles, r.Price),
+		Amount:      float64(bundles) * (regular - r.Price),
+	}}
+}
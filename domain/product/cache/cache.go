@@ -0,0 +1,96 @@
+// Package cache is a read-through caching decorator for any product.Repository
+package cache
+
+import (
+	"time"
+
+	"github.com/gegaryfa/tavern/domain/product"
+	"github.com/gegaryfa/tavern/internal/lru"
+	"github.com/google/uuid"
+)
+
+// Stats holds the hit and miss counters of the cache
+type Stats = lru.Stats
+
+// Repository wraps another product.Repository and keeps recently read products in memory.
+// Writes go straight to the wrapped repository and invalidate the affected entries.
+type Repository struct {
+	next     product.Repository
+	products *lru.Cache[uuid.UUID, product.Product]
+	// all holds the result of GetAll under a single key
+	all *lru.Cache[struct{}, []product.Product]
+}
+
+// New wraps next with a cache holding at most size products for ttl
+func New(next product.Repository, size int, ttl time.Duration) *Repository {
+	return &Repository{
+		next:     next,
+		products: lru.New[uuid.UUID, product.Product](size, ttl),
+		all:      lru.New[struct{}, []product.Product](1, ttl),
+	}
+}
+
+func (r *Repository) GetAll() ([]product.Product, error) {
+	if products, ok := r.all.Get(struct{}{}); ok {
+		return products, nil
+	}
+
+	products, err := r.next.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	r.all.Add(struct{}{}, products)
+	return products, nil
+}
+
+func (r *Repository) GetByID(id uuid.UUID) (product.Product, error) {
+	if p, ok := r.products.Get(id); ok {
+		return p, nil
+	}
+
+	p, err := r.next.GetByID(id)
+	if err != nil {
+		return product.Product{}, err
+	}
+	r.products.Add(id, p)
+	return p, nil
+}
+
+func (r *Repository) Add(p product.Product) error {
+	if err := r.next.Add(p); err != nil {
+		return err
+	}
+	r.all.Purge()
+	return nil
+}
+
+func (r *Repository) Update(p product.Product) error {
+	// Invalidate before returning the error as well, the wrapped repository may have partially applied it
+	defer r.invalidate(p.GetID())
+	return r.next.Update(p)
+}
+
+func (r *Repository) Delete(id uuid.UUID) error {
+	defer r.invalidate(id)
+	return r.next.Delete(id)
+}
+
+// Find is not cached, pages depend on the whole catalog and would have to be invalidated on every write
+func (r *Repository) Find(query product.Query) (product.Page, error) {
+	return r.next.Find(query)
+}
+
+// Stats returns the combined cache counters of GetByID and GetAll
+func (r *Repository) Stats() Stats {
+	byID, all := r.products.Stats(), r.all.Stats()
+	return Stats{
+		Hits:      byID.Hits + all.Hits,
+		Misses:    byID.Misses + all.Misses,
+		Evictions: byID.Evictions + all.Evictions,
+	}
+}
+
+func (r *Repository) invalidate(id uuid.UUID) {
+	r.products.Remove(id)
+	r.all.Purge()
+}
@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gegaryfa/tavern/domain/product"
+	"github.com/gegaryfa/tavern/domain/product/memory"
+	"github.com/google/uuid"
+)
+
+func TestRepository_GetByID(t *testing.T) {
+	beer, err := product.NewProduct("Beer", "Healthy Beverage", 1.99)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backing := memory.New()
+	if err := backing.Add(beer); err != nil {
+		t.Fatal(err)
+	}
+	repo := New(backing, 10, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		if _, err := repo.GetByID(beer.GetID()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := repo.GetByID(uuid.New()); err != product.ErrProductNotFound {
+		t.Errorf("Expected error %v, got %v", product.ErrProductNotFound, err)
+	}
+
+	stats := repo.Stats()
+	if stats.Hits != 2 || stats.Misses != 2 {
+		t.Errorf("Expected 2 hits and 2 misses, got %+v", stats)
+	}
+}
+
+func TestRepository_Invalidation(t *testing.T) {
+	beer, err := product.NewProduct("Beer", "Healthy Beverage", 1.99)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := New(memory.New(), 10, time.Minute)
+	if err := repo.Add(beer); err != nil {
+		t.Fatal(err)
+	}
+
+	// Warm up the cache
+	if _, err := repo.GetByID(beer.GetID()); err != nil {
+		t.Fatal(err)
+	}
+	if all, err := repo.GetAll(); err != nil || len(all) != 1 {
+		t.Fatalf("Expected 1 product, got %d %v", len(all), err)
+	}
+
+	updated := product.Product{}
+	updated.SetID(beer.GetID())
+	updated.SetName("Beer")
+	updated.SetDescription("Healthy Beverage")
+	updated.SetPrice(2.49)
+	if err := repo.Update(updated); err != nil {
+		t.Fatal(err)
+	}
+	found, err := repo.GetByID(beer.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.GetPrice() != 2.49 {
+		t.Errorf("Expected the updated price 2.49, got %v", found.GetPrice())
+	}
+
+	if err := repo.Delete(beer.GetID()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.GetByID(beer.GetID()); err != product.ErrProductNotFound {
+		t.Errorf("Expected error %v, got %v", product.ErrProductNotFound, err)
+	}
+	if all, err := repo.GetAll(); err != nil || len(all) != 0 {
+		t.Errorf("Expected 0 products, got %d %v", len(all), err)
+	}
+}
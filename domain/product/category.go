@@ -0,0 +1,154 @@
+package product
+
+import (
+	"errors"
+	"sort"
+)
+
+var (
+	// ErrInvalidCategory is returned when a product is given a category the tavern does not know
+	ErrInvalidCategory = errors.New("the category is not valid")
+	// ErrInvalidTag is returned when a product is given a tag the tavern does not know
+	ErrInvalidTag = errors.New("the tag is not valid")
+	// ErrInvalidSection is returned when a product is given an empty menu section
+	ErrInvalidSection = errors.New("the menu section is not valid")
+)
+
+// Category is the kind of product, it decides where the product shows up on the menu
+type Category string
+
+const (
+	// CategoryNone is the category of products that were not categorized
+	CategoryNone   Category = ""
+	CategoryDrinks Category = "drinks"
+	CategorySnacks Category = "snacks"
+	CategoryFood   Category = "food"
+)
+
+// categories holds the valid categories in the order they appear on the menu
+var categories = []Category{CategoryDrinks, CategorySnacks, CategoryFood, CategoryNone}
+
+// Valid reports whether the category is known
+func (c Category) Valid() bool {
+	for _, known := range categories {
+		if c == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Tag is a label describing a property of a product
+type Tag string
+
+const (
+	TagVegan      Tag = "vegan"
+	TagAlcoholic  Tag = "alcoholic"
+	TagGlutenFree Tag = "gluten-free"
+)
+
+// Valid reports whether the tag is known
+func (t Tag) Valid() bool {
+	switch t {
+	case TagVegan, TagAlcoholic, TagGlutenFree:
+		return true
+	}
+	return false
+}
+
+// ProductConfiguration is an alias for a function that will take in a pointer to a Product and modify it
+type ProductConfiguration func(p *Product) error
+
+// WithCategory puts the product in the given category
+func WithCategory(category Category) ProductConfiguration {
+	return func(p *Product) error {
+		if !category.Valid() {
+			return ErrInvalidCategory
+		}
+		p.category = category
+		return nil
+	}
+}
+
+// WithTags labels the product with the given tags, duplicates are dropped
+func WithTags(tags ...Tag) ProductConfiguration {
+	return func(p *Product) error {
+		for _, tag := range tags {
+			if !tag.Valid() {
+				return ErrInvalidTag
+			}
+			if !p.HasTag(tag) {
+				p.tags = append(p.tags, tag)
+			}
+		}
+		return nil
+	}
+}
+
+// WithSection places the product under the given section of the menu, such as "Draught beers"
+func WithSection(section string) ProductConfiguration {
+	return func(p *Product) error {
+		if section == "" {
+			return ErrInvalidSection
+		}
+		p.section = section
+		return nil
+	}
+}
+
+// WithAgeRestriction only sells the product to customers of legal age
+func WithAgeRestriction() ProductConfiguration {
+	return func(p *Product) error {
+		p.ageRestricted = true
+		return nil
+	}
+}
+
+// MenuSection is a heading of the menu together with the products listed under it
+type MenuSection struct {
+	Category Category
+	Name     string
+	Products []Product
+}
+
+// NewMenu groups products into menu sections. Sections are ordered by category (drinks, snacks, food, the rest)
+// and then by name, products within a section are ordered by name.
+// Products without a section are listed under a section named after their category.
+func NewMenu(products []Product) []MenuSection {
+	type heading struct {
+		category Category
+		name     string
+	}
+	index := map[heading]int{}
+	var menu []MenuSection
+	for _, p := range products {
+		key := heading{category: p.category, name: p.section}
+		if key.name == "" {
+			key.name = string(p.category)
+		}
+		i, ok := index[key]
+		if !ok {
+			i = len(menu)
+			index[key] = i
+			menu = append(menu, MenuSection{Category: key.category, Name: key.name})
+		}
+		menu[i].Products = append(menu[i].Products, p)
+	}
+
+	rank := map[Category]int{}
+	for i, c := range categories {
+		rank[c] = i
+	}
+	sort.Slice(menu, func(i, j int) bool {
+		if menu[i].Category != menu[j].Category {
+			return rank[menu[i].Category] < rank[menu[j].Category]
+		}
+		return menu[i].Name < menu[j].Name
+	})
+	for _, section := range menu {
+		sort.Slice(section.Products, func(i, j int) bool {
+			return section.Products[i].item.Name < section.Products[j].item.Name
+		})
+	}
+	return menu
+}
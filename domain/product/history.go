@@ -0,0 +1,24 @@
+package product
+
+import "time"
+
+// ChangeKind is the kind of change made to a product
+type ChangeKind string
+
+const (
+	PriceChanged       ChangeKind = "price_changed"
+	PriceScheduled     ChangeKind = "price_scheduled"
+	Renamed            ChangeKind = "renamed"
+	DescriptionUpdated ChangeKind = "description_updated"
+	Discontinued       ChangeKind = "discontinued"
+	Reactivated        ChangeKind = "reactivated"
+)
+
+// Change is a value object recording a single change made through the product methods
+type Change struct {
+	Kind ChangeKind
+	// From and To hold the value before and after the change, formatted as text
+	From string
+	To   string
+	At   time.Time
+}
@@ -1,6 +1,7 @@
 package memory
 
 import (
+	"sort"
 	"sync"
 
 	"github.com/gegaryfa/tavern/domain/product"
@@ -18,7 +19,10 @@ func New() *Repository {
 	}
 }
 
-func (r Repository) GetAll() ([]product.Product, error) {
+func (r *Repository) GetAll() ([]product.Product, error) {
+	r.Lock()
+	defer r.Unlock()
+
 	var products []product.Product
 	for _, product := range r.products {
 		products = append(products, product)
@@ -27,7 +31,10 @@ func (r Repository) GetAll() ([]product.Product, error) {
 	return products, nil
 }
 
-func (r Repository) GetByID(id uuid.UUID) (product.Product, error) {
+func (r *Repository) GetByID(id uuid.UUID) (product.Product, error) {
+	r.Lock()
+	defer r.Unlock()
+
 	if product, ok := r.products[id]; ok {
 		return product, nil
 	}
@@ -35,7 +42,7 @@ func (r Repository) GetByID(id uuid.UUID) (product.Product, error) {
 
 }
 
-func (r Repository) Add(newProduct product.Product) error {
+func (r *Repository) Add(newProduct product.Product) error {
 	r.Lock()
 	defer r.Unlock()
 
@@ -48,7 +55,7 @@ func (r Repository) Add(newProduct product.Product) error {
 	return nil
 }
 
-func (r Repository) Update(upprod product.Product) error {
+func (r *Repository) Update(upprod product.Product) error {
 	r.Lock()
 	defer r.Unlock()
 
@@ -60,7 +67,7 @@ func (r Repository) Update(upprod product.Product) error {
 	return nil
 }
 
-func (r Repository) Delete(id uuid.UUID) error {
+func (r *Repository) Delete(id uuid.UUID) error {
 	r.Lock()
 	defer r.Unlock()
 
@@ -70,3 +77,45 @@ func (r Repository) Delete(id uuid.UUID) error {
 	delete(r.products, id)
 	return nil
 }
+
+// Find filters and sorts all the products, the id is used as tie breaker between equal sort keys
+func (r *Repository) Find(query product.Query) (product.Page, error) {
+	var after *product.Cursor
+	if query.Cursor != "" {
+		cursor, err := product.ParseCursor(query.Cursor)
+		if err != nil {
+			return product.Page{}, err
+		}
+		after = &cursor
+	}
+
+	r.Lock()
+	matches := make([]product.Product, 0)
+	for _, p := range r.products {
+		if query.Matches(p) {
+			matches = append(matches, p)
+		}
+	}
+	r.Unlock()
+
+	less := query.Sort.Less
+	if query.Descending {
+		less = func(a, b product.Cursor) bool { return query.Sort.Less(b, a) }
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return less(product.CursorOf(matches[i]), product.CursorOf(matches[j]))
+	})
+
+	page := product.Page{Products: make([]product.Product, 0)}
+	for _, p := range matches {
+		if after != nil && !less(*after, product.CursorOf(p)) {
+			continue
+		}
+		if len(page.Products) == query.GetLimit() {
+			page.NextCursor = product.CursorOf(page.Products[len(page.Products)-1]).Encode()
+			break
+		}
+		page.Products = append(page.Products, p)
+	}
+	return page, nil
+}
@@ -1,7 +1,9 @@
 package memory
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gegaryfa/tavern/domain/product"
 	"github.com/google/uuid"
@@ -81,3 +83,116 @@ func TestRepository_GetByID(t *testing.T) {
 		})
 	}
 }
+
+func TestRepository_Find(t *testing.T) {
+	repo := New()
+	catalog := []struct {
+		name, description string
+		price             float64
+		quantity          int
+		category          product.Category
+		tags              []product.Tag
+	}{
+		{"Beer", "Healthy Beverage", 1.99, 10, product.CategoryDrinks, []product.Tag{product.TagAlcoholic, product.TagVegan}},
+		{"Peenuts", "Healthy Snacks", 0.99, 0, product.CategorySnacks, []product.Tag{product.TagVegan, product.TagGlutenFree}},
+		{"Wine", "Red grapes", 4.99, 3, product.CategoryDrinks, []product.Tag{product.TagAlcoholic}},
+		{"Ale", "Dark beer", 2.49, 5, product.CategoryDrinks, []product.Tag{product.TagAlcoholic}},
+	}
+	now := time.Now()
+	for _, c := range catalog {
+		p, err := product.NewProduct(c.name, c.description, c.price, product.WithCategory(c.category), product.WithTags(c.tags...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.SetQuantity(c.quantity)
+		// The wine is on a happy hour right now, the queries only look at its list price
+		if c.name == "Wine" {
+			if err := p.SchedulePrice(1.49, now.Add(-time.Hour), now.Add(time.Hour), now); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := repo.Add(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	type testCase struct {
+		name     string
+		query    product.Query
+		expected []string
+	}
+
+	testCases := []testCase{
+		{
+			name:     "All products sorted by name",
+			query:    product.Query{},
+			expected: []string{"Ale", "Beer", "Peenuts", "Wine"},
+		}, {
+			name:     "Text matches name and description",
+			query:    product.Query{Text: "BEER"},
+			expected: []string{"Ale", "Beer"},
+		}, {
+			name:     "Price range",
+			query:    product.Query{MinPrice: 1, MaxPrice: 3},
+			expected: []string{"Ale", "Beer"},
+		}, {
+			name:     "Price range on the list price",
+			query:    product.Query{MaxPrice: 1.5},
+			expected: []string{"Peenuts"},
+		}, {
+			name:     "In stock only",
+			query:    product.Query{InStockOnly: true},
+			expected: []string{"Ale", "Beer", "Wine"},
+		}, {
+			name:     "Category",
+			query:    product.Query{Category: product.CategorySnacks},
+			expected: []string{"Peenuts"},
+		}, {
+			name:     "All tags have to match",
+			query:    product.Query{Tags: []product.Tag{product.TagAlcoholic, product.TagVegan}},
+			expected: []string{"Beer"},
+		}, {
+			name:     "Most expensive first",
+			query:    product.Query{Sort: product.SortByPrice, Descending: true},
+			expected: []string{"Wine", "Ale", "Beer", "Peenuts"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			page, err := repo.Find(tc.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := names(page.Products); strings.Join(got, ",") != strings.Join(tc.expected, ",") {
+				t.Errorf("Expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+
+	// Page through the catalog by price, one product at a time
+	var got []string
+	query := product.Query{Sort: product.SortByPrice, Limit: 1}
+	for {
+		page, err := repo.Find(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, names(page.Products)...)
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+	if expected := "Peenuts,Beer,Ale,Wine"; strings.Join(got, ",") != expected {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func names(products []product.Product) []string {
+	names := make([]string, 0, len(products))
+	for _, p := range products {
+		names = append(names, p.GetItem().Name)
+	}
+	return names
+}
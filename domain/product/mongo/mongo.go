@@ -0,0 +1,327 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"time"
+
+	"github.com/gegaryfa/tavern/domain/product"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type Repository struct {
+	db       *mongo.Database
+	products *mongo.Collection
+}
+
+// mongoProduct is an internal type that is used to store a Product aggregate
+// we make an internal struct for this to avoid coupling this mongo implementation to the product aggregate.
+// Mongo uses bson so we add tags for that
+type mongoProduct struct {
+	ID          uuid.UUID `bson:"id"`
+	Name        string    `bson:"name"`
+	Description string    `bson:"description"`
+	Price       float64   `bson:"price"`
+	Quantity    int       `bson:"quantity"`
+	Category    string    `bson:"category"`
+	Tags        []string  `bson:"tags"`
+	Section     string    `bson:"section"`
+	// Discontinued and History are missing from documents written before products could change
+	Discontinued bool          `bson:"discontinued"`
+	History      []mongoChange `bson:"history"`
+	// Prices is missing from documents written before prices were tracked over time
+	Prices []mongoPricePeriod `bson:"prices"`
+	// AgeRestricted is missing from documents written before products could be age restricted
+	AgeRestricted bool `bson:"age_restricted"`
+}
+
+// mongoPricePeriod is the embedded representation of a product.PricePeriod
+type mongoPricePeriod struct {
+	Amount float64   `bson:"amount"`
+	From   time.Time `bson:"from"`
+	To     time.Time `bson:"to"`
+}
+
+// mongoChange is the embedded representation of a product.Change
+type mongoChange struct {
+	Kind string    `bson:"kind"`
+	From string    `bson:"from"`
+	To   string    `bson:"to"`
+	At   time.Time `bson:"at"`
+}
+
+func NewFromProduct(p product.Product) mongoProduct {
+	tags := make([]string, 0, len(p.GetTags()))
+	for _, tag := range p.GetTags() {
+		tags = append(tags, string(tag))
+	}
+
+	history := make([]mongoChange, 0, len(p.GetHistory()))
+	for _, c := range p.GetHistory() {
+		history = append(history, mongoChange{Kind: string(c.Kind), From: c.From, To: c.To, At: c.At})
+	}
+
+	prices := make([]mongoPricePeriod, 0, len(p.GetPriceHistory()))
+	for _, pp := range p.GetPriceHistory() {
+		prices = append(prices, mongoPricePeriod{Amount: pp.Amount, From: pp.From, To: pp.To})
+	}
+
+	return mongoProduct{
+		ID:            p.GetID(),
+		Name:          p.GetItem().Name,
+		Description:   p.GetItem().Description,
+		Price:         p.GetPrice(),
+		Quantity:      p.GetQuantity(),
+		Category:      string(p.GetCategory()),
+		Tags:          tags,
+		Section:       p.GetSection(),
+		Discontinued:  p.IsDiscontinued(),
+		AgeRestricted: p.IsAgeRestricted(),
+		History:       history,
+		Prices:        prices,
+	}
+}
+
+// ToAggregate converts into a product.Product
+func (m mongoProduct) ToAggregate() product.Product {
+	p := product.Product{}
+
+	p.SetID(m.ID)
+	p.SetName(m.Name)
+	p.SetDescription(m.Description)
+	p.SetPrice(m.Price)
+	p.SetQuantity(m.Quantity)
+	p.SetCategory(product.Category(m.Category))
+	p.SetSection(m.Section)
+
+	tags := make([]product.Tag, 0, len(m.Tags))
+	for _, tag := range m.Tags {
+		tags = append(tags, product.Tag(tag))
+	}
+	p.SetTags(tags)
+
+	p.SetDiscontinued(m.Discontinued)
+	p.SetAgeRestricted(m.AgeRestricted)
+	history := make([]product.Change, 0, len(m.History))
+	for _, c := range m.History {
+		history = append(history, product.Change{Kind: product.ChangeKind(c.Kind), From: c.From, To: c.To, At: c.At})
+	}
+	p.SetHistory(history)
+
+	prices := make([]product.PricePeriod, 0, len(m.Prices))
+	for _, pp := range m.Prices {
+		prices = append(prices, product.PricePeriod{Amount: pp.Amount, From: pp.From, To: pp.To})
+	}
+	p.SetPriceHistory(prices)
+
+	return p
+}
+
+// New creates a new mongodb repository
+func New(ctx context.Context, connectionString string) (*Repository, error) {
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(connectionString))
+	if err != nil {
+		return nil, err
+	}
+
+	db := client.Database("ddd")
+	products := db.Collection("products")
+
+	// The product ID is the identity of the aggregate, so let mongo enforce that it is unique.
+	// The compound indexes back the sorted and paginated queries.
+	_, err = products.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		}, {
+			Keys: bson.D{{Key: "name", Value: 1}, {Key: "id", Value: 1}},
+		}, {
+			Keys: bson.D{{Key: "price", Value: 1}, {Key: "id", Value: 1}},
+		}, {
+			Keys: bson.D{{Key: "category", Value: 1}},
+		}, {
+			Keys: bson.D{{Key: "tags", Value: 1}},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Repository{
+		db:       db,
+		products: products,
+	}, nil
+}
+
+func (r *Repository) GetAll() ([]product.Product, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := r.products.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+
+	var internal []mongoProduct
+	if err := cursor.All(ctx, &internal); err != nil {
+		return nil, err
+	}
+
+	products := make([]product.Product, 0, len(internal))
+	for _, p := range internal {
+		products = append(products, p.ToAggregate())
+	}
+	return products, nil
+}
+
+func (r *Repository) GetByID(id uuid.UUID) (product.Product, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result := r.products.FindOne(ctx, bson.M{"id": id})
+
+	var p mongoProduct
+	err := result.Decode(&p)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return product.Product{}, product.ErrProductNotFound
+	}
+	if err != nil {
+		return product.Product{}, err
+	}
+	return p.ToAggregate(), nil
+}
+
+func (r *Repository) Add(p product.Product) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.products.InsertOne(ctx, NewFromProduct(p))
+	if mongo.IsDuplicateKeyError(err) {
+		return product.ErrProductAlreadyExist
+	}
+	return err
+}
+
+func (r *Repository) Update(p product.Product) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := r.products.ReplaceOne(ctx, bson.M{"id": p.GetID()}, NewFromProduct(p))
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return product.ErrProductNotFound
+	}
+	return nil
+}
+
+func (r *Repository) Delete(id uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := r.products.DeleteOne(ctx, bson.M{"id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return product.ErrProductNotFound
+	}
+	return nil
+}
+
+// Find uses keyset pagination on (sort field, id) so that pages stay stable while products are added
+func (r *Repository) Find(query product.Query) (product.Page, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filters := bson.A{}
+	if query.Text != "" {
+		text := primitive.Regex{Pattern: regexp.QuoteMeta(query.Text), Options: "i"}
+		filters = append(filters, bson.M{"$or": bson.A{
+			bson.M{"name": text},
+			bson.M{"description": text},
+		}})
+	}
+	if query.MinPrice > 0 {
+		filters = append(filters, bson.M{"price": bson.M{"$gte": query.MinPrice}})
+	}
+	if query.MaxPrice > 0 {
+		filters = append(filters, bson.M{"price": bson.M{"$lte": query.MaxPrice}})
+	}
+	if query.InStockOnly {
+		filters = append(filters, bson.M{"quantity": bson.M{"$gt": 0}})
+	}
+	if query.Category != product.CategoryNone {
+		filters = append(filters, bson.M{"category": string(query.Category)})
+	}
+	if len(query.Tags) > 0 {
+		tags := bson.A{}
+		for _, tag := range query.Tags {
+			tags = append(tags, string(tag))
+		}
+		filters = append(filters, bson.M{"tags": bson.M{"$all": tags}})
+	}
+
+	field := "name"
+	if query.Sort == product.SortByPrice {
+		field = "price"
+	}
+	direction, after := 1, "$gt"
+	if query.Descending {
+		direction, after = -1, "$lt"
+	}
+	if query.Cursor != "" {
+		cursor, err := product.ParseCursor(query.Cursor)
+		if err != nil {
+			return product.Page{}, err
+		}
+		id, err := uuid.Parse(cursor.ID)
+		if err != nil {
+			return product.Page{}, product.ErrInvalidCursor
+		}
+		var value interface{} = cursor.Name
+		if query.Sort == product.SortByPrice {
+			value = cursor.Price
+		}
+		filters = append(filters, bson.M{"$or": bson.A{
+			bson.M{field: bson.M{after: value}},
+			bson.M{field: value, "id": bson.M{after: id}},
+		}})
+	}
+
+	filter := bson.M{}
+	if len(filters) > 0 {
+		filter = bson.M{"$and": filters}
+	}
+
+	limit := query.GetLimit()
+	opts := options.Find().
+		SetSort(bson.D{{Key: field, Value: direction}, {Key: "id", Value: direction}}).
+		// Fetch one more than needed to know if there is a next page
+		SetLimit(int64(limit + 1))
+
+	cursor, err := r.products.Find(ctx, filter, opts)
+	if err != nil {
+		return product.Page{}, err
+	}
+	var internal []mongoProduct
+	if err := cursor.All(ctx, &internal); err != nil {
+		return product.Page{}, err
+	}
+
+	page := product.Page{Products: make([]product.Product, 0, len(internal))}
+	for i, m := range internal {
+		if i == limit {
+			page.NextCursor = product.CursorOf(page.Products[limit-1]).Encode()
+			break
+		}
+		page.Products = append(page.Products, m.ToAggregate())
+	}
+	return page, nil
+}
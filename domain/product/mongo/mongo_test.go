@@ -0,0 +1,45 @@
+package mongo
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gegaryfa/tavern/domain/product"
+)
+
+func TestMongoProduct_RoundTrip(t *testing.T) {
+	p, err := product.NewProduct("Beer", "Healthy Beverage", 1.99,
+		product.WithCategory(product.CategoryDrinks),
+		product.WithTags(product.TagAlcoholic, product.TagVegan),
+		product.WithSection("Beers"),
+		product.WithAgeRestriction(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.SetQuantity(5)
+
+	got := NewFromProduct(p).ToAggregate()
+
+	if got.GetID() != p.GetID() {
+		t.Errorf("Expected id %v, got %v", p.GetID(), got.GetID())
+	}
+	if *got.GetItem() != *p.GetItem() {
+		t.Errorf("Expected item %v, got %v", *p.GetItem(), *got.GetItem())
+	}
+	if got.GetPrice() != p.GetPrice() {
+		t.Errorf("Expected price %v, got %v", p.GetPrice(), got.GetPrice())
+	}
+	if got.GetCategory() != p.GetCategory() || got.GetSection() != p.GetSection() {
+		t.Errorf("Expected %v %v, got %v %v", p.GetCategory(), p.GetSection(), got.GetCategory(), got.GetSection())
+	}
+	if !reflect.DeepEqual(got.GetTags(), p.GetTags()) {
+		t.Errorf("Expected tags %v, got %v", p.GetTags(), got.GetTags())
+	}
+	if !got.IsAgeRestricted() {
+		t.Errorf("Expected the product to be age restricted")
+	}
+	if got.GetQuantity() != p.GetQuantity() {
+		t.Errorf("Expected quantity %v, got %v", p.GetQuantity(), got.GetQuantity())
+	}
+}
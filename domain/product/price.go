@@ -0,0 +1,87 @@
+package product
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrInvalidPricePeriod is returned when a scheduled price does not end after it starts
+var ErrInvalidPricePeriod = errors.New("a price period has to end after it starts")
+
+// PricePeriod is a value object holding the price of a product during a period of time
+type PricePeriod struct {
+	Amount float64
+	// From is the instant the price becomes effective, the zero time means since forever
+	From time.Time
+	// To is the instant the price stops being effective, the zero time means until it is replaced
+	To time.Time
+}
+
+// Covers reports whether the period is effective at the given instant
+func (pp PricePeriod) Covers(at time.Time) bool {
+	if at.Before(pp.From) {
+		return false
+	}
+	return pp.To.IsZero() || at.Before(pp.To)
+}
+
+// scheduled reports whether the period is a temporary price, such as a happy hour, instead of a regular one
+func (pp PricePeriod) scheduled() bool {
+	return !pp.To.IsZero()
+}
+
+// SchedulePrice sets a temporary price between from and to, such as a happy hour or a seasonal price.
+// Scheduled prices take precedence over the regular price while they are effective, at is when it was scheduled.
+func (p *Product) SchedulePrice(amount float64, from, to, at time.Time) error {
+	if amount < 0 {
+		return ErrInvalidPrice
+	}
+	if to.IsZero() || !to.After(from) {
+		return ErrInvalidPricePeriod
+	}
+	p.record(PriceScheduled, "", formatPrice(amount), at)
+	p.addPricePeriod(PricePeriod{Amount: amount, From: from, To: to})
+	return nil
+}
+
+// PriceAt returns the price effective at the given instant.
+// A scheduled price covering the instant wins over the regular price, when several do the one starting last wins.
+// Otherwise the last regular price set before the instant is used.
+func (p Product) PriceAt(at time.Time) float64 {
+	var (
+		found     *PricePeriod
+		scheduled bool
+	)
+	for i := range p.prices {
+		pp := p.prices[i]
+		if !pp.Covers(at) {
+			continue
+		}
+		switch {
+		case found == nil,
+			pp.scheduled() && !scheduled,
+			pp.scheduled() == scheduled && !pp.From.Before(found.From):
+			found, scheduled = &p.prices[i], pp.scheduled()
+		}
+	}
+	if found == nil {
+		return p.price
+	}
+	return found.Amount
+}
+
+// GetPriceHistory returns every price the product has had or is scheduled to have, in the order they were set
+func (p Product) GetPriceHistory() []PricePeriod {
+	return p.prices
+}
+
+func (p *Product) SetPriceHistory(prices []PricePeriod) {
+	p.prices = prices
+}
+
+func (p *Product) addPricePeriod(pp PricePeriod) {
+	// Copy the prices so that other copies of the product do not see the change
+	prices := make([]PricePeriod, len(p.prices), len(p.prices)+1)
+	copy(prices, p.prices)
+	p.prices = append(prices, pp)
+}
@@ -0,0 +1,135 @@
+package product
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProduct_PriceAt(t *testing.T) {
+	p, err := NewProduct("Beer", "Healthy Beverage", 1.99)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	day := time.Date(2022, 11, 1, 0, 0, 0, 0, time.UTC)
+	happyHour := day.Add(17 * time.Hour)
+	if err := p.SchedulePrice(0.99, happyHour, happyHour.Add(2*time.Hour), day); err != nil {
+		t.Fatal(err)
+	}
+	// A seasonal price spanning the whole day, the happy hour starts later so it wins while it lasts
+	if err := p.SchedulePrice(1.49, day, day.Add(24*time.Hour), day); err != nil {
+		t.Fatal(err)
+	}
+
+	type testCase struct {
+		test     string
+		at       time.Time
+		expected float64
+	}
+
+	testCases := []testCase{
+		{
+			test:     "regular price before the schedules",
+			at:       day.Add(-time.Hour),
+			expected: 1.99,
+		}, {
+			test:     "seasonal price",
+			at:       day.Add(12 * time.Hour),
+			expected: 1.49,
+		}, {
+			test:     "happy hour",
+			at:       happyHour.Add(30 * time.Minute),
+			expected: 0.99,
+		}, {
+			test:     "happy hour is over",
+			at:       happyHour.Add(2 * time.Hour),
+			expected: 1.49,
+		}, {
+			test:     "regular price after the schedules",
+			at:       day.Add(25 * time.Hour),
+			expected: 1.99,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			if got := p.PriceAt(tc.at); got != tc.expected {
+				t.Errorf("Expected price %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestProduct_ChangePriceKeepsHistory(t *testing.T) {
+	p, err := NewProduct("Beer", "Healthy Beverage", 1.99)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed := time.Date(2022, 11, 1, 18, 0, 0, 0, time.UTC)
+	if err := p.ChangePrice(2.49, changed); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := p.PriceAt(changed.Add(-time.Second)); got != 1.99 {
+		t.Errorf("Expected the old price 1.99 before the change, got %v", got)
+	}
+	if got := p.PriceAt(changed); got != 2.49 {
+		t.Errorf("Expected the new price 2.49 from the change, got %v", got)
+	}
+	if got := p.GetHistory(); len(got) != 1 || !got[0].At.Equal(changed) {
+		t.Errorf("Expected the change to be recorded at %v, got %v", changed, got)
+	}
+	if len(p.GetPriceHistory()) != 2 {
+		t.Errorf("Expected 2 prices in the history, got %v", p.GetPriceHistory())
+	}
+}
+
+func TestProduct_SchedulePrice(t *testing.T) {
+	now := time.Now()
+
+	type testCase struct {
+		test        string
+		amount      float64
+		from, to    time.Time
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{
+			test:        "should return error if price is negative",
+			amount:      -1,
+			from:        now,
+			to:          now.Add(time.Hour),
+			expectedErr: ErrInvalidPrice,
+		}, {
+			test:        "should return error if the period has no end",
+			amount:      1,
+			from:        now,
+			expectedErr: ErrInvalidPricePeriod,
+		}, {
+			test:        "should return error if the period ends before it starts",
+			amount:      1,
+			from:        now,
+			to:          now.Add(-time.Hour),
+			expectedErr: ErrInvalidPricePeriod,
+		}, {
+			test:        "valid period",
+			amount:      1,
+			from:        now,
+			to:          now.Add(time.Hour),
+			expectedErr: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			p, err := NewProduct("Beer", "Healthy Beverage", 1.99)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := p.SchedulePrice(tc.amount, tc.from, tc.to, now); err != tc.expectedErr {
+				t.Errorf("Expected error: %v, got: %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
@@ -2,6 +2,8 @@ package product
 
 import (
 	"errors"
+	"strconv"
+	"time"
 
 	"github.com/gegaryfa/tavern"
 	"github.com/google/uuid"
@@ -10,20 +12,49 @@ import (
 var (
 	// ErrMissingValues is returned when a product is created without a name or description
 	ErrMissingValues = errors.New("missing values")
+	// ErrInvalidPrice is returned when a product is given a negative price
+	ErrInvalidPrice = errors.New("the price must not be negative")
+	// ErrProductDiscontinued is returned when a discontinued product is ordered or discontinued again
+	ErrProductDiscontinued = errors.New("the product is discontinued")
+	// ErrProductNotDiscontinued is returned when reactivating a product that is still on sale
+	ErrProductNotDiscontinued = errors.New("the product is not discontinued")
+	// ErrOutOfStock is returned when reserving more of a product than is in stock
+	ErrOutOfStock = errors.New("the product is out of stock")
+	// ErrInvalidQuantity is returned when reserving or releasing a quantity that is not positive
+	ErrInvalidQuantity = errors.New("the quantity has to be positive")
 )
 
 type Product struct {
 	item     *tavern.Item
 	price    float64
 	quantity int
+	// category decides where the product is listed on the menu
+	category Category
+	// tags describe properties of the product, such as vegan or alcoholic
+	tags []Tag
+	// section is the heading of the menu the product is listed under
+	section string
+	// discontinued products stay in the repository but can no longer be ordered
+	discontinued bool
+	// history holds every change made through the product methods, oldest first
+	history []Change
+	// prices holds the regular and scheduled prices over time, price is the current regular price
+	prices []PricePeriod
+	// ageRestricted products are only sold to customers of legal age, such as alcoholic drinks
+	ageRestricted bool
 }
 
-func NewProduct(name, description string, price float64) (Product, error) {
+// NewProduct is a factory to create a new Product, the configurations set the optional
+// category, tags and menu section and validate them
+func NewProduct(name, description string, price float64, cfgs ...ProductConfiguration) (Product, error) {
 	if name == "" || description == "" {
 		return Product{}, ErrMissingValues
 	}
+	if price < 0 {
+		return Product{}, ErrInvalidPrice
+	}
 
-	return Product{
+	p := Product{
 		item: &tavern.Item{
 			ID:          uuid.New(),
 			Name:        name,
@@ -31,7 +62,17 @@ func NewProduct(name, description string, price float64) (Product, error) {
 		},
 		price:    price,
 		quantity: 0,
-	}, nil
+		tags:     make([]Tag, 0),
+		history:  make([]Change, 0),
+		// The first price is effective since forever so that any instant has a price
+		prices: []PricePeriod{{Amount: price}},
+	}
+	for _, cfg := range cfgs {
+		if err := cfg(&p); err != nil {
+			return Product{}, err
+		}
+	}
+	return p, nil
 }
 
 func (p Product) GetID() uuid.UUID {
@@ -45,3 +86,193 @@ func (p Product) GetItem() *tavern.Item {
 func (p Product) GetPrice() float64 {
 	return p.price
 }
+
+func (p *Product) SetID(id uuid.UUID) {
+	if p.item == nil {
+		p.item = &tavern.Item{}
+	}
+	p.item.ID = id
+}
+
+func (p *Product) SetName(name string) {
+	if p.item == nil {
+		p.item = &tavern.Item{}
+	}
+	p.item.Name = name
+}
+
+func (p *Product) SetDescription(description string) {
+	if p.item == nil {
+		p.item = &tavern.Item{}
+	}
+	p.item.Description = description
+}
+
+func (p *Product) SetPrice(price float64) {
+	p.price = price
+}
+
+func (p Product) GetQuantity() int {
+	return p.quantity
+}
+
+func (p *Product) SetQuantity(quantity int) {
+	p.quantity = quantity
+}
+
+// Reserve takes n of the product out of stock for an order
+func (p *Product) Reserve(n int) error {
+	if n <= 0 {
+		return ErrInvalidQuantity
+	}
+	if n > p.quantity {
+		return ErrOutOfStock
+	}
+	p.quantity -= n
+	return nil
+}
+
+// Release puts n of the product reserved for an order back in stock, such as when the order is cancelled
+func (p *Product) Release(n int) error {
+	if n <= 0 {
+		return ErrInvalidQuantity
+	}
+	p.quantity += n
+	return nil
+}
+
+func (p Product) GetCategory() Category {
+	return p.category
+}
+
+func (p *Product) SetCategory(category Category) {
+	p.category = category
+}
+
+func (p Product) GetTags() []Tag {
+	return p.tags
+}
+
+func (p *Product) SetTags(tags []Tag) {
+	p.tags = tags
+}
+
+// HasTag reports whether the product is labeled with tag
+func (p Product) HasTag(tag Tag) bool {
+	for _, t := range p.tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func (p Product) GetSection() string {
+	return p.section
+}
+
+func (p *Product) SetSection(section string) {
+	p.section = section
+}
+
+// ChangePrice sets a new price effective from at, prices can not be negative
+func (p *Product) ChangePrice(price float64, at time.Time) error {
+	if price < 0 {
+		return ErrInvalidPrice
+	}
+	p.record(PriceChanged, formatPrice(p.price), formatPrice(price), at)
+	if len(p.prices) == 0 {
+		// Products stored before prices were tracked only know their current price
+		p.addPricePeriod(PricePeriod{Amount: p.price})
+	}
+	p.addPricePeriod(PricePeriod{Amount: price, From: at})
+	p.price = price
+	return nil
+}
+
+// Rename gives the product a new name, at is when it was renamed
+func (p *Product) Rename(name string, at time.Time) error {
+	if name == "" {
+		return ErrMissingValues
+	}
+	p.record(Renamed, p.item.Name, name, at)
+	p.copyItem()
+	p.item.Name = name
+	return nil
+}
+
+// UpdateDescription gives the product a new description, at is when it was updated
+func (p *Product) UpdateDescription(description string, at time.Time) error {
+	if description == "" {
+		return ErrMissingValues
+	}
+	p.record(DescriptionUpdated, p.item.Description, description, at)
+	p.copyItem()
+	p.item.Description = description
+	return nil
+}
+
+// Discontinue takes the product off sale at the given instant, it can no longer be ordered
+func (p *Product) Discontinue(at time.Time) error {
+	if p.discontinued {
+		return ErrProductDiscontinued
+	}
+	p.record(Discontinued, "", "", at)
+	p.discontinued = true
+	return nil
+}
+
+// Reactivate puts a discontinued product back on sale at the given instant
+func (p *Product) Reactivate(at time.Time) error {
+	if !p.discontinued {
+		return ErrProductNotDiscontinued
+	}
+	p.record(Reactivated, "", "", at)
+	p.discontinued = false
+	return nil
+}
+
+// IsAgeRestricted reports whether the product is only sold to customers of legal age
+func (p Product) IsAgeRestricted() bool {
+	return p.ageRestricted
+}
+
+func (p *Product) SetAgeRestricted(restricted bool) {
+	p.ageRestricted = restricted
+}
+
+func (p Product) IsDiscontinued() bool {
+	return p.discontinued
+}
+
+func (p *Product) SetDiscontinued(discontinued bool) {
+	p.discontinued = discontinued
+}
+
+// GetHistory returns the changes made to the product, oldest first
+func (p Product) GetHistory() []Change {
+	return p.history
+}
+
+func (p *Product) SetHistory(history []Change) {
+	p.history = history
+}
+
+// record adds the change made at the given instant to the history, the instant comes from the caller so that
+// the history follows its clock
+func (p *Product) record(kind ChangeKind, from, to string, at time.Time) {
+	// Copy the history so that other copies of the product do not see the change
+	history := make([]Change, len(p.history), len(p.history)+1)
+	copy(history, p.history)
+	p.history = append(history, Change{Kind: kind, From: from, To: to, At: at})
+}
+
+// copyItem detaches the item from other copies of the product before it is changed
+func (p *Product) copyItem() {
+	item := *p.item
+	p.item = &item
+}
+
+func formatPrice(price float64) string {
+	return strconv.FormatFloat(price, 'f', -1, 64)
+}
@@ -3,6 +3,7 @@ package product
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/gegaryfa/tavern"
 	"github.com/google/uuid"
@@ -14,6 +15,7 @@ func TestNewProduct(t *testing.T) {
 		name        string
 		description string
 		price       float64
+		cfgs        []ProductConfiguration
 		expectedErr error
 	}
 
@@ -30,11 +32,47 @@ func TestNewProduct(t *testing.T) {
 			price:       1.0,
 			expectedErr: nil,
 		},
+		{
+			test:        "should return error if price is negative",
+			name:        "test",
+			description: "test",
+			price:       -1,
+			expectedErr: ErrInvalidPrice,
+		},
+		{
+			test:        "categorized and tagged",
+			name:        "test",
+			description: "test",
+			price:       1.0,
+			cfgs:        []ProductConfiguration{WithCategory(CategoryDrinks), WithTags(TagVegan, TagAlcoholic), WithSection("Beers")},
+			expectedErr: nil,
+		},
+		{
+			test:        "should return error if category is unknown",
+			name:        "test",
+			description: "test",
+			cfgs:        []ProductConfiguration{WithCategory("desserts")},
+			expectedErr: ErrInvalidCategory,
+		},
+		{
+			test:        "should return error if tag is unknown",
+			name:        "test",
+			description: "test",
+			cfgs:        []ProductConfiguration{WithTags("spicy")},
+			expectedErr: ErrInvalidTag,
+		},
+		{
+			test:        "should return error if section is empty",
+			name:        "test",
+			description: "test",
+			cfgs:        []ProductConfiguration{WithSection("")},
+			expectedErr: ErrInvalidSection,
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.test, func(t *testing.T) {
-			_, err := NewProduct(tc.name, tc.description, tc.price)
+			_, err := NewProduct(tc.name, tc.description, tc.price, tc.cfgs...)
 			if err != tc.expectedErr {
 				t.Errorf("Expected error: %v, got: %v", tc.expectedErr, err)
 			}
@@ -122,3 +160,150 @@ func TestProduct_GetPrice(t *testing.T) {
 		})
 	}
 }
+
+func TestNewMenu(t *testing.T) {
+	newProduct := func(name string, cfgs ...ProductConfiguration) Product {
+		p, err := NewProduct(name, name, 1, cfgs...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	menu := NewMenu([]Product{
+		newProduct("Stew", WithCategory(CategoryFood)),
+		newProduct("Wine", WithCategory(CategoryDrinks), WithSection("Wines")),
+		newProduct("Peenuts", WithCategory(CategorySnacks)),
+		newProduct("Stout", WithCategory(CategoryDrinks), WithSection("Beers")),
+		newProduct("Ale", WithCategory(CategoryDrinks), WithSection("Beers")),
+	})
+
+	expected := []string{"Beers: Ale Stout", "Wines: Wine", "snacks: Peenuts", "food: Stew"}
+	if len(menu) != len(expected) {
+		t.Fatalf("Expected %d sections, got %d", len(expected), len(menu))
+	}
+	for i, section := range menu {
+		got := section.Name + ":"
+		for _, p := range section.Products {
+			got += " " + p.GetItem().Name
+		}
+		if got != expected[i] {
+			t.Errorf("Expected section %q, got %q", expected[i], got)
+		}
+	}
+}
+
+func TestProduct_ChangePrice(t *testing.T) {
+	type testCase struct {
+		test        string
+		price       float64
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{
+			test:        "should return error if price is negative",
+			price:       -1,
+			expectedErr: ErrInvalidPrice,
+		},
+		{
+			test:        "free",
+			price:       0,
+			expectedErr: nil,
+		},
+		{
+			test:        "happy price",
+			price:       2.49,
+			expectedErr: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			p, err := NewProduct("Beer", "Healthy Beverage", 1.99)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = p.ChangePrice(tc.price, time.Now())
+			if err != tc.expectedErr {
+				t.Fatalf("Expected error: %v, got: %v", tc.expectedErr, err)
+			}
+			if err != nil {
+				if p.GetPrice() != 1.99 || len(p.GetHistory()) != 0 {
+					t.Errorf("Expected the product to be left untouched")
+				}
+				return
+			}
+			if p.GetPrice() != tc.price {
+				t.Errorf("Expected price %v, got %v", tc.price, p.GetPrice())
+			}
+			history := p.GetHistory()
+			if len(history) != 1 || history[0].Kind != PriceChanged || history[0].From != "1.99" {
+				t.Errorf("Expected a price change from 1.99 in the history, got %v", history)
+			}
+		})
+	}
+}
+
+func TestProduct_Rename(t *testing.T) {
+	p, err := NewProduct("Beer", "Healthy Beverage", 1.99)
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := p
+
+	if err := p.Rename("", time.Now()); err != ErrMissingValues {
+		t.Errorf("Expected error: %v, got: %v", ErrMissingValues, err)
+	}
+	if err := p.Rename("Lager", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.UpdateDescription("Cold", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	if p.GetItem().Name != "Lager" || p.GetItem().Description != "Cold" {
+		t.Errorf("Expected Lager/Cold, got %v/%v", p.GetItem().Name, p.GetItem().Description)
+	}
+	// Copies made before the change must not be affected
+	if original.GetItem().Name != "Beer" || len(original.GetHistory()) != 0 {
+		t.Errorf("Expected the original copy to be untouched, got %v %v", original.GetItem().Name, original.GetHistory())
+	}
+	if len(p.GetHistory()) != 2 {
+		t.Errorf("Expected 2 changes, got %v", p.GetHistory())
+	}
+}
+
+func TestProduct_Discontinue(t *testing.T) {
+	p, err := NewProduct("Beer", "Healthy Beverage", 1.99)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.Reactivate(time.Now()); err != ErrProductNotDiscontinued {
+		t.Errorf("Expected error: %v, got: %v", ErrProductNotDiscontinued, err)
+	}
+	if err := p.Discontinue(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if !p.IsDiscontinued() {
+		t.Errorf("Expected the product to be discontinued")
+	}
+	if err := p.Discontinue(time.Now()); err != ErrProductDiscontinued {
+		t.Errorf("Expected error: %v, got: %v", ErrProductDiscontinued, err)
+	}
+	if err := p.Reactivate(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if p.IsDiscontinued() {
+		t.Errorf("Expected the product to be on sale again")
+	}
+
+	var kinds []ChangeKind
+	for _, c := range p.GetHistory() {
+		kinds = append(kinds, c.Kind)
+	}
+	if !reflect.DeepEqual(kinds, []ChangeKind{Discontinued, Reactivated}) {
+		t.Errorf("Expected discontinued then reactivated, got %v", kinds)
+	}
+}
@@ -0,0 +1,140 @@
+package product
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// DefaultQueryLimit is the page size used when a Query has no limit
+const DefaultQueryLimit = 50
+
+// ErrInvalidCursor is returned when a Query holds a cursor that was not returned by the repository
+var ErrInvalidCursor = errors.New("the query cursor is not valid")
+
+// SortField is the field products are sorted by, ties are broken by the product ID
+type SortField int
+
+const (
+	// SortByName sorts products alphabetically
+	SortByName SortField = iota
+	// SortByPrice sorts products from cheap to expensive, by their list price
+	SortByPrice
+)
+
+// Query describes which products to find and how to order them
+type Query struct {
+	// Text only keeps products whose name or description contains it, case insensitive
+	Text string
+	// MinPrice only keeps products costing at least this much. The prices filtered and sorted on are the regular
+	// list prices, see GetPrice, scheduled prices such as a happy hour are left out so that a page does not
+	// depend on the instant it is read. PriceAt gives the price of the results at a given instant.
+	MinPrice float64
+	// MaxPrice only keeps products costing at most this much, 0 means there is no upper bound
+	MaxPrice float64
+	// InStockOnly only keeps products with a positive quantity
+	InStockOnly bool
+	// Category only keeps products of this category when it is set
+	Category Category
+	// Tags only keeps products labeled with all of them
+	Tags []Tag
+	// Sort is the field to order the results by
+	Sort SortField
+	// Descending reverses the order
+	Descending bool
+	// Limit is the maximum amount of products in a page, DefaultQueryLimit is used when it is not set
+	Limit int
+	// Cursor is the NextCursor of the previous page, empty for the first page
+	Cursor string
+}
+
+// Page is a single page of products
+type Page struct {
+	Products []Product
+	// NextCursor is passed in the next Query to get the following page, it is empty on the last page
+	NextCursor string
+}
+
+// GetLimit returns the page size of the query
+func (q Query) GetLimit() int {
+	if q.Limit <= 0 {
+		return DefaultQueryLimit
+	}
+	return q.Limit
+}
+
+// Matches reports whether the product passes the filters of the query, prices are compared on the list price
+func (q Query) Matches(p Product) bool {
+	if q.Text != "" {
+		text := strings.ToLower(q.Text)
+		if !strings.Contains(strings.ToLower(p.item.Name), text) &&
+			!strings.Contains(strings.ToLower(p.item.Description), text) {
+			return false
+		}
+	}
+	if p.price < q.MinPrice {
+		return false
+	}
+	if q.MaxPrice > 0 && p.price > q.MaxPrice {
+		return false
+	}
+	if q.InStockOnly && p.quantity <= 0 {
+		return false
+	}
+	if q.Category != CategoryNone && p.category != q.Category {
+		return false
+	}
+	for _, tag := range q.Tags {
+		if !p.HasTag(tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// Cursor is the position of the last product of a page, only the field the query sorts by is used
+type Cursor struct {
+	Name  string  `json:"n,omitempty"`
+	Price float64 `json:"p,omitempty"`
+	ID    string  `json:"i"`
+}
+
+// CursorOf returns the position of p
+func CursorOf(p Product) Cursor {
+	return Cursor{Name: p.item.Name, Price: p.price, ID: p.item.ID.String()}
+}
+
+// Less reports whether a comes before b when sorting by field in ascending order
+func (field SortField) Less(a, b Cursor) bool {
+	switch field {
+	case SortByPrice:
+		if a.Price != b.Price {
+			return a.Price < b.Price
+		}
+	default:
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+	}
+	return a.ID < b.ID
+}
+
+// Encode returns the opaque representation handed out in Page.NextCursor
+func (c Cursor) Encode() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// ParseCursor decodes a cursor created by Cursor.Encode
+func ParseCursor(s string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
@@ -20,4 +20,6 @@ type Repository interface {
 	Add(product Product) error
 	Update(product Product) error
 	Delete(id uuid.UUID) error
+	// Find returns a page of products matching the query
+	Find(query Query) (Page, error)
 }
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS products (
+    id          TEXT PRIMARY KEY,
+    name        TEXT NOT NULL,
+    description TEXT NOT NULL,
+    price       DOUBLE PRECISION NOT NULL,
+    quantity    INTEGER NOT NULL DEFAULT 0
+);
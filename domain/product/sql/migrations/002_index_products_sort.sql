@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS products_name_id ON products (name, id);
+CREATE INDEX IF NOT EXISTS products_price_id ON products (price, id);
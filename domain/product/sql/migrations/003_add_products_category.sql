@@ -0,0 +1,4 @@
+ALTER TABLE products ADD COLUMN category TEXT NOT NULL DEFAULT '';
+ALTER TABLE products ADD COLUMN section TEXT NOT NULL DEFAULT '';
+ALTER TABLE products ADD COLUMN tags TEXT NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS products_category ON products (category);
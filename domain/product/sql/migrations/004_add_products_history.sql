@@ -0,0 +1,2 @@
+ALTER TABLE products ADD COLUMN discontinued BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE products ADD COLUMN history TEXT NOT NULL DEFAULT '[]';
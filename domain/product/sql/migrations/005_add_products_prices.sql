@@ -0,0 +1 @@
+ALTER TABLE products ADD COLUMN prices TEXT NOT NULL DEFAULT '[]';
@@ -0,0 +1 @@
+ALTER TABLE products ADD COLUMN age_restricted BOOLEAN NOT NULL DEFAULT FALSE;
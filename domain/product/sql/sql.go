@@ -0,0 +1,342 @@
+// Package sql is a database/sql implementation of the product repository.
+// It works with PostgreSQL and SQLite, the driver has to be registered by the caller.
+package sql
+
+import (
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"time"
+
+	"github.com/gegaryfa/tavern/domain/product"
+	"github.com/gegaryfa/tavern/internal/sqldb"
+	"github.com/google/uuid"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+type Repository struct {
+	db     *sql.DB
+	driver string
+}
+
+// New opens a connection using the given driver and applies the product schema migrations
+func New(driverName, dataSourceName string) (*Repository, error) {
+	db, err := sql.Open(driverName, dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+	return NewFromDB(db, driverName)
+}
+
+// NewFromDB creates a repository on top of an already opened database and applies the product schema migrations
+func NewFromDB(db *sql.DB, driverName string) (*Repository, error) {
+	sub, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	if err := sqldb.Migrate(db, driverName, "product", sub); err != nil {
+		return nil, err
+	}
+	return &Repository{db: db, driver: driverName}, nil
+}
+
+// scanner is satisfied by both *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanProduct(s scanner) (product.Product, error) {
+	var (
+		id                string
+		name, description string
+		price             float64
+		quantity          int
+		category, section string
+		tags              string
+		discontinued      bool
+		history           string
+		prices            string
+		ageRestricted     bool
+	)
+	if err := s.Scan(&id, &name, &description, &price, &quantity, &category, &section, &tags, &discontinued, &history, &prices, &ageRestricted); err != nil {
+		return product.Product{}, err
+	}
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return product.Product{}, err
+	}
+
+	p := product.Product{}
+	p.SetID(uid)
+	p.SetName(name)
+	p.SetDescription(description)
+	p.SetPrice(price)
+	p.SetQuantity(quantity)
+	p.SetCategory(product.Category(category))
+	p.SetSection(section)
+	p.SetTags(decodeTags(tags))
+	p.SetDiscontinued(discontinued)
+	p.SetAgeRestricted(ageRestricted)
+
+	changes, err := decodeHistory(history)
+	if err != nil {
+		return product.Product{}, err
+	}
+	p.SetHistory(changes)
+
+	periods, err := decodePrices(prices)
+	if err != nil {
+		return product.Product{}, err
+	}
+	p.SetPriceHistory(periods)
+	return p, nil
+}
+
+// sqlPricePeriod is the JSON representation of a product.PricePeriod in the prices column
+type sqlPricePeriod struct {
+	Amount float64   `json:"amount"`
+	From   time.Time `json:"from"`
+	To     time.Time `json:"to"`
+}
+
+func encodePrices(prices []product.PricePeriod) string {
+	periods := make([]sqlPricePeriod, 0, len(prices))
+	for _, pp := range prices {
+		periods = append(periods, sqlPricePeriod{Amount: pp.Amount, From: pp.From, To: pp.To})
+	}
+	raw, _ := json.Marshal(periods)
+	return string(raw)
+}
+
+func decodePrices(encoded string) ([]product.PricePeriod, error) {
+	var periods []sqlPricePeriod
+	if err := json.Unmarshal([]byte(encoded), &periods); err != nil {
+		return nil, err
+	}
+	prices := make([]product.PricePeriod, 0, len(periods))
+	for _, pp := range periods {
+		prices = append(prices, product.PricePeriod{Amount: pp.Amount, From: pp.From, To: pp.To})
+	}
+	return prices, nil
+}
+
+// sqlChange is the JSON representation of a product.Change in the history column
+type sqlChange struct {
+	Kind string    `json:"kind"`
+	From string    `json:"from,omitempty"`
+	To   string    `json:"to,omitempty"`
+	At   time.Time `json:"at"`
+}
+
+func encodeHistory(history []product.Change) string {
+	changes := make([]sqlChange, 0, len(history))
+	for _, c := range history {
+		changes = append(changes, sqlChange{Kind: string(c.Kind), From: c.From, To: c.To, At: c.At})
+	}
+	raw, _ := json.Marshal(changes)
+	return string(raw)
+}
+
+func decodeHistory(encoded string) ([]product.Change, error) {
+	var changes []sqlChange
+	if err := json.Unmarshal([]byte(encoded), &changes); err != nil {
+		return nil, err
+	}
+	history := make([]product.Change, 0, len(changes))
+	for _, c := range changes {
+		history = append(history, product.Change{Kind: product.ChangeKind(c.Kind), From: c.From, To: c.To, At: c.At})
+	}
+	return history, nil
+}
+
+// encodeTags stores the tags in a single column as ",vegan,alcoholic," so that a tag can be matched with LIKE '%,vegan,%'
+func encodeTags(tags []product.Tag) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	encoded := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		encoded = append(encoded, string(tag))
+	}
+	return "," + strings.Join(encoded, ",") + ","
+}
+
+func decodeTags(encoded string) []product.Tag {
+	tags := make([]product.Tag, 0)
+	for _, tag := range strings.Split(strings.Trim(encoded, ","), ",") {
+		if tag != "" {
+			tags = append(tags, product.Tag(tag))
+		}
+	}
+	return tags
+}
+
+func (r *Repository) GetAll() ([]product.Product, error) {
+	rows, err := r.db.Query(`SELECT id, name, description, price, quantity, category, section, tags, discontinued, history, prices, age_restricted FROM products`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var products []product.Product
+	for rows.Next() {
+		p, err := scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+	}
+	return products, rows.Err()
+}
+
+func (r *Repository) GetByID(id uuid.UUID) (product.Product, error) {
+	row := r.db.QueryRow(r.rebind(`SELECT id, name, description, price, quantity, category, section, tags, discontinued, history, prices, age_restricted FROM products WHERE id = ?`), id.String())
+	p, err := scanProduct(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return product.Product{}, product.ErrProductNotFound
+	}
+	return p, err
+}
+
+func (r *Repository) Add(p product.Product) error {
+	result, err := r.db.Exec(
+		r.rebind(`INSERT INTO products (id, name, description, price, quantity, category, section, tags, discontinued, history, prices, age_restricted) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT (id) DO NOTHING`),
+		p.GetID().String(), p.GetItem().Name, p.GetItem().Description, p.GetPrice(), p.GetQuantity(),
+		string(p.GetCategory()), p.GetSection(), encodeTags(p.GetTags()), p.IsDiscontinued(), encodeHistory(p.GetHistory()),
+		encodePrices(p.GetPriceHistory()), p.IsAgeRestricted(),
+	)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return product.ErrProductAlreadyExist
+	}
+	return nil
+}
+
+func (r *Repository) Update(p product.Product) error {
+	result, err := r.db.Exec(
+		r.rebind(`UPDATE products SET name = ?, description = ?, price = ?, quantity = ?, category = ?, section = ?, tags = ?, discontinued = ?, history = ?, prices = ?, age_restricted = ? WHERE id = ?`),
+		p.GetItem().Name, p.GetItem().Description, p.GetPrice(), p.GetQuantity(),
+		string(p.GetCategory()), p.GetSection(), encodeTags(p.GetTags()), p.IsDiscontinued(), encodeHistory(p.GetHistory()),
+		encodePrices(p.GetPriceHistory()), p.IsAgeRestricted(), p.GetID().String(),
+	)
+	if err != nil {
+		return err
+	}
+	return notFoundIfNoRows(result)
+}
+
+func (r *Repository) Delete(id uuid.UUID) error {
+	result, err := r.db.Exec(r.rebind(`DELETE FROM products WHERE id = ?`), id.String())
+	if err != nil {
+		return err
+	}
+	return notFoundIfNoRows(result)
+}
+
+// Find uses keyset pagination on (sort field, id) so that pages stay stable while products are added
+func (r *Repository) Find(query product.Query) (product.Page, error) {
+	var (
+		where []string
+		args  []interface{}
+	)
+	if query.Text != "" {
+		text := "%" + sqldb.EscapeLike(strings.ToLower(query.Text)) + "%"
+		where = append(where, `(LOWER(name) LIKE ? ESCAPE '\' OR LOWER(description) LIKE ? ESCAPE '\')`)
+		args = append(args, text, text)
+	}
+	if query.MinPrice > 0 {
+		where = append(where, `price >= ?`)
+		args = append(args, query.MinPrice)
+	}
+	if query.MaxPrice > 0 {
+		where = append(where, `price <= ?`)
+		args = append(args, query.MaxPrice)
+	}
+	if query.InStockOnly {
+		where = append(where, `quantity > 0`)
+	}
+	if query.Category != product.CategoryNone {
+		where = append(where, `category = ?`)
+		args = append(args, string(query.Category))
+	}
+	for _, tag := range query.Tags {
+		where = append(where, `tags LIKE ? ESCAPE '\'`)
+		args = append(args, "%,"+sqldb.EscapeLike(string(tag))+",%")
+	}
+
+	field := "name"
+	if query.Sort == product.SortByPrice {
+		field = "price"
+	}
+	direction, after := "ASC", ">"
+	if query.Descending {
+		direction, after = "DESC", "<"
+	}
+	if query.Cursor != "" {
+		cursor, err := product.ParseCursor(query.Cursor)
+		if err != nil {
+			return product.Page{}, err
+		}
+		var value interface{} = cursor.Name
+		if query.Sort == product.SortByPrice {
+			value = cursor.Price
+		}
+		where = append(where, fmt.Sprintf(`(%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))`, field, after))
+		args = append(args, value, value, cursor.ID)
+	}
+
+	stmt := `SELECT id, name, description, price, quantity, category, section, tags, discontinued, history, prices, age_restricted FROM products`
+	if len(where) > 0 {
+		stmt += ` WHERE ` + strings.Join(where, ` AND `)
+	}
+	// Fetch one more than needed to know if there is a next page
+	limit := query.GetLimit()
+	stmt += fmt.Sprintf(` ORDER BY %[1]s %[2]s, id %[2]s LIMIT %[3]d`, field, direction, limit+1)
+
+	rows, err := r.db.Query(r.rebind(stmt), args...)
+	if err != nil {
+		return product.Page{}, err
+	}
+	defer rows.Close()
+
+	page := product.Page{Products: make([]product.Product, 0)}
+	for rows.Next() {
+		if len(page.Products) == limit {
+			page.NextCursor = product.CursorOf(page.Products[limit-1]).Encode()
+			break
+		}
+		p, err := scanProduct(rows)
+		if err != nil {
+			return product.Page{}, err
+		}
+		page.Products = append(page.Products, p)
+	}
+	return page, rows.Err()
+}
+
+func notFoundIfNoRows(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return product.ErrProductNotFound
+	}
+	return nil
+}
+
+func (r *Repository) rebind(query string) string {
+	return sqldb.Rebind(r.driver, query)
+}
@@ -0,0 +1,233 @@
+package sql
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gegaryfa/tavern/domain/product"
+	"github.com/google/uuid"
+	_ "modernc.org/sqlite"
+)
+
+func newTestRepository(t *testing.T) *Repository {
+	repo, err := New("sqlite", filepath.Join(t.TempDir(), "tavern.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repo
+}
+
+func TestRepository_Add(t *testing.T) {
+	repo := newTestRepository(t)
+	beer, err := product.NewProduct("Beer", "Healthy Beverage", 1.99)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.Add(beer); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Add(beer); err != product.ErrProductAlreadyExist {
+		t.Errorf("Expected error %v, got %v", product.ErrProductAlreadyExist, err)
+	}
+
+	all, err := repo.GetAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 1 {
+		t.Errorf("Expected 1 product, got %d", len(all))
+	}
+}
+
+func TestRepository_GetByID(t *testing.T) {
+	repo := newTestRepository(t)
+	beer, err := product.NewProduct("Beer", "Healthy Beverage", 1.99)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Add(beer); err != nil {
+		t.Fatal(err)
+	}
+
+	type testCase struct {
+		name        string
+		id          uuid.UUID
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{
+			name:        "Get product by id",
+			id:          beer.GetID(),
+			expectedErr: nil,
+		}, {
+			name:        "Get non-existing product by id",
+			id:          uuid.New(),
+			expectedErr: product.ErrProductNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := repo.GetByID(tc.id)
+			if err != tc.expectedErr {
+				t.Errorf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestRepository_UpdateDelete(t *testing.T) {
+	repo := newTestRepository(t)
+	beer, err := product.NewProduct("Beer", "Healthy Beverage", 1.99)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Add(beer); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := beer.ChangePrice(2.49, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if err := beer.Discontinue(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	beer.SetAgeRestricted(true)
+	if err := repo.Update(beer); err != nil {
+		t.Fatal(err)
+	}
+	found, err := repo.GetByID(beer.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.GetPrice() != 2.49 {
+		t.Errorf("Expected price 2.49, got %v", found.GetPrice())
+	}
+	if !found.IsDiscontinued() {
+		t.Errorf("Expected the product to be discontinued")
+	}
+	if !found.IsAgeRestricted() {
+		t.Errorf("Expected the product to be age restricted")
+	}
+	if len(found.GetHistory()) != 2 || found.GetHistory()[0].Kind != product.PriceChanged {
+		t.Errorf("Expected the history to be stored, got %v", found.GetHistory())
+	}
+
+	if err := repo.Delete(beer.GetID()); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Delete(beer.GetID()); err != product.ErrProductNotFound {
+		t.Errorf("Expected error %v, got %v", product.ErrProductNotFound, err)
+	}
+}
+
+func TestRepository_Find(t *testing.T) {
+	repo := newTestRepository(t)
+	catalog := []struct {
+		name, description string
+		price             float64
+		quantity          int
+		category          product.Category
+		tags              []product.Tag
+	}{
+		{"Beer", "Healthy Beverage", 1.99, 10, product.CategoryDrinks, []product.Tag{product.TagAlcoholic, product.TagVegan}},
+		{"Peenuts", "Healthy Snacks", 0.99, 0, product.CategorySnacks, []product.Tag{product.TagVegan, product.TagGlutenFree}},
+		{"Wine", "Red grapes", 4.99, 3, product.CategoryDrinks, []product.Tag{product.TagAlcoholic}},
+		{"Ale", "Dark beer", 2.49, 5, product.CategoryDrinks, []product.Tag{product.TagAlcoholic}},
+	}
+	now := time.Now()
+	for _, c := range catalog {
+		p, err := product.NewProduct(c.name, c.description, c.price, product.WithCategory(c.category), product.WithTags(c.tags...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.SetQuantity(c.quantity)
+		// The wine is on a happy hour right now, the queries only look at its list price
+		if c.name == "Wine" {
+			if err := p.SchedulePrice(1.49, now.Add(-time.Hour), now.Add(time.Hour), now); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := repo.Add(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	type testCase struct {
+		name     string
+		query    product.Query
+		expected string
+	}
+
+	testCases := []testCase{
+		{
+			name:     "Text matches name and description",
+			query:    product.Query{Text: "BEER"},
+			expected: "Ale,Beer",
+		}, {
+			name:     "Price range in stock",
+			query:    product.Query{MaxPrice: 3, InStockOnly: true},
+			expected: "Ale,Beer",
+		}, {
+			name:     "Price range on the list price",
+			query:    product.Query{MaxPrice: 1.5},
+			expected: "Peenuts",
+		}, {
+			name:     "Category",
+			query:    product.Query{Category: product.CategorySnacks},
+			expected: "Peenuts",
+		}, {
+			name:     "All tags have to match",
+			query:    product.Query{Tags: []product.Tag{product.TagAlcoholic, product.TagVegan}},
+			expected: "Beer",
+		}, {
+			name:     "Wildcards in tags are matched literally",
+			query:    product.Query{Tags: []product.Tag{"%"}},
+			expected: "",
+		}, {
+			name:     "Most expensive first",
+			query:    product.Query{Sort: product.SortByPrice, Descending: true},
+			expected: "Wine,Ale,Beer,Peenuts",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			page, err := repo.Find(tc.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, p := range page.Products {
+				got = append(got, p.GetItem().Name)
+			}
+			if strings.Join(got, ",") != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+
+	// Page through the catalog by price, one product at a time
+	var got []string
+	query := product.Query{Sort: product.SortByPrice, Limit: 1}
+	for {
+		page, err := repo.Find(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range page.Products {
+			got = append(got, p.GetItem().Name)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+	if expected := "Peenuts,Beer,Ale,Wine"; strings.Join(got, ",") != expected {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
@@ -0,0 +1,67 @@
+package memory
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/gegaryfa/tavern/domain/saga"
+	"github.com/google/uuid"
+)
+
+// Store fulfills the saga.Store interface
+type Store struct {
+	states map[uuid.UUID]saga.State
+	sync.Mutex
+}
+
+// New is a factory function to generate a new store of saga states
+func New() *Store {
+	return &Store{
+		states: make(map[uuid.UUID]saga.State),
+	}
+}
+
+func (s *Store) Get(id uuid.UUID) (saga.State, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if state, ok := s.states[id]; ok {
+		return copyState(state), nil
+	}
+	return saga.State{}, saga.ErrSagaNotFound
+}
+
+func (s *Store) Save(state saga.State) error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.states[state.ID] = copyState(state)
+	return nil
+}
+
+// Unfinished returns the sagas oldest update first
+func (s *Store) Unfinished() ([]saga.State, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	var states []saga.State
+	for _, state := range s.states {
+		if !state.Status.Finished() {
+			states = append(states, copyState(state))
+		}
+	}
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].UpdatedAt.Before(states[j].UpdatedAt)
+	})
+	return states, nil
+}
+
+// copyState copies the data so that the steps can not change a stored state
+func copyState(state saga.State) saga.State {
+	data := make(saga.Data, len(state.Data))
+	for k, v := range state.Data {
+		data[k] = v
+	}
+	state.Data = data
+	return state
+}
@@ -0,0 +1,164 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/gegaryfa/tavern/domain/saga"
+)
+
+// recorder builds steps that log what they do
+type recorder struct {
+	log []string
+	// fail makes the action of the step with the name fail
+	fail map[string]error
+	// failCompensation makes the compensation of the step with the name fail
+	failCompensation map[string]error
+}
+
+func (r *recorder) step(name string) saga.Step {
+	return saga.Step{
+		Name: name,
+		Action: func(ctx context.Context, data saga.Data) error {
+			if err := r.fail[name]; err != nil {
+				return err
+			}
+			r.log = append(r.log, name)
+			data[name] = "done"
+			return nil
+		},
+		Compensate: func(ctx context.Context, data saga.Data) error {
+			if err := r.failCompensation[name]; err != nil {
+				return err
+			}
+			r.log = append(r.log, "undo "+name)
+			delete(data, name)
+			return nil
+		},
+	}
+}
+
+func TestCoordinator(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	type testCase struct {
+		test             string
+		fail             map[string]error
+		failCompensation map[string]error
+		expectedStatus   saga.Status
+		expectedLog      []string
+		expectedErr      error
+	}
+
+	testCases := []testCase{
+		{
+			test:           "All steps succeed",
+			expectedStatus: saga.StatusCompleted,
+			expectedLog:    []string{"reserve", "create", "charge"},
+		},
+		{
+			test:           "Failed step compensates the steps done in reverse",
+			fail:           map[string]error{"charge": errFailed},
+			expectedStatus: saga.StatusCompensated,
+			expectedLog:    []string{"reserve", "create", "undo create", "undo reserve"},
+			expectedErr:    errFailed,
+		},
+		{
+			test:             "Failed compensation leaves the saga compensating",
+			fail:             map[string]error{"charge": errFailed},
+			failCompensation: map[string]error{"reserve": errFailed},
+			expectedStatus:   saga.StatusCompensating,
+			expectedLog:      []string{"reserve", "create", "undo create"},
+			expectedErr:      errFailed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			r := &recorder{fail: tc.fail, failCompensation: tc.failCompensation}
+			store := New()
+			c, err := saga.NewCoordinator(saga.WithStore(store), saga.WithSaga(saga.Definition{
+				Name:  "ordering",
+				Steps: []saga.Step{r.step("reserve"), r.step("create"), r.step("charge")},
+			}))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			state, err := c.Start(context.Background(), "ordering", saga.Data{"customer": "percy"})
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+			if state.Status != tc.expectedStatus {
+				t.Errorf("Expected status %s, got %s", tc.expectedStatus, state.Status)
+			}
+			if !reflect.DeepEqual(r.log, tc.expectedLog) {
+				t.Errorf("Expected steps %v, got %v", tc.expectedLog, r.log)
+			}
+			stored, err := store.Get(state.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.Status != state.Status || stored.Step != state.Step || !reflect.DeepEqual(stored.Data, state.Data) {
+				t.Errorf("Expected the state %v to be stored, got %v", state, stored)
+			}
+		})
+	}
+
+	if _, err := saga.NewCoordinator(); err != saga.ErrNoStore {
+		t.Errorf("Expected error %v, got %v", saga.ErrNoStore, err)
+	}
+}
+
+func TestCoordinator_Resume(t *testing.T) {
+	errFailed := errors.New("failed")
+	r := &recorder{failCompensation: map[string]error{"reserve": errFailed}}
+	store := New()
+	definition := saga.Definition{
+		Name:  "ordering",
+		Steps: []saga.Step{r.step("reserve"), r.step("create"), r.step("charge")},
+	}
+	c, err := saga.NewCoordinator(saga.WithStore(store), saga.WithSaga(definition))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A saga interrupted after its first step and one left compensating
+	running := saga.State{ID: [16]byte{1}, Saga: "ordering", Status: saga.StatusRunning, Step: 1, Data: saga.Data{"reserve": "done"}}
+	compensating := saga.State{ID: [16]byte{2}, Saga: "ordering", Status: saga.StatusCompensating, Step: 1, Data: saga.Data{"reserve": "done"}, Error: "charge: failed"}
+	for _, s := range []saga.State{running, compensating} {
+		if err := store.Save(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := c.Resume(context.Background()); !errors.Is(err, errFailed) {
+		t.Errorf("Expected error %v, got %v", errFailed, err)
+	}
+	// The compensation succeeds once retried
+	r.failCompensation = nil
+	if err := c.Resume(context.Background()); err == nil || err.Error() != "saga ordering "+compensating.ID.String()+": charge: failed" {
+		t.Errorf("Expected the failure of the compensated saga, got %v", err)
+	}
+
+	expected := []string{"create", "charge", "undo reserve"}
+	if !reflect.DeepEqual(r.log, expected) {
+		t.Errorf("Expected steps %v, got %v", expected, r.log)
+	}
+	unfinished, err := store.Unfinished()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unfinished) != 0 {
+		t.Errorf("Expected every saga to be finished, got %v", unfinished)
+	}
+	state, err := store.Get(compensating.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Status != saga.StatusCompensated || len(state.Data) != 0 {
+		t.Errorf("Expected the saga to be compensated, got %v", state)
+	}
+}
@@ -0,0 +1,222 @@
+// Package saga runs processes spanning several services as a sequence of steps. When a step fails the steps done
+// so far are compensated in reverse order. The state of every saga is stored after each step so that the sagas
+// interrupted by a crash can be resumed.
+package saga
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrNoStore is returned when a Coordinator is created without a store
+	ErrNoStore = errors.New("no saga store configured")
+	// ErrUnknownSaga is returned when starting or resuming a saga that was not registered
+	ErrUnknownSaga = errors.New("the saga is not registered")
+	// ErrSagaNotFound is returned when a saga state is not found
+	ErrSagaNotFound = errors.New("the saga was not found")
+)
+
+// Status is the stage a saga is in
+type Status string
+
+const (
+	// StatusRunning sagas are performing their steps
+	StatusRunning Status = "running"
+	// StatusCompensating sagas are undoing their steps after one of them failed
+	StatusCompensating Status = "compensating"
+	// StatusCompleted sagas performed all their steps
+	StatusCompleted Status = "completed"
+	// StatusCompensated sagas undid all the steps they performed
+	StatusCompensated Status = "compensated"
+)
+
+// Finished reports whether a saga in the status is done
+func (s Status) Finished() bool {
+	return s == StatusCompleted || s == StatusCompensated
+}
+
+// Data is what the steps of a saga share, such as the identifiers of what they created. It is stored with
+// the saga so it has to hold text.
+type Data map[string]string
+
+// Step is a step of a saga. Steps run again when the saga is resumed after a crash that happened before their
+// outcome was stored, so both functions have to tolerate running twice, such as by recording in the data what
+// they did.
+type Step struct {
+	Name   string
+	Action func(ctx context.Context, data Data) error
+	// Compensate undoes the action, steps that can not be undone leave it nil
+	Compensate func(ctx context.Context, data Data) error
+}
+
+// Definition is a saga, the steps run in order
+type Definition struct {
+	Name  string
+	Steps []Step
+}
+
+// State is the stored progress of a saga
+type State struct {
+	ID   uuid.UUID
+	Saga string
+	// Status is the stage the saga is in
+	Status Status
+	// Step is the number of steps performed, while compensating the number of steps left to compensate
+	Step int
+	Data Data
+	// Error is the failure of the step that made the saga compensate
+	Error     string
+	UpdatedAt time.Time
+}
+
+// Store is the interface to fulfill to keep the state of the sagas
+type Store interface {
+	Get(id uuid.UUID) (State, error)
+	// Save stores the state, replacing the previous state of the saga
+	Save(State) error
+	// Unfinished returns the sagas that are running or compensating
+	Unfinished() ([]State, error)
+}
+
+// Configuration is an alias for a function that configures a Coordinator
+type Configuration func(c *Coordinator) error
+
+// Coordinator runs the sagas and stores their progress. A single coordinator should run against a store,
+// otherwise a saga might be resumed twice.
+type Coordinator struct {
+	store Store
+	sagas map[string]Definition
+	now   func() time.Time
+}
+
+// NewCoordinator creates a Coordinator, a store has to be configured
+func NewCoordinator(cfgs ...Configuration) (*Coordinator, error) {
+	c := &Coordinator{
+		sagas: make(map[string]Definition),
+		now:   time.Now,
+	}
+	for _, cfg := range cfgs {
+		if err := cfg(c); err != nil {
+			return nil, err
+		}
+	}
+	if c.store == nil {
+		return nil, ErrNoStore
+	}
+	return c, nil
+}
+
+// WithStore keeps the state of the sagas in s
+func WithStore(s Store) Configuration {
+	return func(c *Coordinator) error {
+		c.store = s
+		return nil
+	}
+}
+
+// WithSaga registers the saga so that it can be started and resumed
+func WithSaga(d Definition) Configuration {
+	return func(c *Coordinator) error {
+		c.sagas[d.Name] = d
+		return nil
+	}
+}
+
+// WithClock replaces the clock used to date the states, it is meant for tests
+func WithClock(now func() time.Time) Configuration {
+	return func(c *Coordinator) error {
+		c.now = now
+		return nil
+	}
+}
+
+// Start runs a new saga with the data. It returns the error of the failed step once the saga is compensated,
+// or the error of the compensation that failed, the saga is then left compensating to be resumed.
+func (c *Coordinator) Start(ctx context.Context, saga string, data Data) (State, error) {
+	d, ok := c.sagas[saga]
+	if !ok {
+		return State{}, fmt.Errorf("%s: %w", saga, ErrUnknownSaga)
+	}
+	s := State{ID: uuid.New(), Saga: saga, Status: StatusRunning, Data: Data{}}
+	for k, v := range data {
+		s.Data[k] = v
+	}
+	if err := c.save(&s); err != nil {
+		return State{}, err
+	}
+	return c.run(ctx, d, s)
+}
+
+// Resume carries on with the sagas that were interrupted, the errors of all the sagas are returned together
+func (c *Coordinator) Resume(ctx context.Context) error {
+	states, err := c.store.Unfinished()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, s := range states {
+		d, ok := c.sagas[s.Saga]
+		if !ok {
+			errs = append(errs, fmt.Errorf("saga %s %s: %w", s.Saga, s.ID, ErrUnknownSaga))
+			continue
+		}
+		if _, err := c.run(ctx, d, s); err != nil {
+			errs = append(errs, fmt.Errorf("saga %s %s: %w", s.Saga, s.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// run performs the steps left and compensates them when one fails
+func (c *Coordinator) run(ctx context.Context, d Definition, s State) (State, error) {
+	var failure error
+	for s.Status == StatusRunning && s.Step < len(d.Steps) {
+		step := d.Steps[s.Step]
+		if err := step.Action(ctx, s.Data); err != nil {
+			failure = fmt.Errorf("%s: %w", step.Name, err)
+			s.Status = StatusCompensating
+			s.Error = failure.Error()
+		} else {
+			s.Step++
+		}
+		if err := c.save(&s); err != nil {
+			return s, err
+		}
+	}
+	if s.Status == StatusRunning {
+		s.Status = StatusCompleted
+		return s, c.save(&s)
+	}
+
+	for s.Step > 0 {
+		step := d.Steps[s.Step-1]
+		if step.Compensate != nil {
+			if err := step.Compensate(ctx, s.Data); err != nil {
+				return s, fmt.Errorf("compensate %s: %w", step.Name, err)
+			}
+		}
+		s.Step--
+		if err := c.save(&s); err != nil {
+			return s, err
+		}
+	}
+	s.Status = StatusCompensated
+	if err := c.save(&s); err != nil {
+		return s, err
+	}
+	if failure == nil {
+		// The saga was resumed, only the message of the failure was stored
+		failure = errors.New(s.Error)
+	}
+	return s, failure
+}
+
+func (c *Coordinator) save(s *State) error {
+	s.UpdatedAt = c.now()
+	return c.store.Save(*s)
+}
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS saga_states (
+    id         TEXT PRIMARY KEY,
+    saga       TEXT NOT NULL,
+    status     TEXT NOT NULL,
+    step       INTEGER NOT NULL,
+    -- data is the JSON object shared by the steps
+    data       TEXT NOT NULL,
+    error      TEXT NOT NULL DEFAULT '',
+    -- updated_at is in unix nanoseconds so that it compares the same on every database
+    updated_at BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS saga_states_status ON saga_states (status);
@@ -0,0 +1,119 @@
+// Package sql is a database/sql implementation of the saga state store.
+// It works with PostgreSQL and SQLite, the driver has to be registered by the caller.
+package sql
+
+import (
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"time"
+
+	"github.com/gegaryfa/tavern/domain/saga"
+	"github.com/gegaryfa/tavern/internal/sqldb"
+	"github.com/google/uuid"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// Store fulfills the saga.Store interface, the sagas can be resumed after a restart of the tavern
+type Store struct {
+	db     *sql.DB
+	driver string
+}
+
+// New opens a connection using the given driver and applies the saga schema migrations
+func New(driverName, dataSourceName string) (*Store, error) {
+	db, err := sql.Open(driverName, dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+	return NewFromDB(db, driverName)
+}
+
+// NewFromDB creates a store on top of an already opened database and applies the saga schema migrations
+func NewFromDB(db *sql.DB, driverName string) (*Store, error) {
+	sub, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	if err := sqldb.Migrate(db, driverName, "saga", sub); err != nil {
+		return nil, err
+	}
+	return &Store{db: db, driver: driverName}, nil
+}
+
+const selectStates = `SELECT id, saga, status, step, data, error, updated_at FROM saga_states`
+
+func (s *Store) Get(id uuid.UUID) (saga.State, error) {
+	state, err := scanState(s.db.QueryRow(s.rebind(selectStates+` WHERE id = ?`), id.String()))
+	if errors.Is(err, sql.ErrNoRows) {
+		return saga.State{}, saga.ErrSagaNotFound
+	}
+	return state, err
+}
+
+func (s *Store) Save(state saga.State) error {
+	data, err := json.Marshal(state.Data)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(s.rebind(`INSERT INTO saga_states (id, saga, status, step, data, error, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET status = excluded.status, step = excluded.step, data = excluded.data,
+			error = excluded.error, updated_at = excluded.updated_at`),
+		state.ID.String(), state.Saga, string(state.Status), state.Step, string(data), state.Error, state.UpdatedAt.UnixNano(),
+	)
+	return err
+}
+
+// Unfinished returns the sagas oldest update first
+func (s *Store) Unfinished() ([]saga.State, error) {
+	rows, err := s.db.Query(s.rebind(selectStates+` WHERE status IN (?, ?) ORDER BY updated_at`),
+		string(saga.StatusRunning), string(saga.StatusCompensating))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var states []saga.State
+	for rows.Next() {
+		state, err := scanState(rows)
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, state)
+	}
+	return states, rows.Err()
+}
+
+// scanState reads a row selected with selectStates
+func scanState(row interface{ Scan(dest ...any) error }) (saga.State, error) {
+	var (
+		state     saga.State
+		id        string
+		status    string
+		data      string
+		updatedAt int64
+	)
+	if err := row.Scan(&id, &state.Saga, &status, &state.Step, &data, &state.Error, &updatedAt); err != nil {
+		return saga.State{}, err
+	}
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return saga.State{}, err
+	}
+	if err := json.Unmarshal([]byte(data), &state.Data); err != nil {
+		return saga.State{}, err
+	}
+	state.ID = parsed
+	state.Status = saga.Status(status)
+	state.UpdatedAt = time.Unix(0, updatedAt)
+	return state, nil
+}
+
+func (s *Store) rebind(query string) string {
+	return sqldb.Rebind(s.driver, query)
+}
@@ -0,0 +1,59 @@
+package sql
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gegaryfa/tavern/domain/saga"
+	"github.com/google/uuid"
+	_ "modernc.org/sqlite"
+)
+
+func TestSQLStore(t *testing.T) {
+	now := time.Date(2022, 11, 1, 18, 0, 0, 0, time.UTC)
+	s, err := New("sqlite", filepath.Join(t.TempDir(), "tavern.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	running := saga.State{ID: uuid.New(), Saga: "ordering", Status: saga.StatusRunning, Step: 1,
+		Data: saga.Data{"customer_id": "percy"}, UpdatedAt: now}
+	completed := saga.State{ID: uuid.New(), Saga: "ordering", Status: saga.StatusCompleted, Step: 3,
+		Data: saga.Data{}, UpdatedAt: now}
+	for _, state := range []saga.State{running, completed} {
+		if err := s.Save(state); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Saving again replaces the state
+	running.Status = saga.StatusCompensating
+	running.Data["order_id"] = "order-1"
+	running.Error = "charge: failed"
+	running.UpdatedAt = now.Add(time.Second)
+	if err := s.Save(running); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.Get(running.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Data, running.Data) || got.Status != running.Status || got.Step != running.Step ||
+		got.Error != running.Error || !got.UpdatedAt.Equal(running.UpdatedAt) {
+		t.Errorf("Expected %v, got %v", running, got)
+	}
+
+	unfinished, err := s.Unfinished()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unfinished) != 1 || unfinished[0].ID != running.ID {
+		t.Errorf("Expected only the compensating saga, got %v", unfinished)
+	}
+
+	if _, err := s.Get(uuid.New()); err != saga.ErrSagaNotFound {
+		t.Errorf("Expected error %v, got %v", saga.ErrSagaNotFound, err)
+	}
+}
@@ -0,0 +1,148 @@
+package memory
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gegaryfa/tavern/domain/order"
+	"github.com/gegaryfa/tavern/domain/tab"
+	"github.com/google/uuid"
+)
+
+type Repository struct {
+	tabs map[uuid.UUID]tab.Tab
+	sync.Mutex
+}
+
+func New() *Repository {
+	return &Repository{
+		tabs: make(map[uuid.UUID]tab.Tab),
+	}
+}
+
+func (r *Repository) Get(id uuid.UUID) (tab.Tab, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if t, ok := r.tabs[id]; ok {
+		return t, nil
+	}
+	return tab.Tab{}, tab.ErrTabNotFound
+}
+
+func (r *Repository) GetOpen(customerID uuid.UUID) (tab.Tab, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	for _, t := range r.tabs {
+		if t.GetCustomerID() == customerID && t.IsOpen() {
+			return t, nil
+		}
+	}
+	return tab.Tab{}, tab.ErrTabNotFound
+}
+
+func (r *Repository) Add(t tab.Tab) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if _, ok := r.tabs[t.GetID()]; ok {
+		return tab.ErrTabAlreadyExist
+	}
+	for _, existing := range r.tabs {
+		if existing.GetCustomerID() == t.GetCustomerID() && existing.IsOpen() {
+			return tab.ErrTabAlreadyOpen
+		}
+	}
+	r.tabs[t.GetID()] = t
+	return nil
+}
+
+func (r *Repository) Update(t tab.Tab) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if _, ok := r.tabs[t.GetID()]; !ok {
+		return tab.ErrTabNotFound
+	}
+	r.tabs[t.GetID()] = t
+	return nil
+}
+
+func (r *Repository) AddOrder(id uuid.UUID, o order.Order) (tab.Tab, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	t, ok := r.tabs[id]
+	if !ok {
+		return tab.Tab{}, tab.ErrTabNotFound
+	}
+	if err := t.AddOrder(o); err != nil {
+		return tab.Tab{}, err
+	}
+	r.tabs[id] = t
+	return t, nil
+}
+
+func (r *Repository) RemoveOrder(id, orderID uuid.UUID) (tab.Tab, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	t, ok := r.tabs[id]
+	if !ok {
+		return tab.Tab{}, tab.ErrTabNotFound
+	}
+	if err := t.RemoveOrder(orderID); err != nil {
+		return tab.Tab{}, err
+	}
+	r.tabs[id] = t
+	return t, nil
+}
+
+func (r *Repository) Close(id uuid.UUID, expected []tab.Entry, at time.Time) (tab.Tab, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	t, ok := r.tabs[id]
+	if !ok {
+		return tab.Tab{}, tab.ErrTabNotFound
+	}
+	if t.IsOpen() && !sameEntries(t.GetEntries(), expected) {
+		return tab.Tab{}, tab.ErrTabChanged
+	}
+	if err := t.Close(at); err != nil {
+		return tab.Tab{}, err
+	}
+	r.tabs[id] = t
+	return t, nil
+}
+
+func (r *Repository) Reopen(id uuid.UUID) (tab.Tab, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	t, ok := r.tabs[id]
+	if !ok {
+		return tab.Tab{}, tab.ErrTabNotFound
+	}
+	for _, existing := range r.tabs {
+		if existing.GetID() != id && existing.GetCustomerID() == t.GetCustomerID() && existing.IsOpen() {
+			return tab.Tab{}, tab.ErrTabAlreadyOpen
+		}
+	}
+	t.Reopen()
+	r.tabs[id] = t
+	return t, nil
+}
+
+func sameEntries(a, b []tab.Entry) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
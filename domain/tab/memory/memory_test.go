@@ -0,0 +1,80 @@
+package memory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gegaryfa/tavern/domain/order"
+	"github.com/gegaryfa/tavern/domain/tab"
+	"github.com/google/uuid"
+)
+
+func TestMemoryTabRepository(t *testing.T) {
+	repo := New()
+	customerID := uuid.New()
+	tb, err := tab.NewTab(customerID, 10, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Add(tb); err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := tab.NewTab(customerID, 20, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Add(second); err != tab.ErrTabAlreadyOpen {
+		t.Errorf("Expected error %v, got %v", tab.ErrTabAlreadyOpen, err)
+	}
+
+	o, err := order.NewOrder(customerID, []order.Line{{ProductID: uuid.New(), Name: "Beer", Quantity: 2, UnitPrice: 4}}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.AddOrder(tb.GetID(), o); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.AddOrder(tb.GetID(), o); err != tab.ErrCreditLimitExceeded {
+		t.Errorf("Expected error %v, got %v", tab.ErrCreditLimitExceeded, err)
+	}
+
+	open, err := repo.GetOpen(customerID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if open.Total() != 8 {
+		t.Errorf("Expected total 8, got %v", open.Total())
+	}
+
+	// A tab is only closed with the orders it was read with
+	if _, err := repo.Close(tb.GetID(), nil, time.Now()); err != tab.ErrTabChanged {
+		t.Errorf("Expected error %v, got %v", tab.ErrTabChanged, err)
+	}
+	if _, err := repo.Close(tb.GetID(), open.GetEntries(), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Close(tb.GetID(), open.GetEntries(), time.Now()); err != tab.ErrTabClosed {
+		t.Errorf("Expected error %v, got %v", tab.ErrTabClosed, err)
+	}
+	if reopened, err := repo.Reopen(tb.GetID()); err != nil || !reopened.IsOpen() {
+		t.Errorf("Expected the tab to be reopened, got %v %v", reopened, err)
+	}
+
+	// Once settled the customer can open a new tab
+	if err := open.Close(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Update(open); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.GetOpen(customerID); err != tab.ErrTabNotFound {
+		t.Errorf("Expected error %v, got %v", tab.ErrTabNotFound, err)
+	}
+	if err := repo.Add(second); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Reopen(tb.GetID()); err != tab.ErrTabAlreadyOpen {
+		t.Errorf("Expected error %v, got %v", tab.ErrTabAlreadyOpen, err)
+	}
+}
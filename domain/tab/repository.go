@@ -0,0 +1,37 @@
+package tab
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gegaryfa/tavern/domain/order"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrTabNotFound is returned when a tab is not found
+	ErrTabNotFound = errors.New("the tab was not found")
+	// ErrTabAlreadyExist is returned when trying to add a tab that already exists
+	ErrTabAlreadyExist = errors.New("the tab already exists")
+	// ErrTabAlreadyOpen is returned when opening a tab for a customer that already has an open tab
+	ErrTabAlreadyOpen = errors.New("the customer already has an open tab")
+)
+
+// Repository is the repository interface to fulfill to use the tab aggregate
+type Repository interface {
+	Get(id uuid.UUID) (Tab, error)
+	// GetOpen returns the open tab of the customer
+	GetOpen(customerID uuid.UUID) (Tab, error)
+	// Add stores a new tab, a customer can only have one open tab at a time
+	Add(tab Tab) error
+	Update(tab Tab) error
+	// AddOrder atomically puts the order on the tab so that concurrent orders can not exceed the credit limit
+	AddOrder(id uuid.UUID, o order.Order) (Tab, error)
+	// RemoveOrder atomically takes the order off the tab
+	RemoveOrder(id, orderID uuid.UUID) (Tab, error)
+	// Close atomically closes the open tab when it still holds the expected entries, so that concurrent settles
+	// can not both pay it and an order put on it meanwhile is not lost. It fails with ErrTabChanged otherwise.
+	Close(id uuid.UUID, expected []Entry, at time.Time) (Tab, error)
+	// Reopen atomically opens the closed tab again, the customer must not have opened another tab meanwhile
+	Reopen(id uuid.UUID) (Tab, error)
+}
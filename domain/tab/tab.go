@@ -0,0 +1,206 @@
+// Package tab holds the aggregate of the running bill a customer settles in one payment
+package tab
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gegaryfa/tavern"
+	"github.com/gegaryfa/tavern/domain/order"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrMissingCustomer is returned when a tab is opened without a customer
+	ErrMissingCustomer = errors.New("a tab has to belong to a customer")
+	// ErrInvalidLimit is returned when a tab is opened without a positive credit limit
+	ErrInvalidLimit = errors.New("the credit limit of a tab has to be positive")
+	// ErrCreditLimitExceeded is returned when an order would take the tab over its credit limit
+	ErrCreditLimitExceeded = errors.New("the order would exceed the credit limit of the tab")
+	// ErrTabClosed is returned when adding orders to or settling a tab that has been settled
+	ErrTabClosed = errors.New("the tab is closed")
+	// ErrWrongCustomer is returned when adding the order of another customer to a tab
+	ErrWrongCustomer = errors.New("the order belongs to another customer than the tab")
+	// ErrOrderNotOnTab is returned when taking an order off a tab it was not put on
+	ErrOrderNotOnTab = errors.New("the order is not on the tab")
+	// ErrTabChanged is returned when closing a tab whose orders changed since it was read
+	ErrTabChanged = errors.New("the orders on the tab changed")
+)
+
+// Entry is a value object holding an order put on the tab
+type Entry struct {
+	OrderID uuid.UUID
+	// Amount is the total of the order when it was put on the tab
+	Amount float64
+}
+
+// Tab is the aggregate of the orders a customer pays later, in one go
+type Tab struct {
+	id         uuid.UUID
+	customerID uuid.UUID
+	// limit is the credit given to the customer, the total of the tab can not exceed it
+	limit    float64
+	entries  []Entry
+	openedAt time.Time
+	// closedAt is the zero time while the tab is open
+	closedAt time.Time
+}
+
+// NewTab is a factory to open a new Tab for the customer
+func NewTab(customerID uuid.UUID, limit float64, openedAt time.Time) (Tab, error) {
+	if customerID == uuid.Nil {
+		return Tab{}, ErrMissingCustomer
+	}
+	if limit <= 0 {
+		return Tab{}, ErrInvalidLimit
+	}
+	return Tab{
+		id:         uuid.New(),
+		customerID: customerID,
+		limit:      limit,
+		entries:    make([]Entry, 0),
+		openedAt:   openedAt,
+	}, nil
+}
+
+func (t Tab) GetID() uuid.UUID {
+	return t.id
+}
+
+func (t *Tab) SetID(id uuid.UUID) {
+	t.id = id
+}
+
+func (t Tab) GetCustomerID() uuid.UUID {
+	return t.customerID
+}
+
+func (t *Tab) SetCustomerID(id uuid.UUID) {
+	t.customerID = id
+}
+
+func (t Tab) GetLimit() float64 {
+	return t.limit
+}
+
+func (t *Tab) SetLimit(limit float64) {
+	t.limit = limit
+}
+
+func (t Tab) GetEntries() []Entry {
+	return t.entries
+}
+
+func (t *Tab) SetEntries(entries []Entry) {
+	t.entries = entries
+}
+
+func (t Tab) GetOpenedAt() time.Time {
+	return t.openedAt
+}
+
+func (t *Tab) SetOpenedAt(openedAt time.Time) {
+	t.openedAt = openedAt
+}
+
+func (t Tab) GetClosedAt() time.Time {
+	return t.closedAt
+}
+
+func (t *Tab) SetClosedAt(closedAt time.Time) {
+	t.closedAt = closedAt
+}
+
+// IsOpen reports whether orders can still be put on the tab
+func (t Tab) IsOpen() bool {
+	return t.closedAt.IsZero()
+}
+
+// OrderIDs returns the orders put on the tab, oldest first
+func (t Tab) OrderIDs() []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(t.entries))
+	for _, e := range t.entries {
+		ids = append(ids, e.OrderID)
+	}
+	return ids
+}
+
+// Total returns the sum of the orders put on the tab
+func (t Tab) Total() float64 {
+	var total float64
+	for _, e := range t.entries {
+		total += e.Amount
+	}
+	return total
+}
+
+// Remaining returns how much can still be put on the tab
+func (t Tab) Remaining() float64 {
+	return t.limit - t.Total()
+}
+
+// CanAfford reports whether an amount fits in the credit left on the tab
+func (t Tab) CanAfford(amount float64) bool {
+	// Compare in cents so that rounding errors do not reject an order that exactly reaches the limit
+	return tavern.Cents(t.Total()+amount) <= tavern.Cents(t.limit)
+}
+
+// AddOrder puts the order on the tab, it fails when the tab is closed or the order does not fit in the credit limit
+func (t *Tab) AddOrder(o order.Order) error {
+	if !t.IsOpen() {
+		return ErrTabClosed
+	}
+	if o.GetCustomerID() != t.customerID {
+		return ErrWrongCustomer
+	}
+	if !t.CanAfford(o.Total()) {
+		return ErrCreditLimitExceeded
+	}
+	// Copy on write so that copies of the tab handed out earlier are not changed
+	entries := make([]Entry, len(t.entries), len(t.entries)+1)
+	copy(entries, t.entries)
+	t.entries = append(entries, Entry{OrderID: o.GetID(), Amount: o.Total()})
+	return nil
+}
+
+// RemoveOrder takes a cancelled order off the tab, it fails when the tab is closed or does not hold the order
+func (t *Tab) RemoveOrder(orderID uuid.UUID) error {
+	if !t.IsOpen() {
+		return ErrTabClosed
+	}
+	entries := make([]Entry, 0, len(t.entries))
+	for _, e := range t.entries {
+		if e.OrderID != orderID {
+			entries = append(entries, e)
+		}
+	}
+	if len(entries) == len(t.entries) {
+		return ErrOrderNotOnTab
+	}
+	t.entries = entries
+	return nil
+}
+
+// Contains reports whether the order was put on the tab
+func (t Tab) Contains(orderID uuid.UUID) bool {
+	for _, e := range t.entries {
+		if e.OrderID == orderID {
+			return true
+		}
+	}
+	return false
+}
+
+// Close closes the tab once it has been paid
+func (t *Tab) Close(at time.Time) error {
+	if !t.IsOpen() {
+		return ErrTabClosed
+	}
+	t.closedAt = at
+	return nil
+}
+
+// Reopen opens the tab again, such as when paying it failed
+func (t *Tab) Reopen() {
+	t.closedAt = time.Time{}
+}
@@ -0,0 +1,113 @@
+package tab
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gegaryfa/tavern/domain/order"
+	"github.com/google/uuid"
+)
+
+func newOrder(t *testing.T, customerID uuid.UUID, total float64) order.Order {
+	o, err := order.NewOrder(customerID, []order.Line{
+		{ProductID: uuid.New(), Name: "Beer", Quantity: 1, UnitPrice: total},
+	}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return o
+}
+
+func TestNewTab(t *testing.T) {
+	type testCase struct {
+		test        string
+		customerID  uuid.UUID
+		limit       float64
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{test: "Missing customer", customerID: uuid.Nil, limit: 50, expectedErr: ErrMissingCustomer},
+		{test: "No limit", customerID: uuid.New(), limit: 0, expectedErr: ErrInvalidLimit},
+		{test: "Valid tab", customerID: uuid.New(), limit: 50, expectedErr: nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			_, err := NewTab(tc.customerID, tc.limit, time.Now())
+			if err != tc.expectedErr {
+				t.Errorf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestTab_AddOrder(t *testing.T) {
+	customerID := uuid.New()
+	tb, err := NewTab(customerID, 10, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type testCase struct {
+		test        string
+		order       order.Order
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{test: "First order", order: newOrder(t, customerID, 6.3), expectedErr: nil},
+		{test: "Over the limit", order: newOrder(t, customerID, 4), expectedErr: ErrCreditLimitExceeded},
+		{test: "Other customer", order: newOrder(t, uuid.New(), 1), expectedErr: ErrWrongCustomer},
+		{test: "Exactly the limit", order: newOrder(t, customerID, 3.7), expectedErr: nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			if err := tb.AddOrder(tc.order); err != tc.expectedErr {
+				t.Errorf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+
+	if len(tb.OrderIDs()) != 2 {
+		t.Errorf("Expected 2 orders on the tab, got %d", len(tb.OrderIDs()))
+	}
+	if err := tb.Close(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if err := tb.AddOrder(newOrder(t, customerID, 0.5)); err != ErrTabClosed {
+		t.Errorf("Expected error %v, got %v", ErrTabClosed, err)
+	}
+	if err := tb.Close(time.Now()); err != ErrTabClosed {
+		t.Errorf("Expected error %v, got %v", ErrTabClosed, err)
+	}
+}
+
+func TestTab_RemoveOrder(t *testing.T) {
+	customerID := uuid.New()
+	tb, err := NewTab(customerID, 10, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, second := newOrder(t, customerID, 6), newOrder(t, customerID, 4)
+	for _, o := range []order.Order{first, second} {
+		if err := tb.AddOrder(o); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := tb.RemoveOrder(first.GetID()); err != nil {
+		t.Fatal(err)
+	}
+	if tb.Contains(first.GetID()) || !tb.Contains(second.GetID()) || tb.Total() != 4 {
+		t.Errorf("Expected only the second order on the tab, got %v", tb.GetEntries())
+	}
+	if err := tb.RemoveOrder(first.GetID()); err != ErrOrderNotOnTab {
+		t.Errorf("Expected error %v, got %v", ErrOrderNotOnTab, err)
+	}
+	// The credit freed by the cancelled order can be used again
+	if err := tb.AddOrder(newOrder(t, customerID, 6)); err != nil {
+		t.Errorf("Expected the order to fit in the freed credit, got %v", err)
+	}
+}
@@ -0,0 +1,197 @@
+// Package tax calculates the taxes charged on orders
+package tax
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/gegaryfa/tavern/domain/order"
+)
+
+// ErrInvalidRate is returned when a tax rate is negative
+var ErrInvalidRate = errors.New("a tax rate must not be negative")
+
+// Rounding decides when tax amounts are rounded to cents
+type Rounding int
+
+const (
+	// RoundPerLine rounds the tax of every line before adding them up
+	RoundPerLine Rounding = iota
+	// RoundPerCategory adds up the exact tax of the lines and rounds the sum of every category
+	RoundPerCategory
+)
+
+// Configuration is an alias for a function that configures a Calculator
+type Configuration func(c *Calculator) error
+
+// Calculator charges a tax rate per product category, products can be taxed apart by their tags such as alcoholic
+type Calculator struct {
+	// rates holds the percentage charged per category, 24 means 24%
+	rates map[string]float64
+	// tagRates holds the percentage charged per product tag, in the order they were configured
+	tagRates []tagRate
+	// defaultRate is charged on categories without a rate
+	defaultRate float64
+	// inclusive is set when the prices already contain the taxes
+	inclusive bool
+	rounding  Rounding
+}
+
+// NewCalculator creates a Calculator, without configurations no tax is charged on exclusive prices
+func NewCalculator(cfgs ...Configuration) (*Calculator, error) {
+	c := &Calculator{
+		rates:    make(map[string]float64),
+		rounding: RoundPerLine,
+	}
+	for _, cfg := range cfgs {
+		if err := cfg(c); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// WithRate charges rate percent on the products of the category
+func WithRate(category string, rate float64) Configuration {
+	return func(c *Calculator) error {
+		if rate < 0 {
+			return ErrInvalidRate
+		}
+		c.rates[category] = rate
+		return nil
+	}
+}
+
+// WithTagRate charges rate percent on the products labeled with the tag, such as alcoholic drinks, instead of the
+// rate of their category. A product with several taxed tags is charged the rate configured first.
+func WithTagRate(tag string, rate float64) Configuration {
+	return func(c *Calculator) error {
+		if rate < 0 {
+			return ErrInvalidRate
+		}
+		for i := range c.tagRates {
+			if c.tagRates[i].tag == tag {
+				c.tagRates[i].rate = rate
+				return nil
+			}
+		}
+		c.tagRates = append(c.tagRates, tagRate{tag: tag, rate: rate})
+		return nil
+	}
+}
+
+// WithDefaultRate charges rate percent on the products of categories without their own rate
+func WithDefaultRate(rate float64) Configuration {
+	return func(c *Calculator) error {
+		if rate < 0 {
+			return ErrInvalidRate
+		}
+		c.defaultRate = rate
+		return nil
+	}
+}
+
+// WithInclusivePrices treats the prices as already containing the taxes, the taxes are then
+// only broken down on the invoice instead of being added to the total
+func WithInclusivePrices() Configuration {
+	return func(c *Calculator) error {
+		c.inclusive = true
+		return nil
+	}
+}
+
+// WithRounding sets when the tax amounts are rounded to cents
+func WithRounding(r Rounding) Configuration {
+	return func(c *Calculator) error {
+		c.rounding = r
+		return nil
+	}
+}
+
+// Rate returns the percentage charged on the category
+func (c *Calculator) Rate(category string) float64 {
+	if rate, ok := c.rates[category]; ok {
+		return rate
+	}
+	return c.defaultRate
+}
+
+// LineRate returns the percentage charged on the line and the tag it is charged for, the tag is empty when the
+// line is charged the rate of its category
+func (c *Calculator) LineRate(l order.Line) (float64, string) {
+	for _, tr := range c.tagRates {
+		for _, tag := range l.Tags {
+			if tag == tr.tag {
+				return tr.rate, tag
+			}
+		}
+	}
+	return c.Rate(l.Category), ""
+}
+
+// Calculate returns the tax breakdown of the order, one entry per category and taxed tag sorted by category.
+// Discounts are spread over the lines in proportion to their totals so that every category is taxed
+// on what was actually paid for it.
+func (c *Calculator) Calculate(o order.Order) []order.Tax {
+	// share is the part of every line that is left after the discounts
+	share := 1.0
+	if subtotal := o.Subtotal(); subtotal > 0 {
+		share = o.Net() / subtotal
+	}
+
+	type group struct{ category, tag string }
+	index := map[group]int{}
+	var taxes []order.Tax
+	for _, l := range o.GetLines() {
+		rate, tag := c.LineRate(l)
+		amount := l.Total() * share
+		base, tax := amount, amount*rate/100
+		if c.inclusive {
+			tax = amount * rate / (100 + rate)
+		}
+		if c.rounding == RoundPerLine {
+			tax = round(tax)
+		}
+		if c.inclusive {
+			base = amount - tax
+		}
+
+		i, ok := index[group{l.Category, tag}]
+		if !ok {
+			i = len(taxes)
+			index[group{l.Category, tag}] = i
+			taxes = append(taxes, order.Tax{Category: l.Category, Tag: tag, Rate: rate})
+		}
+		taxes[i].Base += base
+		taxes[i].Amount += tax
+	}
+
+	for i := range taxes {
+		taxes[i].Base = round(taxes[i].Base)
+		taxes[i].Amount = round(taxes[i].Amount)
+	}
+	sort.Slice(taxes, func(i, j int) bool {
+		if taxes[i].Category != taxes[j].Category {
+			return taxes[i].Category < taxes[j].Category
+		}
+		return taxes[i].Tag < taxes[j].Tag
+	})
+	return taxes
+}
+
+// Apply replaces the tax breakdown of the order, it has to be applied again whenever the order is discounted
+func (c *Calculator) Apply(o *order.Order) {
+	o.ApplyTax(c.Calculate(*o), c.inclusive)
+}
+
+// tagRate is the percentage charged on the products labeled with a tag
+type tagRate struct {
+	tag  string
+	rate float64
+}
+
+// round rounds an amount of money to cents
+func round(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
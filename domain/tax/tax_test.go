@@ -0,0 +1,130 @@
+package tax
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gegaryfa/tavern/domain/order"
+	"github.com/google/uuid"
+)
+
+func newOrder(t *testing.T, lines ...order.Line) order.Order {
+	o, err := order.NewOrder(uuid.New(), lines, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return o
+}
+
+func TestCalculator_Calculate(t *testing.T) {
+	beer := order.Line{ProductID: uuid.New(), Name: "Beer", Category: "drinks", Quantity: 2, UnitPrice: 5}
+	stew := order.Line{ProductID: uuid.New(), Name: "Stew", Category: "food", Quantity: 1, UnitPrice: 10}
+	wine := order.Line{ProductID: uuid.New(), Name: "Wine", Category: "drinks", Tags: []string{"vegan", "alcoholic"}, Quantity: 1, UnitPrice: 10}
+	juice := order.Line{ProductID: uuid.New(), Name: "Juice", Category: "drinks", Tags: []string{"vegan"}, Quantity: 1, UnitPrice: 10}
+	mints := func() order.Line {
+		return order.Line{ProductID: uuid.New(), Name: "Mint", Category: "snacks", Quantity: 1, UnitPrice: 0.04}
+	}
+
+	type testCase struct {
+		test          string
+		cfgs          []Configuration
+		order         order.Order
+		expectedTaxes []order.Tax
+		expectedTotal float64
+	}
+
+	discounted := newOrder(t, beer, stew)
+	if err := discounted.ApplyDiscount(order.Discount{Rule: "half off", Amount: 10}); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []testCase{
+		{
+			test:  "Rate per category",
+			cfgs:  []Configuration{WithRate("drinks", 24), WithRate("food", 13)},
+			order: newOrder(t, beer, stew),
+			expectedTaxes: []order.Tax{
+				{Category: "drinks", Rate: 24, Base: 10, Amount: 2.4},
+				{Category: "food", Rate: 13, Base: 10, Amount: 1.3},
+			},
+			expectedTotal: 23.7,
+		}, {
+			test:  "Alcoholic and soft drinks in the same order",
+			cfgs:  []Configuration{WithRate("drinks", 13), WithTagRate("alcoholic", 24)},
+			order: newOrder(t, wine, juice),
+			expectedTaxes: []order.Tax{
+				{Category: "drinks", Rate: 13, Base: 10, Amount: 1.3},
+				{Category: "drinks", Tag: "alcoholic", Rate: 24, Base: 10, Amount: 2.4},
+			},
+			expectedTotal: 23.7,
+		}, {
+			test:  "Default rate",
+			cfgs:  []Configuration{WithRate("drinks", 24), WithDefaultRate(6)},
+			order: newOrder(t, stew),
+			expectedTaxes: []order.Tax{
+				{Category: "food", Rate: 6, Base: 10, Amount: 0.6},
+			},
+			expectedTotal: 10.6,
+		}, {
+			test:  "Inclusive prices",
+			cfgs:  []Configuration{WithRate("drinks", 25), WithInclusivePrices()},
+			order: newOrder(t, beer),
+			expectedTaxes: []order.Tax{
+				{Category: "drinks", Rate: 25, Base: 8, Amount: 2},
+			},
+			expectedTotal: 10,
+		}, {
+			test:  "Discounts are spread over the categories",
+			cfgs:  []Configuration{WithRate("drinks", 20), WithRate("food", 10)},
+			order: discounted,
+			expectedTaxes: []order.Tax{
+				{Category: "drinks", Rate: 20, Base: 5, Amount: 1},
+				{Category: "food", Rate: 10, Base: 5, Amount: 0.5},
+			},
+			expectedTotal: 11.5,
+		}, {
+			test:  "Rounding per line",
+			cfgs:  []Configuration{WithDefaultRate(10), WithRounding(RoundPerLine)},
+			order: newOrder(t, mints(), mints(), mints()),
+			expectedTaxes: []order.Tax{
+				{Category: "snacks", Rate: 10, Base: 0.12, Amount: 0},
+			},
+			expectedTotal: 0.12,
+		}, {
+			test:  "Rounding per category",
+			cfgs:  []Configuration{WithDefaultRate(10), WithRounding(RoundPerCategory)},
+			order: newOrder(t, mints(), mints(), mints()),
+			expectedTaxes: []order.Tax{
+				{Category: "snacks", Rate: 10, Base: 0.12, Amount: 0.01},
+			},
+			expectedTotal: 0.13,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			c, err := NewCalculator(tc.cfgs...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			o := tc.order
+			c.Apply(&o)
+			if !reflect.DeepEqual(o.GetTaxes(), tc.expectedTaxes) {
+				t.Errorf("Expected taxes %v, got %v", tc.expectedTaxes, o.GetTaxes())
+			}
+			if round(o.Total()) != tc.expectedTotal {
+				t.Errorf("Expected total %v, got %v", tc.expectedTotal, o.Total())
+			}
+		})
+	}
+}
+
+func TestNewCalculator_InvalidRate(t *testing.T) {
+	if _, err := NewCalculator(WithRate("drinks", -1)); err != ErrInvalidRate {
+		t.Errorf("Expected error %v, got %v", ErrInvalidRate, err)
+	}
+	if _, err := NewCalculator(WithTagRate("alcoholic", -1)); err != ErrInvalidRate {
+		t.Errorf("Expected error %v, got %v", ErrInvalidRate, err)
+	}
+}
@@ -0,0 +1,81 @@
+package memory
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/gegaryfa/tavern/domain/ticket"
+	"github.com/google/uuid"
+)
+
+type Repository struct {
+	tickets map[uuid.UUID]ticket.Ticket
+	sync.Mutex
+}
+
+func New() *Repository {
+	return &Repository{
+		tickets: make(map[uuid.UUID]ticket.Ticket),
+	}
+}
+
+func (r *Repository) Get(id uuid.UUID) (ticket.Ticket, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if t, ok := r.tickets[id]; ok {
+		return t, nil
+	}
+	return ticket.Ticket{}, ticket.ErrTicketNotFound
+}
+
+func (r *Repository) Add(t ticket.Ticket) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if _, ok := r.tickets[t.GetID()]; ok {
+		return ticket.ErrTicketAlreadyExist
+	}
+	r.tickets[t.GetID()] = t
+	return nil
+}
+
+func (r *Repository) Update(t ticket.Ticket) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if _, ok := r.tickets[t.GetID()]; !ok {
+		return ticket.ErrTicketNotFound
+	}
+	r.tickets[t.GetID()] = t
+	return nil
+}
+
+func (r *Repository) Queue(station ticket.Station) ([]ticket.Ticket, error) {
+	return r.filter(func(t ticket.Ticket) bool {
+		return t.GetStation() == station && t.IsOpen()
+	}), nil
+}
+
+func (r *Repository) ByOrder(orderID uuid.UUID) ([]ticket.Ticket, error) {
+	return r.filter(func(t ticket.Ticket) bool {
+		return t.GetOrderID() == orderID
+	}), nil
+}
+
+// filter returns the tickets matching keep, oldest first
+func (r *Repository) filter(keep func(t ticket.Ticket) bool) []ticket.Ticket {
+	r.Lock()
+	defer r.Unlock()
+
+	var tickets []ticket.Ticket
+	for _, t := range r.tickets {
+		if keep(t) {
+			tickets = append(tickets, t)
+		}
+	}
+	sort.Slice(tickets, func(i, j int) bool {
+		return tickets[i].GetCreatedAt().Before(tickets[j].GetCreatedAt())
+	})
+	return tickets
+}
@@ -0,0 +1,59 @@
+package memory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gegaryfa/tavern/domain/order"
+	"github.com/gegaryfa/tavern/domain/ticket"
+	"github.com/google/uuid"
+)
+
+func TestMemoryTicketRepository(t *testing.T) {
+	now := time.Date(2022, 11, 1, 18, 0, 0, 0, time.UTC)
+	repo := New()
+	orderID := uuid.New()
+	lines := []order.Line{{ProductID: uuid.New(), Name: "Beer", Quantity: 1}}
+
+	var tickets []ticket.Ticket
+	for i, station := range []ticket.Station{ticket.StationBar, ticket.StationKitchen, ticket.StationBar} {
+		tk, err := ticket.NewTicket(orderID, station, lines, now.Add(time.Duration(i)*time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.Add(tk); err != nil {
+			t.Fatal(err)
+		}
+		tickets = append(tickets, tk)
+	}
+	if err := repo.Add(tickets[0]); err != ticket.ErrTicketAlreadyExist {
+		t.Errorf("Expected error %v, got %v", ticket.ErrTicketAlreadyExist, err)
+	}
+
+	done := tickets[0]
+	if err := done.Cancel(); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Update(done); err != nil {
+		t.Fatal(err)
+	}
+
+	queue, err := repo.Queue(ticket.StationBar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queue) != 1 || queue[0].GetID() != tickets[2].GetID() {
+		t.Errorf("Expected only the open bar ticket to be queued, got %v", queue)
+	}
+	byOrder, err := repo.ByOrder(orderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(byOrder) != 3 || byOrder[0].GetID() != tickets[0].GetID() {
+		t.Errorf("Expected the tickets of the order oldest first, got %v", byOrder)
+	}
+
+	if _, err := repo.Get(uuid.New()); err != ticket.ErrTicketNotFound {
+		t.Errorf("Expected error %v, got %v", ticket.ErrTicketNotFound, err)
+	}
+}
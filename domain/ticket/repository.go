@@ -0,0 +1,25 @@
+package ticket
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrTicketNotFound is returned when a ticket is not found
+	ErrTicketNotFound = errors.New("the ticket was not found")
+	// ErrTicketAlreadyExist is returned when trying to add a ticket that already exists
+	ErrTicketAlreadyExist = errors.New("the ticket already exists")
+)
+
+// Repository is the repository interface to fulfill to use the ticket aggregate
+type Repository interface {
+	Get(id uuid.UUID) (Ticket, error)
+	Add(t Ticket) error
+	Update(t Ticket) error
+	// Queue returns the open tickets of the station, oldest first
+	Queue(station Station) ([]Ticket, error)
+	// ByOrder returns the tickets of the order, oldest first
+	ByOrder(orderID uuid.UUID) ([]Ticket, error)
+}
@@ -0,0 +1,78 @@
+package ticket
+
+import (
+	"errors"
+
+	"github.com/gegaryfa/tavern/domain/order"
+)
+
+// ErrInvalidStation is returned when routing products to a station without a name
+var ErrInvalidStation = errors.New("the station is not valid")
+
+// RouterConfiguration is an alias for a function that configures a Router
+type RouterConfiguration func(r *Router) error
+
+// Router decides the station preparing the lines of an order from the category of their product
+type Router struct {
+	routes map[string]Station
+	// fallback prepares the lines of the categories without a route
+	fallback Station
+}
+
+// NewRouter creates a Router sending the drinks to the bar and everything else to the kitchen
+func NewRouter(cfgs ...RouterConfiguration) (*Router, error) {
+	r := &Router{
+		routes:   map[string]Station{"drinks": StationBar},
+		fallback: StationKitchen,
+	}
+	for _, cfg := range cfgs {
+		if err := cfg(r); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// WithRoute sends the products of the category to the station
+func WithRoute(category string, station Station) RouterConfiguration {
+	return func(r *Router) error {
+		if station == "" {
+			return ErrInvalidStation
+		}
+		r.routes[category] = station
+		return nil
+	}
+}
+
+// WithFallback sends the products of the categories without a route to the station
+func WithFallback(station Station) RouterConfiguration {
+	return func(r *Router) error {
+		if station == "" {
+			return ErrInvalidStation
+		}
+		r.fallback = station
+		return nil
+	}
+}
+
+// Station returns the station preparing the products of the category
+func (r *Router) Station(category string) Station {
+	if s, ok := r.routes[category]; ok {
+		return s
+	}
+	return r.fallback
+}
+
+// Route groups the lines by the station preparing them, the stations are in the order of their first line
+func (r *Router) Route(lines []order.Line) ([]Station, map[Station][]order.Line) {
+	var stations []Station
+	routed := make(map[Station][]order.Line)
+	for _, l := range lines {
+		s := r.Station(l.Category)
+		if _, ok := routed[s]; !ok {
+			stations = append(stations, s)
+		}
+		routed[s] = append(routed[s], l)
+	}
+	return stations, routed
+}
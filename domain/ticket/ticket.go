@@ -0,0 +1,234 @@
+// Package ticket holds the aggregate of the work a station of the tavern, such as the bar, does for an order
+package ticket
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gegaryfa/tavern/domain/order"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrMissingOrder is returned when a ticket is created without an order
+	ErrMissingOrder = errors.New("a ticket has to belong to an order")
+	// ErrNoLines is returned when a ticket is created without anything to prepare
+	ErrNoLines = errors.New("a ticket has to hold at least one line")
+	// ErrMissingStaff is returned when a ticket is claimed, started or completed without a member of staff
+	ErrMissingStaff = errors.New("a ticket has to be handled by a member of staff")
+	// ErrAlreadyClaimed is returned when claiming a ticket that was claimed already
+	ErrAlreadyClaimed = errors.New("the ticket was claimed already")
+	// ErrNotClaimed is returned when starting or completing a ticket the member of staff did not claim
+	ErrNotClaimed = errors.New("the ticket was not claimed by the member of staff")
+	// ErrNotStarted is returned when completing a ticket that was not started
+	ErrNotStarted = errors.New("the ticket was not started")
+	// ErrTicketClosed is returned when handling a ticket that was completed or cancelled
+	ErrTicketClosed = errors.New("the ticket is closed")
+)
+
+// Station is the part of the tavern preparing the products, such as the bar
+type Station string
+
+const (
+	StationBar     Station = "bar"
+	StationKitchen Station = "kitchen"
+)
+
+// Status is the stage of the preparation of a ticket
+type Status string
+
+const (
+	// StatusQueued tickets are waiting for a member of staff
+	StatusQueued Status = "queued"
+	// StatusClaimed tickets were taken by a member of staff
+	StatusClaimed Status = "claimed"
+	// StatusInProgress tickets are being prepared
+	StatusInProgress Status = "in_progress"
+	// StatusDone tickets were prepared
+	StatusDone Status = "done"
+	// StatusCancelled tickets were called off with their order
+	StatusCancelled Status = "cancelled"
+)
+
+// Ticket is the aggregate of the lines of an order a station prepares
+type Ticket struct {
+	id      uuid.UUID
+	orderID uuid.UUID
+	station Station
+	// lines holds the ordered products the station prepares
+	lines  []order.Line
+	status Status
+	// staffID is the member of staff who claimed the ticket
+	staffID     uuid.UUID
+	createdAt   time.Time
+	claimedAt   time.Time
+	startedAt   time.Time
+	completedAt time.Time
+}
+
+// NewTicket is a factory to queue a new Ticket for the station
+func NewTicket(orderID uuid.UUID, station Station, lines []order.Line, createdAt time.Time) (Ticket, error) {
+	if orderID == uuid.Nil {
+		return Ticket{}, ErrMissingOrder
+	}
+	if len(lines) == 0 {
+		return Ticket{}, ErrNoLines
+	}
+	return Ticket{
+		id:        uuid.New(),
+		orderID:   orderID,
+		station:   station,
+		lines:     lines,
+		status:    StatusQueued,
+		createdAt: createdAt,
+	}, nil
+}
+
+func (t Ticket) GetID() uuid.UUID {
+	return t.id
+}
+
+func (t *Ticket) SetID(id uuid.UUID) {
+	t.id = id
+}
+
+func (t Ticket) GetOrderID() uuid.UUID {
+	return t.orderID
+}
+
+func (t *Ticket) SetOrderID(id uuid.UUID) {
+	t.orderID = id
+}
+
+func (t Ticket) GetStation() Station {
+	return t.station
+}
+
+func (t *Ticket) SetStation(station Station) {
+	t.station = station
+}
+
+func (t Ticket) GetLines() []order.Line {
+	return t.lines
+}
+
+func (t *Ticket) SetLines(lines []order.Line) {
+	t.lines = lines
+}
+
+func (t Ticket) GetStatus() Status {
+	return t.status
+}
+
+func (t *Ticket) SetStatus(status Status) {
+	t.status = status
+}
+
+func (t Ticket) GetStaffID() uuid.UUID {
+	return t.staffID
+}
+
+func (t *Ticket) SetStaffID(id uuid.UUID) {
+	t.staffID = id
+}
+
+func (t Ticket) GetCreatedAt() time.Time {
+	return t.createdAt
+}
+
+func (t *Ticket) SetCreatedAt(createdAt time.Time) {
+	t.createdAt = createdAt
+}
+
+func (t Ticket) GetClaimedAt() time.Time {
+	return t.claimedAt
+}
+
+func (t *Ticket) SetClaimedAt(claimedAt time.Time) {
+	t.claimedAt = claimedAt
+}
+
+func (t Ticket) GetStartedAt() time.Time {
+	return t.startedAt
+}
+
+func (t *Ticket) SetStartedAt(startedAt time.Time) {
+	t.startedAt = startedAt
+}
+
+func (t Ticket) GetCompletedAt() time.Time {
+	return t.completedAt
+}
+
+func (t *Ticket) SetCompletedAt(completedAt time.Time) {
+	t.completedAt = completedAt
+}
+
+// IsOpen reports whether the ticket still has to be prepared
+func (t Ticket) IsOpen() bool {
+	return t.status != StatusDone && t.status != StatusCancelled
+}
+
+// Claim gives the ticket to the member of staff, a ticket is claimed once
+func (t *Ticket) Claim(staffID uuid.UUID, at time.Time) error {
+	if staffID == uuid.Nil {
+		return ErrMissingStaff
+	}
+	switch {
+	case !t.IsOpen():
+		return ErrTicketClosed
+	case t.status != StatusQueued:
+		return ErrAlreadyClaimed
+	}
+	t.status = StatusClaimed
+	t.staffID = staffID
+	t.claimedAt = at
+	return nil
+}
+
+// Start begins the preparation, only the member of staff who claimed the ticket can start it
+func (t *Ticket) Start(staffID uuid.UUID, at time.Time) error {
+	if err := t.check(staffID); err != nil {
+		return err
+	}
+	if t.status == StatusClaimed {
+		t.status = StatusInProgress
+		t.startedAt = at
+	}
+	return nil
+}
+
+// Complete ends the preparation of a started ticket, only the member of staff who claimed it can complete it
+func (t *Ticket) Complete(staffID uuid.UUID, at time.Time) error {
+	if err := t.check(staffID); err != nil {
+		return err
+	}
+	if t.status != StatusInProgress {
+		return ErrNotStarted
+	}
+	t.status = StatusDone
+	t.completedAt = at
+	return nil
+}
+
+// Cancel calls the ticket off, such as when its order is cancelled
+func (t *Ticket) Cancel() error {
+	if !t.IsOpen() {
+		return ErrTicketClosed
+	}
+	t.status = StatusCancelled
+	return nil
+}
+
+// check makes sure the open ticket was claimed by the member of staff
+func (t Ticket) check(staffID uuid.UUID) error {
+	switch {
+	case staffID == uuid.Nil:
+		return ErrMissingStaff
+	case !t.IsOpen():
+		return ErrTicketClosed
+	case t.status == StatusQueued || t.staffID != staffID:
+		return ErrNotClaimed
+	}
+	return nil
+}
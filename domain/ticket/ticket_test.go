@@ -0,0 +1,156 @@
+package ticket
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gegaryfa/tavern/domain/order"
+	"github.com/google/uuid"
+)
+
+func TestTicket_Lifecycle(t *testing.T) {
+	now := time.Date(2022, 11, 1, 18, 0, 0, 0, time.UTC)
+	barman, cook := uuid.New(), uuid.New()
+	newTicket := func(t *testing.T) Ticket {
+		tk, err := NewTicket(uuid.New(), StationBar, []order.Line{{ProductID: uuid.New(), Name: "Beer", Quantity: 2}}, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tk
+	}
+
+	type testCase struct {
+		test           string
+		operations     []func(tk *Ticket) error
+		expectedErr    error
+		expectedStatus Status
+	}
+
+	claim := func(staff uuid.UUID) func(tk *Ticket) error {
+		return func(tk *Ticket) error { return tk.Claim(staff, now) }
+	}
+	start := func(staff uuid.UUID) func(tk *Ticket) error {
+		return func(tk *Ticket) error { return tk.Start(staff, now) }
+	}
+	complete := func(staff uuid.UUID) func(tk *Ticket) error {
+		return func(tk *Ticket) error { return tk.Complete(staff, now) }
+	}
+
+	testCases := []testCase{
+		{
+			test:           "Prepare a ticket",
+			operations:     []func(tk *Ticket) error{claim(barman), start(barman), complete(barman)},
+			expectedStatus: StatusDone,
+		}, {
+			test:           "Claim a claimed ticket",
+			operations:     []func(tk *Ticket) error{claim(barman), claim(cook)},
+			expectedErr:    ErrAlreadyClaimed,
+			expectedStatus: StatusClaimed,
+		}, {
+			test:           "Start a ticket claimed by someone else",
+			operations:     []func(tk *Ticket) error{claim(barman), start(cook)},
+			expectedErr:    ErrNotClaimed,
+			expectedStatus: StatusClaimed,
+		}, {
+			test:           "Start an unclaimed ticket",
+			operations:     []func(tk *Ticket) error{start(barman)},
+			expectedErr:    ErrNotClaimed,
+			expectedStatus: StatusQueued,
+		}, {
+			test:           "Complete a ticket that was not started",
+			operations:     []func(tk *Ticket) error{claim(barman), complete(barman)},
+			expectedErr:    ErrNotStarted,
+			expectedStatus: StatusClaimed,
+		}, {
+			test:           "Claim a cancelled ticket",
+			operations:     []func(tk *Ticket) error{(*Ticket).Cancel, claim(barman)},
+			expectedErr:    ErrTicketClosed,
+			expectedStatus: StatusCancelled,
+		}, {
+			test:           "Claim without staff",
+			operations:     []func(tk *Ticket) error{claim(uuid.Nil)},
+			expectedErr:    ErrMissingStaff,
+			expectedStatus: StatusQueued,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			tk := newTicket(t)
+			var err error
+			for _, op := range tc.operations {
+				if err = op(&tk); err != nil {
+					break
+				}
+			}
+			if err != tc.expectedErr {
+				t.Errorf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+			if tk.GetStatus() != tc.expectedStatus {
+				t.Errorf("Expected status %s, got %s", tc.expectedStatus, tk.GetStatus())
+			}
+		})
+	}
+
+	if _, err := NewTicket(uuid.New(), StationBar, nil, now); err != ErrNoLines {
+		t.Errorf("Expected error %v, got %v", ErrNoLines, err)
+	}
+}
+
+func TestRouter_Route(t *testing.T) {
+	lines := []order.Line{
+		{Name: "Burger", Category: "food", Quantity: 1},
+		{Name: "Beer", Category: "drinks", Quantity: 2},
+		{Name: "Peanuts", Category: "snacks", Quantity: 1},
+	}
+
+	type testCase struct {
+		test             string
+		cfgs             []RouterConfiguration
+		expectedStations []Station
+		expectedBar      []string
+	}
+
+	testCases := []testCase{
+		{
+			test:             "Drinks go to the bar",
+			expectedStations: []Station{StationKitchen, StationBar},
+			expectedBar:      []string{"Beer"},
+		}, {
+			test:             "Snacks are served at the bar",
+			cfgs:             []RouterConfiguration{WithRoute("snacks", StationBar)},
+			expectedStations: []Station{StationKitchen, StationBar},
+			expectedBar:      []string{"Beer", "Peanuts"},
+		}, {
+			test:             "Everything goes to the bar",
+			cfgs:             []RouterConfiguration{WithFallback(StationBar)},
+			expectedStations: []Station{StationBar},
+			expectedBar:      []string{"Burger", "Beer", "Peanuts"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			r, err := NewRouter(tc.cfgs...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			stations, routed := r.Route(lines)
+			if !reflect.DeepEqual(stations, tc.expectedStations) {
+				t.Errorf("Expected stations %v, got %v", tc.expectedStations, stations)
+			}
+			var bar []string
+			for _, l := range routed[StationBar] {
+				bar = append(bar, l.Name)
+			}
+			if !reflect.DeepEqual(bar, tc.expectedBar) {
+				t.Errorf("Expected %v at the bar, got %v", tc.expectedBar, bar)
+			}
+		})
+	}
+
+	if _, err := NewRouter(WithRoute("food", "")); err != ErrInvalidStation {
+		t.Errorf("Expected error %v, got %v", ErrInvalidStation, err)
+	}
+}
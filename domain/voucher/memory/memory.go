@@ -0,0 +1,72 @@
+package memory
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gegaryfa/tavern/domain/voucher"
+	"github.com/google/uuid"
+)
+
+type Repository struct {
+	vouchers map[string]voucher.Voucher
+	sync.Mutex
+}
+
+// New is a factory function to generate a new repository of vouchers
+func New() *Repository {
+	return &Repository{
+		vouchers: make(map[string]voucher.Voucher),
+	}
+}
+
+func (r *Repository) Get(code string) (voucher.Voucher, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if v, ok := r.vouchers[voucher.NormalizeCode(code)]; ok {
+		return v, nil
+	}
+	return voucher.Voucher{}, voucher.ErrVoucherNotFound
+}
+
+func (r *Repository) Add(v voucher.Voucher) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if _, ok := r.vouchers[v.GetCode()]; ok {
+		return voucher.ErrVoucherAlreadyExist
+	}
+	r.vouchers[v.GetCode()] = v
+	return nil
+}
+
+func (r *Repository) Redeem(code string, customerID, orderID uuid.UUID, at time.Time) (voucher.Voucher, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	v, ok := r.vouchers[voucher.NormalizeCode(code)]
+	if !ok {
+		return voucher.Voucher{}, voucher.ErrVoucherNotFound
+	}
+	if err := v.Redeem(customerID, orderID, at); err != nil {
+		return voucher.Voucher{}, err
+	}
+	r.vouchers[v.GetCode()] = v
+	return v, nil
+}
+
+func (r *Repository) Release(code string, orderID uuid.UUID) error {
+	r.Lock()
+	defer r.Unlock()
+
+	v, ok := r.vouchers[voucher.NormalizeCode(code)]
+	if !ok {
+		return voucher.ErrVoucherNotFound
+	}
+	if err := v.Release(orderID); err != nil {
+		return err
+	}
+	r.vouchers[v.GetCode()] = v
+	return nil
+}
@@ -0,0 +1,55 @@
+package memory
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gegaryfa/tavern/domain/voucher"
+	"github.com/google/uuid"
+)
+
+func TestMemoryVoucherRepository_Redeem(t *testing.T) {
+	repo := New()
+	v, err := voucher.NewVoucher("SPRING", voucher.WithAmount(5), voucher.WithMaxRedemptions(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Add(v); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Add(v); err != voucher.ErrVoucherAlreadyExist {
+		t.Errorf("Expected error %v, got %v", voucher.ErrVoucherAlreadyExist, err)
+	}
+	if _, err := repo.Redeem("WINTER", uuid.New(), uuid.New(), time.Now()); err != voucher.ErrVoucherNotFound {
+		t.Errorf("Expected error %v, got %v", voucher.ErrVoucherNotFound, err)
+	}
+
+	// Concurrent orders must never redeem the voucher more often than allowed
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	redeemed := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := repo.Redeem("spring", uuid.New(), uuid.New(), time.Now()); err == nil {
+				mu.Lock()
+				redeemed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if redeemed != 5 {
+		t.Errorf("Expected 5 redemptions, got %d", redeemed)
+	}
+	stored, err := repo.Get("SPRING")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored.GetRedemptions()) != 5 {
+		t.Errorf("Expected 5 stored redemptions, got %d", len(stored.GetRedemptions()))
+	}
+}
@@ -0,0 +1,26 @@
+package voucher
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrVoucherNotFound is returned when no voucher has the code
+	ErrVoucherNotFound = errors.New("the voucher was not found")
+	// ErrVoucherAlreadyExist is returned when adding a voucher with a code that is already in use
+	ErrVoucherAlreadyExist = errors.New("the voucher already exists")
+)
+
+// Repository is the repository interface to fulfill to use the voucher aggregate
+type Repository interface {
+	Get(code string) (Voucher, error)
+	Add(voucher Voucher) error
+	// Redeem records a redemption of the voucher by the customer for the order, checking the limits and
+	// recording the redemption happen atomically so that concurrent orders can not exceed the limits
+	Redeem(code string, customerID, orderID uuid.UUID, at time.Time) (Voucher, error)
+	// Release atomically undoes the redemption of the voucher made for the order
+	Release(code string, orderID uuid.UUID) error
+}
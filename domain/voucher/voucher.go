@@ -0,0 +1,256 @@
+// Package voucher holds the aggregate of discount codes customers redeem when ordering
+package voucher
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidCode is returned when a voucher is created without a code
+	ErrInvalidCode = errors.New("a voucher has to have a code")
+	// ErrInvalidValue is returned when a voucher is not worth exactly one positive amount or percentage
+	ErrInvalidValue = errors.New("a voucher has to be worth either an amount or a percentage up to 100")
+	// ErrInvalidLimit is returned when a redemption limit is negative
+	ErrInvalidLimit = errors.New("the redemption limit must not be negative")
+	// ErrVoucherExpired is returned when redeeming a voucher after its expiry
+	ErrVoucherExpired = errors.New("the voucher has expired")
+	// ErrVoucherExhausted is returned when redeeming a voucher that reached its maximum redemptions
+	ErrVoucherExhausted = errors.New("the voucher has been redeemed the maximum number of times")
+	// ErrCustomerLimitReached is returned when a customer redeemed a voucher as many times as allowed
+	ErrCustomerLimitReached = errors.New("the customer can not redeem the voucher again")
+	// ErrNotRedeemed is returned when releasing a redemption that was never made for the order
+	ErrNotRedeemed = errors.New("the voucher was not redeemed for the order")
+)
+
+// Configuration is an alias for a function that sets an optional property of a Voucher
+type Configuration func(v *Voucher) error
+
+// Redemption is a value object recording a single use of a voucher
+type Redemption struct {
+	CustomerID uuid.UUID
+	// OrderID is the order the voucher was redeemed for, it is released when that order is called off
+	OrderID uuid.UUID
+	At      time.Time
+}
+
+// Voucher is the aggregate of a discount code, it is worth either a fixed amount or a percentage of the order
+type Voucher struct {
+	id   uuid.UUID
+	code string
+	// amount is taken off the order total, capped at the total
+	amount float64
+	// percent of the order total taken off, 25 means a quarter off
+	percent float64
+	// expiresAt is the instant the voucher stops being redeemable, zero never expires
+	expiresAt time.Time
+	// maxRedemptions is how many times the voucher can be redeemed in total, 0 is unlimited
+	maxRedemptions int
+	// perCustomerLimit is how many times a single customer can redeem the voucher, 0 is unlimited
+	perCustomerLimit int
+	// redemptions holds every use of the voucher, oldest first
+	redemptions []Redemption
+}
+
+// NewVoucher is a factory to create a new Voucher, one of WithAmount or WithPercent has to be given
+func NewVoucher(code string, cfgs ...Configuration) (Voucher, error) {
+	code = NormalizeCode(code)
+	if code == "" {
+		return Voucher{}, ErrInvalidCode
+	}
+
+	v := Voucher{
+		id:          uuid.New(),
+		code:        code,
+		redemptions: make([]Redemption, 0),
+	}
+	for _, cfg := range cfgs {
+		if err := cfg(&v); err != nil {
+			return Voucher{}, err
+		}
+	}
+	if (v.amount > 0) == (v.percent > 0) {
+		return Voucher{}, ErrInvalidValue
+	}
+	return v, nil
+}
+
+// NormalizeCode returns the canonical form of a code, codes are case insensitive
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// WithAmount makes the voucher worth a fixed amount
+func WithAmount(amount float64) Configuration {
+	return func(v *Voucher) error {
+		if amount <= 0 {
+			return ErrInvalidValue
+		}
+		v.amount = amount
+		return nil
+	}
+}
+
+// WithPercent makes the voucher worth a percentage of the order total
+func WithPercent(percent float64) Configuration {
+	return func(v *Voucher) error {
+		if percent <= 0 || percent > 100 {
+			return ErrInvalidValue
+		}
+		v.percent = percent
+		return nil
+	}
+}
+
+// WithExpiry stops the voucher from being redeemed from expiresAt on
+func WithExpiry(expiresAt time.Time) Configuration {
+	return func(v *Voucher) error {
+		v.expiresAt = expiresAt
+		return nil
+	}
+}
+
+// WithMaxRedemptions limits how many times the voucher can be redeemed in total
+func WithMaxRedemptions(n int) Configuration {
+	return func(v *Voucher) error {
+		if n < 0 {
+			return ErrInvalidLimit
+		}
+		v.maxRedemptions = n
+		return nil
+	}
+}
+
+// WithPerCustomerLimit limits how many times a single customer can redeem the voucher
+func WithPerCustomerLimit(n int) Configuration {
+	return func(v *Voucher) error {
+		if n < 0 {
+			return ErrInvalidLimit
+		}
+		v.perCustomerLimit = n
+		return nil
+	}
+}
+
+func (v Voucher) GetID() uuid.UUID {
+	return v.id
+}
+
+func (v *Voucher) SetID(id uuid.UUID) {
+	v.id = id
+}
+
+func (v Voucher) GetCode() string {
+	return v.code
+}
+
+func (v *Voucher) SetCode(code string) {
+	v.code = NormalizeCode(code)
+}
+
+func (v Voucher) GetAmount() float64 {
+	return v.amount
+}
+
+func (v *Voucher) SetAmount(amount float64) {
+	v.amount = amount
+}
+
+func (v Voucher) GetPercent() float64 {
+	return v.percent
+}
+
+func (v *Voucher) SetPercent(percent float64) {
+	v.percent = percent
+}
+
+func (v Voucher) GetExpiresAt() time.Time {
+	return v.expiresAt
+}
+
+func (v *Voucher) SetExpiresAt(expiresAt time.Time) {
+	v.expiresAt = expiresAt
+}
+
+func (v Voucher) GetMaxRedemptions() int {
+	return v.maxRedemptions
+}
+
+func (v *Voucher) SetMaxRedemptions(n int) {
+	v.maxRedemptions = n
+}
+
+func (v Voucher) GetPerCustomerLimit() int {
+	return v.perCustomerLimit
+}
+
+func (v *Voucher) SetPerCustomerLimit(n int) {
+	v.perCustomerLimit = n
+}
+
+func (v Voucher) GetRedemptions() []Redemption {
+	return v.redemptions
+}
+
+func (v *Voucher) SetRedemptions(redemptions []Redemption) {
+	v.redemptions = redemptions
+}
+
+// RedemptionsBy returns how many times the customer redeemed the voucher
+func (v Voucher) RedemptionsBy(customerID uuid.UUID) int {
+	var n int
+	for _, r := range v.redemptions {
+		if r.CustomerID == customerID {
+			n++
+		}
+	}
+	return n
+}
+
+// Redeem records a use of the voucher by the customer for the order at the given instant, it fails when the
+// voucher is expired or when the total or the per customer limit has been reached
+func (v *Voucher) Redeem(customerID, orderID uuid.UUID, at time.Time) error {
+	if !v.expiresAt.IsZero() && !at.Before(v.expiresAt) {
+		return ErrVoucherExpired
+	}
+	if v.maxRedemptions > 0 && len(v.redemptions) >= v.maxRedemptions {
+		return ErrVoucherExhausted
+	}
+	if v.perCustomerLimit > 0 && v.RedemptionsBy(customerID) >= v.perCustomerLimit {
+		return ErrCustomerLimitReached
+	}
+	// Copy on write so that copies of the voucher handed out earlier are not changed
+	redemptions := make([]Redemption, len(v.redemptions), len(v.redemptions)+1)
+	copy(redemptions, v.redemptions)
+	v.redemptions = append(redemptions, Redemption{CustomerID: customerID, OrderID: orderID, At: at})
+	return nil
+}
+
+// Release undoes the redemption made for the order, it is used when the order fails or is cancelled
+func (v *Voucher) Release(orderID uuid.UUID) error {
+	for i := len(v.redemptions) - 1; i >= 0; i-- {
+		if v.redemptions[i].OrderID != orderID {
+			continue
+		}
+		redemptions := make([]Redemption, 0, len(v.redemptions)-1)
+		redemptions = append(redemptions, v.redemptions[:i]...)
+		v.redemptions = append(redemptions, v.redemptions[i+1:]...)
+		return nil
+	}
+	return ErrNotRedeemed
+}
+
+// Discount returns how much the voucher takes off an order of the given total, it never exceeds the total
+func (v Voucher) Discount(total float64) float64 {
+	discount := v.amount
+	if v.percent > 0 {
+		discount = total * v.percent / 100
+	}
+	if discount > total {
+		return total
+	}
+	return discount
+}
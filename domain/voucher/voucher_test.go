@@ -0,0 +1,136 @@
+package voucher
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestNewVoucher(t *testing.T) {
+	type testCase struct {
+		test        string
+		code        string
+		cfgs        []Configuration
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{
+			test:        "Missing code",
+			code:        " ",
+			cfgs:        []Configuration{WithAmount(5)},
+			expectedErr: ErrInvalidCode,
+		}, {
+			test:        "No value",
+			code:        "SPRING",
+			expectedErr: ErrInvalidValue,
+		}, {
+			test:        "Amount and percent",
+			code:        "SPRING",
+			cfgs:        []Configuration{WithAmount(5), WithPercent(10)},
+			expectedErr: ErrInvalidValue,
+		}, {
+			test:        "Percent above 100",
+			code:        "SPRING",
+			cfgs:        []Configuration{WithPercent(150)},
+			expectedErr: ErrInvalidValue,
+		}, {
+			test:        "Negative limit",
+			code:        "SPRING",
+			cfgs:        []Configuration{WithAmount(5), WithMaxRedemptions(-1)},
+			expectedErr: ErrInvalidLimit,
+		}, {
+			test:        "Valid voucher",
+			code:        "spring",
+			cfgs:        []Configuration{WithPercent(10), WithPerCustomerLimit(1)},
+			expectedErr: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			v, err := NewVoucher(tc.code, tc.cfgs...)
+			if err != tc.expectedErr {
+				t.Fatalf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+			if err == nil && v.GetCode() != "SPRING" {
+				t.Errorf("Expected code SPRING, got %s", v.GetCode())
+			}
+		})
+	}
+}
+
+func TestVoucher_Redeem(t *testing.T) {
+	now := time.Date(2022, 11, 1, 18, 0, 0, 0, time.UTC)
+	percy, anna := uuid.New(), uuid.New()
+	first, second := uuid.New(), uuid.New()
+
+	v, err := NewVoucher("SPRING",
+		WithAmount(5),
+		WithExpiry(now.Add(time.Hour)),
+		WithMaxRedemptions(3),
+		WithPerCustomerLimit(2),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type testCase struct {
+		test        string
+		customer    uuid.UUID
+		order       uuid.UUID
+		at          time.Time
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{test: "First redemption", customer: percy, order: first, at: now, expectedErr: nil},
+		{test: "Second redemption", customer: percy, order: second, at: now, expectedErr: nil},
+		{test: "Per customer limit", customer: percy, order: uuid.New(), at: now, expectedErr: ErrCustomerLimitReached},
+		{test: "Expired", customer: anna, order: uuid.New(), at: now.Add(time.Hour), expectedErr: ErrVoucherExpired},
+		{test: "Other customer", customer: anna, order: uuid.New(), at: now, expectedErr: nil},
+		{test: "Maximum redemptions", customer: anna, order: uuid.New(), at: now, expectedErr: ErrVoucherExhausted},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			err := v.Redeem(tc.customer, tc.order, tc.at)
+			if err != tc.expectedErr {
+				t.Errorf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+
+	// Cancelling the first order releases its redemption, not the latest one of the customer
+	if err := v.Release(first); err != nil {
+		t.Fatal(err)
+	}
+	if len(v.GetRedemptions()) != 2 || v.RedemptionsBy(percy) != 1 || v.GetRedemptions()[0].OrderID != second {
+		t.Errorf("Expected the redemption of the first order to be released, got %v", v.GetRedemptions())
+	}
+	if err := v.Release(first); err != ErrNotRedeemed {
+		t.Errorf("Expected error %v, got %v", ErrNotRedeemed, err)
+	}
+}
+
+func TestVoucher_Discount(t *testing.T) {
+	fixed, err := NewVoucher("FIVE", WithAmount(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tenth, err := NewVoucher("TENTH", WithPercent(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := fixed.Discount(20); got != 5 {
+		t.Errorf("Expected discount 5, got %v", got)
+	}
+	if got := fixed.Discount(3); got != 3 {
+		t.Errorf("Expected the discount to be capped at 3, got %v", got)
+	}
+	if got := tenth.Discount(20); got != 2 {
+		t.Errorf("Expected discount 2, got %v", got)
+	}
+}
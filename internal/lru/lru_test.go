@@ -0,0 +1,49 @@
+package lru
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCache_Eviction(t *testing.T) {
+	c := New[string, int](2, 0)
+	c.Add("beer", 1)
+	c.Add("wine", 2)
+	// Touch beer so wine becomes the least recently used entry
+	c.Get("beer")
+	c.Add("peanuts", 3)
+
+	if _, ok := c.Get("wine"); ok {
+		t.Errorf("Expected wine to be evicted")
+	}
+	if v, ok := c.Get("beer"); !ok || v != 1 {
+		t.Errorf("Expected beer to be cached, got %v %v", v, ok)
+	}
+	if c.Len() != 2 {
+		t.Errorf("Expected 2 entries, got %d", c.Len())
+	}
+
+	stats := c.Stats()
+	if stats.Hits != 2 || stats.Misses != 1 || stats.Evictions != 1 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestCache_Expiry(t *testing.T) {
+	now := time.Date(2022, 11, 1, 20, 0, 0, 0, time.UTC)
+	c := New[string, int](10, time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.Add("beer", 1)
+	if _, ok := c.Get("beer"); !ok {
+		t.Fatalf("Expected beer to be cached")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, ok := c.Get("beer"); ok {
+		t.Errorf("Expected beer to be expired")
+	}
+	if c.Len() != 0 {
+		t.Errorf("Expected expired entry to be dropped, got %d entries", c.Len())
+	}
+}
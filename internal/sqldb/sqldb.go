@@ -0,0 +1,97 @@
+// Package sqldb holds the small helpers shared by the database/sql repositories,
+// such as placeholder rebinding and embedded schema migrations.
+package sqldb
+
+import (
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Rebind rewrites the ? placeholders in query to the style expected by the driver.
+// PostgreSQL drivers expect numbered placeholders ($1, $2, ...), SQLite accepts ? as is.
+func Rebind(driverName, query string) string {
+	if !isPostgres(driverName) {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r != '?' {
+			b.WriteRune(r)
+			continue
+		}
+		n++
+		b.WriteString("$")
+		b.WriteString(strconv.Itoa(n))
+	}
+	return b.String()
+}
+
+func isPostgres(driverName string) bool {
+	switch driverName {
+	case "postgres", "pgx", "pgx/v5":
+		return true
+	}
+	return false
+}
+
+// Migrate applies all the *.sql files found in migrations, in lexical order, that have not been applied yet.
+// Applied migrations are recorded in the schema_migrations table under component/filename so that
+// several repositories can share one database.
+func Migrate(db *sql.DB, driverName, component string, migrations fs.FS) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (name TEXT PRIMARY KEY)`)
+	if err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	files, err := fs.Glob(migrations, "*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		name := component + "/" + file
+
+		var count int
+		err := db.QueryRow(Rebind(driverName, `SELECT COUNT(*) FROM schema_migrations WHERE name = ?`), name).Scan(&count)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+
+		stmt, err := fs.ReadFile(migrations, file)
+		if err != nil {
+			return err
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(string(stmt)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %s: %w", name, err)
+		}
+		if _, err := tx.Exec(Rebind(driverName, `INSERT INTO schema_migrations (name) VALUES (?)`), name); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EscapeLike escapes the LIKE wildcards so that s is matched literally, the query must use ESCAPE '\'
+func EscapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
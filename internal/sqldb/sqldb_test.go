@@ -0,0 +1,34 @@
+package sqldb
+
+import "testing"
+
+func TestRebind(t *testing.T) {
+	type testCase struct {
+		test     string
+		driver   string
+		query    string
+		expected string
+	}
+
+	testCases := []testCase{
+		{
+			test:     "sqlite keeps question marks",
+			driver:   "sqlite",
+			query:    "SELECT * FROM customers WHERE id = ? AND name = ?",
+			expected: "SELECT * FROM customers WHERE id = ? AND name = ?",
+		}, {
+			test:     "postgres uses numbered placeholders",
+			driver:   "postgres",
+			query:    "SELECT * FROM customers WHERE id = ? AND name = ?",
+			expected: "SELECT * FROM customers WHERE id = $1 AND name = $2",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			if got := Rebind(tc.driver, tc.query); got != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
@@ -2,6 +2,8 @@
 package tavern
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 )
 
@@ -11,6 +13,8 @@ type Person struct {
 	ID uuid.UUID
 	// Name is the name of the person
 	Name string
-	// Age is the age of the person
+	// Age is the age of the person, it is only used when the date of birth is unknown
 	Age int
+	// DateOfBirth is the zero time when it is unknown
+	DateOfBirth time.Time
 }
@@ -0,0 +1,252 @@
+// Package billing charges customers for what they ordered
+package billing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/gegaryfa/tavern"
+	"github.com/gegaryfa/tavern/domain/customer"
+	"github.com/gegaryfa/tavern/domain/ledger"
+	"github.com/gegaryfa/tavern/telemetry"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	// ErrNoCustomerRepository is returned when a CustomerBilling is created without a customer repository
+	ErrNoCustomerRepository = errors.New("no customer repository configured")
+	// ErrInvalidBill is returned when a bill has a negative amount, tip or service charge, or more tax than amount
+	ErrInvalidBill = errors.New("the amounts of a bill must not be negative and the tax must not exceed the amount")
+	// ErrInvalidRefund is returned when a refund is not positive, has a negative amount or holds more tax than amount
+	ErrInvalidRefund = errors.New("a refund has to be positive and the tax must not exceed the amount")
+	// ErrNoJournal is returned when reconciling the customers of a billing that books nothing in the ledger
+	ErrNoJournal = errors.New("no journal configured")
+	// ErrNotReconciled is returned when the journal does not match the transactions recorded on a customer
+	ErrNotReconciled = errors.New("the journal does not match the transactions of the customer")
+)
+
+// Bill is a value object holding what a customer is charged
+type Bill struct {
+	CustomerID uuid.UUID
+	// OrderIDs are the orders paid by the bill
+	OrderIDs []uuid.UUID
+	// Amount is the total of the orders
+	Amount float64
+	// Tax is the part of the amount collected for the tax authority
+	Tax float64
+	// ServiceCharge is added for large parties
+	ServiceCharge float64
+	// Tip is given to the staff on top of the bill
+	Tip float64
+	// StaffID is the member of staff receiving the tip, uuid.Nil leaves it to the tip pool of the tavern
+	StaffID uuid.UUID
+}
+
+// Total returns everything the customer pays
+func (b Bill) Total() float64 {
+	return b.Amount + b.ServiceCharge + b.Tip
+}
+
+// Refund is a value object holding money given back to a customer for orders they were billed for
+type Refund struct {
+	CustomerID uuid.UUID
+	// OrderIDs are the orders the money is given back for
+	OrderIDs []uuid.UUID
+	Amount   float64
+	// Tax is the part of the amount that was collected for the tax authority
+	Tax float64
+	// ServiceCharge and Tip give back what was billed on top of the orders, such as when a whole bill is reversed
+	ServiceCharge float64
+	Tip           float64
+	// Reason explains why the money is given back, such as a cancellation
+	Reason string
+}
+
+// Total returns everything the customer is paid back
+func (r Refund) Total() float64 {
+	return r.Amount + r.ServiceCharge + r.Tip
+}
+
+// valid tells whether the amounts of the refund add up
+func (r Refund) valid() bool {
+	return tavern.Cents(r.Total()) > 0 && r.Amount >= 0 && r.ServiceCharge >= 0 && r.Tip >= 0 &&
+		r.Tax >= 0 && r.Tax <= r.Amount
+}
+
+// Service is the interface to fulfill to bill customers
+type Service interface {
+	Bill(ctx context.Context, bill Bill) error
+	// Refund reverses a bill, or part of it, by paying the customer back
+	Refund(ctx context.Context, refund Refund) error
+}
+
+// CustomerBillingConfiguration is an alias for a function that will take in a pointer to a CustomerBilling and modify it
+type CustomerBillingConfiguration func(b *CustomerBilling) error
+
+// CustomerBilling bills customers by recording the payment, the service charge and the tip as separate
+// transactions of the customer, paid to the tavern
+type CustomerBilling struct {
+	Customers customer.Repository
+
+	// tavernID is the party the customers pay
+	tavernID uuid.UUID
+	// journal records the bills in the ledger, nothing is recorded when it is nil
+	journal ledger.Journal
+	// now returns the current time, transactions are created at that instant
+	now    func() time.Time
+	tracer trace.Tracer
+	logger *slog.Logger
+}
+
+// NewCustomerBilling creates a CustomerBilling, a customer repository has to be configured
+func NewCustomerBilling(cfgs ...CustomerBillingConfiguration) (*CustomerBilling, error) {
+	b := &CustomerBilling{
+		now:    time.Now,
+		tracer: otel.Tracer(telemetry.TracerName),
+		logger: telemetry.NopLogger(),
+	}
+	for _, cfg := range cfgs {
+		if err := cfg(b); err != nil {
+			return nil, err
+		}
+	}
+	if b.Customers == nil {
+		return nil, ErrNoCustomerRepository
+	}
+	return b, nil
+}
+
+// WithCustomerRepository records the transactions on the customers of cr
+func WithCustomerRepository(cr customer.Repository) CustomerBillingConfiguration {
+	return func(b *CustomerBilling) error {
+		b.Customers = cr
+		return nil
+	}
+}
+
+// WithTavernID sets the party the customers pay
+func WithTavernID(id uuid.UUID) CustomerBillingConfiguration {
+	return func(b *CustomerBilling) error {
+		b.tavernID = id
+		return nil
+	}
+}
+
+// WithJournal records every bill as an entry of the ledger
+func WithJournal(j ledger.Journal) CustomerBillingConfiguration {
+	return func(b *CustomerBilling) error {
+		b.journal = j
+		return nil
+	}
+}
+
+// WithClock replaces the clock used to date the transactions, it is meant for tests
+func WithClock(now func() time.Time) CustomerBillingConfiguration {
+	return func(b *CustomerBilling) error {
+		b.now = now
+		return nil
+	}
+}
+
+// WithTracerProvider creates the spans of the CustomerBilling from tp instead of the global provider
+func WithTracerProvider(tp trace.TracerProvider) CustomerBillingConfiguration {
+	return func(b *CustomerBilling) error {
+		b.tracer = tp.Tracer(telemetry.TracerName)
+		return nil
+	}
+}
+
+// WithLogger writes the logs of the CustomerBilling to logger, by default nothing is logged
+func WithLogger(logger *slog.Logger) CustomerBillingConfiguration {
+	return func(b *CustomerBilling) error {
+		b.logger = telemetry.ContextLogger(logger)
+		return nil
+	}
+}
+
+// Bill records the bill as transactions of the customer, amounts that are zero are not recorded
+func (b *CustomerBilling) Bill(ctx context.Context, bill Bill) (err error) {
+	ctx, span := b.tracer.Start(ctx, "CustomerBilling.Bill", trace.WithAttributes(
+		attribute.String("customer.id", bill.CustomerID.String()),
+		attribute.Float64("bill.total", bill.Total()),
+	))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	if !bill.valid() {
+		return ErrInvalidBill
+	}
+
+	if _, err := b.Customers.Get(bill.CustomerID); err != nil {
+		return err
+	}
+	// The bill is booked before the customer is charged, the entry is reversed when charging fails
+	now := b.now()
+	entry, err := b.record(bill, ledger.CustomerAccount(bill.CustomerID), now)
+	if err != nil {
+		return err
+	}
+	reference := reference(bill.OrderIDs)
+	c, err := customer.Change(b.Customers, bill.CustomerID, func(c *customer.Customer) error {
+		if cents := tavern.Cents(bill.Amount); cents > 0 {
+			c.AddTransaction(tavern.NewTransaction(cents, c.GetID(), b.tavernID, now).
+				WithReference(reference).
+				WithKind(tavern.TransactionPayment))
+		}
+		if cents := tavern.Cents(bill.ServiceCharge); cents > 0 {
+			c.AddTransaction(tavern.NewTransaction(cents, c.GetID(), b.tavernID, now).
+				WithReference(reference).
+				WithKind(tavern.TransactionServiceCharge))
+		}
+		if cents := tavern.Cents(bill.Tip); cents > 0 {
+			to := bill.StaffID
+			if to == uuid.Nil {
+				to = b.tavernID
+			}
+			c.AddTransaction(tavern.NewTransaction(cents, c.GetID(), to, now).
+				WithReference(reference).
+				WithKind(tavern.TransactionTip))
+		}
+		return nil
+	})
+	if err != nil {
+		return b.reverse(ctx, entry, err)
+	}
+
+	b.logger.InfoContext(ctx, "customer billed",
+		slog.String("customer_id", c.GetID().String()),
+		slog.Float64("amount", bill.Amount),
+		slog.Float64("service_charge", bill.ServiceCharge),
+		slog.Float64("tip", bill.Tip),
+	)
+	return nil
+}
+
+// valid tells whether the amounts of the bill add up
+func (b Bill) valid() bool {
+	return b.Amount >= 0 && b.ServiceCharge >= 0 && b.Tip >= 0 && b.Tax >= 0 && b.Tax <= b.Amount
+}
+
+// reference describes the orders a bill pays for
+func reference(orderIDs []uuid.UUID) string {
+	switch len(orderIDs) {
+	case 0:
+		return ""
+	case 1:
+		return fmt.Sprintf("order %s", orderIDs[0])
+	default:
+		return fmt.Sprintf("%d orders from %s", len(orderIDs), orderIDs[0])
+	}
+}
@@ -0,0 +1,264 @@
+package billing
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gegaryfa/tavern"
+	"github.com/gegaryfa/tavern/domain/customer"
+	"github.com/gegaryfa/tavern/domain/customer/memory"
+	"github.com/gegaryfa/tavern/domain/ledger"
+	ledgermemory "github.com/gegaryfa/tavern/domain/ledger/memory"
+	"github.com/google/uuid"
+)
+
+func TestCustomerBilling_Bill(t *testing.T) {
+	now := time.Date(2022, 11, 1, 18, 0, 0, 0, time.UTC)
+	tavernID, staffID := uuid.New(), uuid.New()
+	customers := memory.New()
+
+	b, err := NewCustomerBilling(
+		WithCustomerRepository(customers),
+		WithTavernID(tavernID),
+		WithClock(func() time.Time { return now }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := customer.NewCustomer("Percy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := customers.Add(c); err != nil {
+		t.Fatal(err)
+	}
+
+	bill := Bill{CustomerID: c.GetID(), OrderIDs: []uuid.UUID{uuid.New()}, Amount: 20, ServiceCharge: 2, Tip: 1.5, StaffID: staffID}
+	if err := b.Bill(context.Background(), bill); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Bill(context.Background(), Bill{CustomerID: c.GetID(), Amount: 5, Tip: -1}); err != ErrInvalidBill {
+		t.Errorf("Expected error %v, got %v", ErrInvalidBill, err)
+	}
+
+	c, err = customers.Get(c.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	type expected struct {
+		kind   tavern.TransactionKind
+		amount int
+		to     uuid.UUID
+	}
+	want := []expected{
+		{tavern.TransactionPayment, 2000, tavernID},
+		{tavern.TransactionServiceCharge, 200, tavernID},
+		{tavern.TransactionTip, 150, staffID},
+	}
+	got := c.GetTransactions()
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d transactions, got %d", len(want), len(got))
+	}
+	for i, w := range want {
+		if got[i].GetKind() != w.kind || got[i].GetAmount() != w.amount || got[i].GetTo() != w.to || got[i].GetFrom() != c.GetID() {
+			t.Errorf("Expected %v, got %v", w, got[i])
+		}
+	}
+}
+
+func TestCustomerBilling_TipReport(t *testing.T) {
+	now := time.Date(2022, 11, 1, 18, 0, 0, 0, time.UTC)
+	tavernID, staffID := uuid.New(), uuid.New()
+	customers := memory.New()
+
+	b, err := NewCustomerBilling(
+		WithCustomerRepository(customers),
+		WithTavernID(tavernID),
+		WithClock(func() time.Time { return now }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"Percy", "Anna"} {
+		c, err := customer.NewCustomer(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := customers.Add(c); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.Bill(context.Background(), Bill{CustomerID: c.GetID(), Amount: 10, Tip: 1, StaffID: staffID}); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.Bill(context.Background(), Bill{CustomerID: c.GetID(), Amount: 10, Tip: 0.5}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report, err := b.TipReport(now.Add(-time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Tips[staffID] != 2 || report.Tips[tavernID] != 1 || report.Total() != 3 {
+		t.Errorf("Expected 2 for the staff and 1 for the pool, got %v", report.Tips)
+	}
+
+	report, err = b.TipReport(now.Add(time.Hour), now.Add(2*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Total() != 0 {
+		t.Errorf("Expected no tips outside the period, got %v", report.Tips)
+	}
+}
+
+func TestNewCustomerBilling_NoRepository(t *testing.T) {
+	if _, err := NewCustomerBilling(); err != ErrNoCustomerRepository {
+		t.Errorf("Expected error %v, got %v", ErrNoCustomerRepository, err)
+	}
+}
+
+func TestCustomerBilling_Journal(t *testing.T) {
+	now := time.Date(2022, 11, 1, 18, 0, 0, 0, time.UTC)
+	customers := memory.New()
+	journal := ledgermemory.New()
+
+	b, err := NewCustomerBilling(
+		WithCustomerRepository(customers),
+		WithJournal(journal),
+		WithClock(func() time.Time { return now }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := customer.NewCustomer("Percy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := customers.Add(c); err != nil {
+		t.Fatal(err)
+	}
+
+	bill := Bill{CustomerID: c.GetID(), OrderIDs: []uuid.UUID{uuid.New()}, Amount: 12.4, Tax: 2.4, ServiceCharge: 1, Tip: 1.5}
+	if err := b.Bill(context.Background(), bill); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Bill(context.Background(), Bill{CustomerID: c.GetID(), Amount: 5, Tax: 6}); err != ErrInvalidBill {
+		t.Errorf("Expected error %v, got %v", ErrInvalidBill, err)
+	}
+
+	type testCase struct {
+		account  ledger.Account
+		expected int
+	}
+
+	testCases := []testCase{
+		{account: ledger.CustomerAccount(c.GetID()), expected: -1490},
+		{account: ledger.Revenue, expected: 1100},
+		{account: ledger.TaxPayable, expected: 240},
+		{account: ledger.Tips, expected: 150},
+	}
+
+	for _, tc := range testCases {
+		got, err := journal.Balance(tc.account.ID, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.expected {
+			t.Errorf("Expected %s balance %d, got %d", tc.account.Type, tc.expected, got)
+		}
+	}
+	if err := b.Reconcile(context.Background(), c.GetID()); err != nil {
+		t.Errorf("Expected the journal to reconcile, got %v", err)
+	}
+
+	// An entry without the transactions on the customer, such as one that could not be reversed, is found
+	stray, err := ledger.NewEntry("order", now, tavern.NewTransaction(500, c.GetID(), ledger.Revenue.ID, now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := journal.Record(stray); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Reconcile(context.Background(), c.GetID()); !errors.Is(err, ErrNotReconciled) {
+		t.Errorf("Expected error %v, got %v", ErrNotReconciled, err)
+	}
+}
+
+// failingJournal fails to record any entry
+type failingJournal struct {
+	ledger.Journal
+}
+
+func (failingJournal) Record(ledger.Entry) error {
+	return errors.New("journal unavailable")
+}
+
+// failingCustomers fails to update any customer
+type failingCustomers struct {
+	customer.Repository
+}
+
+func (failingCustomers) Update(customer.Customer) error {
+	return customer.ErrUpdateCustomer
+}
+
+func TestBilling_JournalFirst(t *testing.T) {
+	customers := memory.New()
+	c, err := customer.NewCustomer("Percy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.TopUp(20, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if err := customers.Add(c); err != nil {
+		t.Fatal(err)
+	}
+	bill := Bill{CustomerID: c.GetID(), Amount: 12.4, Tax: 2.4, Tip: 1.5}
+	refund := Refund{CustomerID: c.GetID(), Amount: 5, Tax: 1}
+
+	// Nothing is charged when the journal can not book the bill
+	b, err := NewWalletBilling(WithCustomerRepository(customers), WithJournal(failingJournal{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Bill(context.Background(), bill); err == nil {
+		t.Error("Expected the journal to fail the bill")
+	}
+	if err := b.Refund(context.Background(), refund); err == nil {
+		t.Error("Expected the journal to fail the refund")
+	}
+	if _, err := b.TopUp(context.Background(), c.GetID(), 5); err == nil {
+		t.Error("Expected the journal to fail the top up")
+	}
+	if balance, _ := b.Balance(c.GetID()); balance != 20 {
+		t.Errorf("Expected balance 20, got %v", balance)
+	}
+
+	// The journal entry is reversed when the customer can not be charged
+	journal := ledgermemory.New()
+	cb, err := NewCustomerBilling(WithCustomerRepository(failingCustomers{customers}), WithJournal(journal))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wb, err := NewWalletBilling(WithCustomerRepository(failingCustomers{customers}), WithJournal(journal))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, service := range []Service{cb, wb} {
+		if err := service.Bill(context.Background(), bill); err != customer.ErrUpdateCustomer {
+			t.Errorf("Expected error %v, got %v", customer.ErrUpdateCustomer, err)
+		}
+		if err := service.Refund(context.Background(), refund); err != customer.ErrUpdateCustomer {
+			t.Errorf("Expected error %v, got %v", customer.ErrUpdateCustomer, err)
+		}
+	}
+	for _, account := range []ledger.Account{ledger.CustomerAccount(c.GetID()), ledger.WalletAccount(c.WalletID()), ledger.Revenue, ledger.Tips} {
+		if got, err := journal.Balance(account.ID, time.Now()); err != nil || got != 0 {
+			t.Errorf("Expected the %s balance to be reversed, got %d %v", account.Type, got, err)
+		}
+	}
+}
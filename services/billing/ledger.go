@@ -0,0 +1,154 @@
+package billing
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/gegaryfa/tavern"
+	"github.com/gegaryfa/tavern/domain/customer"
+	"github.com/gegaryfa/tavern/domain/ledger"
+	"github.com/google/uuid"
+)
+
+// record books the bill paid out of the payer account in the journal. The tax is owed to the tax authority,
+// the rest of the amount and the service charge are revenue and the tip goes to the staff.
+func (b *CustomerBilling) record(bill Bill, payer ledger.Account, at time.Time) (ledger.Entry, error) {
+	if b.journal == nil {
+		return ledger.Entry{}, nil
+	}
+	legs := []struct {
+		amount float64
+		to     ledger.Account
+		kind   tavern.TransactionKind
+	}{
+		{bill.Amount - bill.Tax, ledger.Revenue, tavern.TransactionPayment},
+		{bill.Tax, ledger.TaxPayable, tavern.TransactionPayment},
+		{bill.ServiceCharge, ledger.Revenue, tavern.TransactionServiceCharge},
+		{bill.Tip, ledger.Tips, tavern.TransactionTip},
+	}
+	var transactions []tavern.Transaction
+	for _, leg := range legs {
+		if cents := tavern.Cents(leg.amount); cents > 0 {
+			transactions = append(transactions, tavern.NewTransaction(cents, payer.ID, leg.to.ID, at).WithKind(leg.kind))
+		}
+	}
+	if len(transactions) == 0 {
+		return ledger.Entry{}, nil
+	}
+	return b.recordEntry(reference(bill.OrderIDs), at, transactions...)
+}
+
+// recordRefund books the refund paid back to the payer account in the journal, reversing the legs of the bill
+func (b *CustomerBilling) recordRefund(refund Refund, payer ledger.Account, at time.Time) (ledger.Entry, error) {
+	if b.journal == nil {
+		return ledger.Entry{}, nil
+	}
+	legs := []struct {
+		amount float64
+		from   ledger.Account
+	}{
+		{refund.Amount - refund.Tax, ledger.Revenue},
+		{refund.Tax, ledger.TaxPayable},
+		{refund.ServiceCharge, ledger.Revenue},
+		{refund.Tip, ledger.Tips},
+	}
+	var transactions []tavern.Transaction
+	for _, leg := range legs {
+		if cents := tavern.Cents(leg.amount); cents > 0 {
+			transactions = append(transactions, tavern.NewTransaction(cents, leg.from.ID, payer.ID, at).WithKind(tavern.TransactionRefund))
+		}
+	}
+	return b.recordEntry(refund.reference(), at, transactions...)
+}
+
+// recordEntry books the transactions as a single entry of the journal
+func (b *CustomerBilling) recordEntry(reference string, at time.Time, transactions ...tavern.Transaction) (ledger.Entry, error) {
+	e, err := ledger.NewEntry(reference, at, transactions...)
+	if err != nil {
+		return ledger.Entry{}, err
+	}
+	if err := b.journal.Record(e); err != nil {
+		return ledger.Entry{}, fmt.Errorf("journal: %w", err)
+	}
+	return e, nil
+}
+
+// reverse books the reversal of an entry whose transactions could not be recorded on the customer and returns
+// the failure. Failing to book the reversal is logged, the journal then has to be corrected by hand.
+func (b *CustomerBilling) reverse(ctx context.Context, e ledger.Entry, cause error) error {
+	if e.GetID() == uuid.Nil {
+		return cause
+	}
+	now := b.now()
+	transactions := e.GetTransactions()
+	reversal := make([]tavern.Transaction, 0, len(transactions))
+	for _, t := range transactions {
+		reversal = append(reversal, tavern.NewTransaction(t.GetAmount(), t.GetTo(), t.GetFrom(), now).WithKind(t.GetKind()))
+	}
+	if _, err := b.recordEntry(fmt.Sprintf("reversal of %s", e.GetReference()), now, reversal...); err != nil {
+		b.logger.ErrorContext(ctx, "journal entry not reversed",
+			slog.String("entry_id", e.GetID().String()),
+			slog.String("error", err.Error()),
+		)
+	}
+	return cause
+}
+
+// booked are the kinds of the customer transactions that are booked in the journal, vouchers move no money
+var booked = map[tavern.TransactionKind]bool{
+	tavern.TransactionPayment:       true,
+	tavern.TransactionServiceCharge: true,
+	tavern.TransactionTip:           true,
+	tavern.TransactionTopUp:         true,
+	tavern.TransactionRefund:        true,
+}
+
+// Reconcile checks the balances of the customer and wallet accounts of the customer in the journal against the
+// transactions recorded on the customer, such as after an entry could not be reversed. The journal has to have
+// booked the transactions of the customer from the start.
+func (b *CustomerBilling) Reconcile(ctx context.Context, customerID uuid.UUID) error {
+	if b.journal == nil {
+		return ErrNoJournal
+	}
+	c, err := b.Customers.Get(customerID)
+	if err != nil {
+		return err
+	}
+	at := b.now()
+	for _, account := range []ledger.Account{ledger.CustomerAccount(c.GetID()), ledger.WalletAccount(c.WalletID())} {
+		journaled, err := b.journal.Balance(account.ID, at)
+		if err != nil {
+			return fmt.Errorf("journal: %w", err)
+		}
+		if recorded := transactionBalance(c, account.ID, at); journaled != recorded {
+			b.logger.ErrorContext(ctx, "journal not reconciled",
+				slog.String("customer_id", c.GetID().String()),
+				slog.String("account", string(account.Type)),
+				slog.Int("journal_balance", journaled),
+				slog.Int("transaction_balance", recorded),
+			)
+			return fmt.Errorf("%w: %s balance %d in the journal, %d in the transactions", ErrNotReconciled, account.Type, journaled, recorded)
+		}
+	}
+	return nil
+}
+
+// transactionBalance returns the balance of the account in cents from the booked transactions of the customer
+// made until the given instant, inclusive
+func transactionBalance(c customer.Customer, account uuid.UUID, at time.Time) int {
+	var balance int
+	for _, t := range c.GetTransactions() {
+		if !booked[t.GetKind()] || t.GetCreatedAt().After(at) {
+			continue
+		}
+		if t.GetTo() == account {
+			balance += t.GetAmount()
+		}
+		if t.GetFrom() == account {
+			balance -= t.GetAmount()
+		}
+	}
+	return balance
+}
@@ -0,0 +1,110 @@
+package billing
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/gegaryfa/tavern"
+	"github.com/gegaryfa/tavern/domain/customer"
+	"github.com/gegaryfa/tavern/domain/ledger"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Refund pays the customer back with a refund transaction from the tavern, the compensation of the payment
+func (b *CustomerBilling) Refund(ctx context.Context, refund Refund) (err error) {
+	ctx, span := b.tracer.Start(ctx, "CustomerBilling.Refund", trace.WithAttributes(
+		attribute.String("customer.id", refund.CustomerID.String()),
+		attribute.Float64("refund.total", refund.Total()),
+	))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	if !refund.valid() {
+		return ErrInvalidRefund
+	}
+	if _, err := b.Customers.Get(refund.CustomerID); err != nil {
+		return err
+	}
+	// The refund is booked before the customer is paid back, the entry is reversed when that fails
+	now := b.now()
+	entry, err := b.recordRefund(refund, ledger.CustomerAccount(refund.CustomerID), now)
+	if err != nil {
+		return err
+	}
+	c, err := customer.Change(b.Customers, refund.CustomerID, func(c *customer.Customer) error {
+		c.AddTransaction(tavern.NewTransaction(tavern.Cents(refund.Total()), b.tavernID, c.GetID(), now).
+			WithReference(refund.reference()).
+			WithKind(tavern.TransactionRefund))
+		return nil
+	})
+	if err != nil {
+		return b.reverse(ctx, entry, err)
+	}
+
+	b.logger.InfoContext(ctx, "customer refunded",
+		slog.String("customer_id", c.GetID().String()),
+		slog.Float64("total", refund.Total()),
+		slog.String("reason", refund.Reason),
+	)
+	return nil
+}
+
+// Refund puts the money back in the wallet of the customer
+func (w *WalletBilling) Refund(ctx context.Context, refund Refund) (err error) {
+	ctx, span := w.tracer.Start(ctx, "WalletBilling.Refund", trace.WithAttributes(
+		attribute.String("customer.id", refund.CustomerID.String()),
+		attribute.Float64("refund.total", refund.Total()),
+	))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	if !refund.valid() {
+		return ErrInvalidRefund
+	}
+
+	c, err := w.Customers.Get(refund.CustomerID)
+	if err != nil {
+		return err
+	}
+	// The refund is booked before the money is put back in the wallet, the entry is reversed when that fails
+	now := w.now()
+	entry, err := w.recordRefund(refund, ledger.WalletAccount(c.WalletID()), now)
+	if err != nil {
+		return err
+	}
+	c, err = customer.Change(w.Customers, refund.CustomerID, func(c *customer.Customer) error {
+		return c.RefundWallet(refund.Total(), w.tavernID, refund.reference(), now)
+	})
+	if err != nil {
+		return w.reverse(ctx, entry, err)
+	}
+
+	w.logger.InfoContext(ctx, "wallet refunded",
+		slog.String("customer_id", c.GetID().String()),
+		slog.Float64("total", refund.Total()),
+		slog.Float64("balance", c.Balance()),
+		slog.String("reason", refund.Reason),
+	)
+	return nil
+}
+
+// reference describes what the refund is for
+func (r Refund) reference() string {
+	if r.Reason == "" {
+		return reference(r.OrderIDs)
+	}
+	return fmt.Sprintf("%s: %s", reference(r.OrderIDs), r.Reason)
+}
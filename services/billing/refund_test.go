@@ -0,0 +1,85 @@
+package billing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gegaryfa/tavern"
+	"github.com/gegaryfa/tavern/domain/customer"
+	"github.com/gegaryfa/tavern/domain/customer/memory"
+	"github.com/gegaryfa/tavern/domain/ledger"
+	ledgermemory "github.com/gegaryfa/tavern/domain/ledger/memory"
+	"github.com/google/uuid"
+)
+
+func TestBilling_Refund(t *testing.T) {
+	customers := memory.New()
+	journal := ledgermemory.New()
+	b, err := NewCustomerBilling(WithCustomerRepository(customers), WithJournal(journal))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewWalletBilling(WithCustomerRepository(customers), WithJournal(journal))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := customer.NewCustomer("Percy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := customers.Add(c); err != nil {
+		t.Fatal(err)
+	}
+
+	orderID := uuid.New()
+	if err := b.Bill(context.Background(), Bill{CustomerID: c.GetID(), OrderIDs: []uuid.UUID{orderID}, Amount: 12.4, Tax: 2.4}); err != nil {
+		t.Fatal(err)
+	}
+	refund := Refund{CustomerID: c.GetID(), OrderIDs: []uuid.UUID{orderID}, Amount: 6.2, Tax: 1.2, Reason: "cancelled"}
+	if err := b.Refund(context.Background(), refund); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Refund(context.Background(), refund); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Refund(context.Background(), Refund{CustomerID: c.GetID(), Amount: 1, Tax: 2}); err != ErrInvalidRefund {
+		t.Errorf("Expected error %v, got %v", ErrInvalidRefund, err)
+	}
+
+	c, err = customers.Get(c.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := c.GetTransactions()
+	if len(got) != 3 || got[1].GetKind() != tavern.TransactionRefund || got[1].GetTo() != c.GetID() || got[1].GetAmount() != 620 {
+		t.Errorf("Expected a refund of 620 cents to the customer, got %v", got)
+	}
+	if c.Balance() != 6.2 {
+		t.Errorf("Expected the wallet refund to be put in the wallet, got balance %v", c.Balance())
+	}
+
+	type testCase struct {
+		account  ledger.Account
+		expected int
+	}
+
+	testCases := []testCase{
+		{account: ledger.CustomerAccount(c.GetID()), expected: -620},
+		{account: ledger.WalletAccount(c.WalletID()), expected: 620},
+		{account: ledger.Revenue, expected: 0},
+		{account: ledger.TaxPayable, expected: 0},
+	}
+
+	for _, tc := range testCases {
+		balance, err := journal.Balance(tc.account.ID, c.GetTransactions()[2].GetCreatedAt())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if balance != tc.expected {
+			t.Errorf("Expected %s balance %d, got %d", tc.account.Type, tc.expected, balance)
+		}
+	}
+	if err := w.Reconcile(context.Background(), c.GetID()); err != nil {
+		t.Errorf("Expected the journal to reconcile, got %v", err)
+	}
+}
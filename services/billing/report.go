@@ -0,0 +1,50 @@
+package billing
+
+import (
+	"time"
+
+	"github.com/gegaryfa/tavern"
+	"github.com/gegaryfa/tavern/domain/customer"
+	"github.com/google/uuid"
+)
+
+// TipReport holds the tips received in a period per member of staff, tips left to the pool
+// are reported under the tavern ID
+type TipReport struct {
+	From, To time.Time
+	Tips     map[uuid.UUID]float64
+}
+
+// Total returns the sum of all the tips of the report
+func (r TipReport) Total() float64 {
+	var total float64
+	for _, tip := range r.Tips {
+		total += tip
+	}
+	return total
+}
+
+// TipReport adds up the tip transactions of all customers created from from, inclusive, until to, exclusive
+func (b *CustomerBilling) TipReport(from, to time.Time) (TipReport, error) {
+	report := TipReport{From: from, To: to, Tips: make(map[uuid.UUID]float64)}
+
+	query := customer.ListQuery{}
+	for {
+		page, err := b.Customers.List(query)
+		if err != nil {
+			return TipReport{}, err
+		}
+		for _, c := range page.Customers {
+			for _, t := range c.GetTransactions() {
+				if t.GetKind() != tavern.TransactionTip || t.GetCreatedAt().Before(from) || !t.GetCreatedAt().Before(to) {
+					continue
+				}
+				report.Tips[t.GetTo()] += float64(t.GetAmount()) / 100
+			}
+		}
+		if page.NextCursor == "" {
+			return report, nil
+		}
+		query.Cursor = page.NextCursor
+	}
+}
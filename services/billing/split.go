@@ -0,0 +1,163 @@
+package billing
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/gegaryfa/tavern"
+	"github.com/gegaryfa/tavern/domain/order"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrNoPayers is returned when a split has no one to pay
+	ErrNoPayers = errors.New("a split needs at least one payer")
+	// ErrSplitMismatch is returned when the parts of a split do not add up exactly to the total
+	ErrSplitMismatch = errors.New("the parts of the split do not add up to the total")
+	// ErrUnassignedLine is returned when splitting by line and a line has no payer
+	ErrUnassignedLine = errors.New("a line of the bill has no payer")
+	// ErrUnknownLine is returned when splitting by line and a payer is given a line the bill does not have
+	ErrUnknownLine = errors.New("a payer is assigned a line the bill does not have")
+)
+
+// Share is a value object holding the part of a bill a customer pays
+type Share struct {
+	CustomerID uuid.UUID
+	Amount     float64
+}
+
+// Split decides how the total of a bill is shared between several customers, the shares add up exactly to the total
+type Split interface {
+	Shares(total float64, lines []order.Line) ([]Share, error)
+}
+
+// Even splits the total in equal parts, the cents that can not be split go to the first payers
+type Even struct {
+	Payers []uuid.UUID
+}
+
+func (s Even) Shares(total float64, _ []order.Line) ([]Share, error) {
+	if len(s.Payers) == 0 {
+		return nil, ErrNoPayers
+	}
+	weights := make([]float64, len(s.Payers))
+	for i := range weights {
+		weights[i] = 1
+	}
+	return shares(s.Payers, Allocate(tavern.Cents(total), weights)), nil
+}
+
+// ByLine makes every customer pay for the products they had, the total, with its discounts and taxes,
+// is shared in proportion to the lines of every payer
+type ByLine struct {
+	// Payers holds the customer paying for each line, by its index in the bill. The lines of a tab are
+	// those of its orders, oldest first. Every line needs a payer and every index has to be a line of the bill.
+	Payers map[int]uuid.UUID
+}
+
+func (s ByLine) Shares(total float64, lines []order.Line) ([]Share, error) {
+	if len(s.Payers) == 0 {
+		return nil, ErrNoPayers
+	}
+	// A line out of range is most likely a line of another bill, the payer would not pay anything
+	for i := range s.Payers {
+		if i < 0 || i >= len(lines) {
+			return nil, ErrUnknownLine
+		}
+	}
+	var payers []uuid.UUID
+	index := map[uuid.UUID]int{}
+	var weights []float64
+	for i, l := range lines {
+		payer, ok := s.Payers[i]
+		if !ok {
+			return nil, ErrUnassignedLine
+		}
+		p, ok := index[payer]
+		if !ok {
+			p = len(payers)
+			index[payer] = p
+			payers = append(payers, payer)
+			weights = append(weights, 0)
+		}
+		weights[p] += l.Total()
+	}
+	if len(payers) == 0 {
+		return nil, ErrNoPayers
+	}
+	return shares(payers, Allocate(tavern.Cents(total), weights)), nil
+}
+
+// ByAmount makes every customer pay the agreed amount, the amounts have to add up exactly to the total
+type ByAmount struct {
+	Amounts []Share
+}
+
+func (s ByAmount) Shares(total float64, _ []order.Line) ([]Share, error) {
+	if len(s.Amounts) == 0 {
+		return nil, ErrNoPayers
+	}
+	var sum int
+	for _, share := range s.Amounts {
+		if share.Amount < 0 {
+			return nil, ErrInvalidBill
+		}
+		sum += tavern.Cents(share.Amount)
+	}
+	if sum != tavern.Cents(total) {
+		return nil, ErrSplitMismatch
+	}
+	return s.Amounts, nil
+}
+
+// Allocate spreads cents over parts in proportion to their weights, with the largest remainder method
+// so that the parts add up exactly to cents. Ties go to the first parts.
+func Allocate(cents int, weights []float64) []int {
+	parts := make([]int, len(weights))
+	if len(weights) == 0 {
+		return parts
+	}
+	var sum float64
+	for _, w := range weights {
+		sum += w
+	}
+	// Without anything to weigh by the cents are split evenly
+	even := sum <= 0
+	if even {
+		sum = float64(len(weights))
+	}
+
+	remainders := make([]float64, len(weights))
+	left := cents
+	for i, w := range weights {
+		if even {
+			w = 1
+		}
+		exact := float64(cents) * w / sum
+		parts[i] = int(exact)
+		remainders[i] = exact - float64(parts[i])
+		left -= parts[i]
+	}
+
+	byRemainder := make([]int, len(weights))
+	for i := range byRemainder {
+		byRemainder[i] = i
+	}
+	sort.SliceStable(byRemainder, func(a, b int) bool {
+		return remainders[byRemainder[a]] > remainders[byRemainder[b]]
+	})
+	for i := 0; left > 0; i++ {
+		parts[byRemainder[i%len(byRemainder)]]++
+		left--
+	}
+	return parts
+}
+
+// shares pairs the payers with the cents they pay
+func shares(payers []uuid.UUID, cents []int) []Share {
+	result := make([]Share, len(payers))
+	for i, payer := range payers {
+		result[i] = Share{CustomerID: payer, Amount: float64(cents[i]) / 100}
+	}
+	return result
+}
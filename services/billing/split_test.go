@@ -0,0 +1,105 @@
+package billing
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gegaryfa/tavern/domain/order"
+	"github.com/google/uuid"
+)
+
+func TestSplit_Shares(t *testing.T) {
+	percy, anna, bob := uuid.New(), uuid.New(), uuid.New()
+	beer, wine := uuid.New(), uuid.New()
+	lines := []order.Line{
+		{ProductID: beer, Name: "Beer", Quantity: 3, UnitPrice: 2},
+		{ProductID: wine, Name: "Wine", Quantity: 1, UnitPrice: 4},
+	}
+
+	type testCase struct {
+		test  string
+		split Split
+		total float64
+		// lines defaults to the beers and the wine
+		lines          []order.Line
+		expectedShares []Share
+		expectedErr    error
+	}
+
+	testCases := []testCase{
+		{
+			test:           "Evenly",
+			split:          Even{Payers: []uuid.UUID{percy, anna, bob}},
+			total:          10,
+			expectedShares: []Share{{percy, 3.34}, {anna, 3.33}, {bob, 3.33}},
+		}, {
+			test:        "Evenly without payers",
+			split:       Even{},
+			total:       10,
+			expectedErr: ErrNoPayers,
+		}, {
+			test:  "By line",
+			split: ByLine{Payers: map[int]uuid.UUID{0: percy, 1: anna}},
+			// A 10% discount is shared in proportion to the lines
+			total:          9,
+			expectedShares: []Share{{percy, 5.4}, {anna, 3.6}},
+		}, {
+			test:           "By line with the same product",
+			split:          ByLine{Payers: map[int]uuid.UUID{0: percy, 1: anna, 2: bob}},
+			lines:          append(lines, order.Line{ProductID: beer, Name: "Beer", Quantity: 1, UnitPrice: 2}),
+			total:          12,
+			expectedShares: []Share{{percy, 6}, {anna, 4}, {bob, 2}},
+		}, {
+			test:        "By line with an unassigned line",
+			split:       ByLine{Payers: map[int]uuid.UUID{0: percy}},
+			total:       10,
+			expectedErr: ErrUnassignedLine,
+		}, {
+			test:        "By line with a line the bill does not have",
+			split:       ByLine{Payers: map[int]uuid.UUID{0: percy, 1: anna, 2: bob}},
+			total:       10,
+			expectedErr: ErrUnknownLine,
+		}, {
+			test:        "By line with a negative line",
+			split:       ByLine{Payers: map[int]uuid.UUID{-1: bob, 0: percy, 1: anna}},
+			total:       10,
+			expectedErr: ErrUnknownLine,
+		}, {
+			test:           "By amount",
+			split:          ByAmount{Amounts: []Share{{percy, 7.5}, {anna, 2.5}}},
+			total:          10,
+			expectedShares: []Share{{percy, 7.5}, {anna, 2.5}},
+		}, {
+			test:        "By amount not adding up",
+			split:       ByAmount{Amounts: []Share{{percy, 7.5}, {anna, 2.49}}},
+			total:       10,
+			expectedErr: ErrSplitMismatch,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			if tc.lines == nil {
+				tc.lines = lines
+			}
+			shares, err := tc.split.Shares(tc.total, tc.lines)
+			if err != tc.expectedErr {
+				t.Fatalf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+			if err == nil && !reflect.DeepEqual(shares, tc.expectedShares) {
+				t.Errorf("Expected shares %v, got %v", tc.expectedShares, shares)
+			}
+		})
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	got := Allocate(100, []float64{1, 1, 1})
+	if !reflect.DeepEqual(got, []int{34, 33, 33}) {
+		t.Errorf("Expected [34 33 33], got %v", got)
+	}
+	got = Allocate(7, []float64{0, 0})
+	if !reflect.DeepEqual(got, []int{4, 3}) {
+		t.Errorf("Expected [4 3], got %v", got)
+	}
+}
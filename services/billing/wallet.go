@@ -0,0 +1,143 @@
+package billing
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/gegaryfa/tavern"
+	"github.com/gegaryfa/tavern/domain/customer"
+	"github.com/gegaryfa/tavern/domain/ledger"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// WalletBilling bills customers by charging their prepaid wallet, the payment, the service charge and the tip
+// are paid out of the wallet as separate transactions. It is configured like a CustomerBilling.
+// Concurrent bills can not overdraw a wallet, every charge is made on the latest revision of the customer.
+type WalletBilling struct {
+	*CustomerBilling
+}
+
+// NewWalletBilling creates a WalletBilling, a customer repository has to be configured
+func NewWalletBilling(cfgs ...CustomerBillingConfiguration) (*WalletBilling, error) {
+	b, err := NewCustomerBilling(cfgs...)
+	if err != nil {
+		return nil, err
+	}
+	return &WalletBilling{CustomerBilling: b}, nil
+}
+
+// Bill charges the wallet of the customer, nothing is charged when the wallet can not pay the whole bill
+func (w *WalletBilling) Bill(ctx context.Context, bill Bill) (err error) {
+	ctx, span := w.tracer.Start(ctx, "WalletBilling.Bill", trace.WithAttributes(
+		attribute.String("customer.id", bill.CustomerID.String()),
+		attribute.Float64("bill.total", bill.Total()),
+	))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	if !bill.valid() {
+		return ErrInvalidBill
+	}
+
+	now := w.now()
+	reference := reference(bill.OrderIDs)
+	tipTo := bill.StaffID
+	if tipTo == uuid.Nil {
+		tipTo = w.tavernID
+	}
+	charges := []struct {
+		amount float64
+		to     uuid.UUID
+		kind   tavern.TransactionKind
+	}{
+		{bill.Amount, w.tavernID, tavern.TransactionPayment},
+		{bill.ServiceCharge, w.tavernID, tavern.TransactionServiceCharge},
+		{bill.Tip, tipTo, tavern.TransactionTip},
+	}
+	// The wallet is checked before booking the bill so that bills it can not pay are not booked and reversed,
+	// it is checked again when charging it
+	c, err := w.Customers.Get(bill.CustomerID)
+	if err != nil {
+		return err
+	}
+	if tavern.Cents(bill.Amount)+tavern.Cents(bill.ServiceCharge)+tavern.Cents(bill.Tip) > tavern.Cents(c.Balance()) {
+		return customer.ErrInsufficientFunds
+	}
+	entry, err := w.record(bill, ledger.WalletAccount(c.WalletID()), now)
+	if err != nil {
+		return err
+	}
+	// The charges are made on a copy, the customer is only stored when all of them succeeded
+	c, err = customer.Change(w.Customers, bill.CustomerID, func(c *customer.Customer) error {
+		for _, charge := range charges {
+			if tavern.Cents(charge.amount) <= 0 {
+				continue
+			}
+			if err := c.ChargeWallet(charge.amount, charge.to, charge.kind, reference, now); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return w.reverse(ctx, entry, err)
+	}
+
+	w.logger.InfoContext(ctx, "wallet charged",
+		slog.String("customer_id", c.GetID().String()),
+		slog.Float64("total", bill.Total()),
+		slog.Float64("balance", c.Balance()),
+	)
+	return nil
+}
+
+// TopUp puts money in the wallet of the customer and returns the new balance
+func (w *WalletBilling) TopUp(ctx context.Context, customerID uuid.UUID, amount float64) (float64, error) {
+	if tavern.Cents(amount) <= 0 {
+		return 0, customer.ErrInvalidAmount
+	}
+	c, err := w.Customers.Get(customerID)
+	if err != nil {
+		return 0, err
+	}
+	// The top up is booked before the money is put in the wallet, the entry is reversed when that fails
+	now := w.now()
+	var entry ledger.Entry
+	if w.journal != nil {
+		entry, err = w.recordEntry("wallet top up", now,
+			tavern.NewTransaction(tavern.Cents(amount), c.GetID(), c.WalletID(), now).WithKind(tavern.TransactionTopUp))
+		if err != nil {
+			return 0, err
+		}
+	}
+	c, err = customer.Change(w.Customers, customerID, func(c *customer.Customer) error {
+		return c.TopUp(amount, now)
+	})
+	if err != nil {
+		return 0, w.reverse(ctx, entry, err)
+	}
+
+	w.logger.InfoContext(ctx, "wallet topped up",
+		slog.String("customer_id", c.GetID().String()),
+		slog.Float64("amount", amount),
+		slog.Float64("balance", c.Balance()),
+	)
+	return c.Balance(), nil
+}
+
+// Balance returns the money left in the wallet of the customer
+func (w *WalletBilling) Balance(customerID uuid.UUID) (float64, error) {
+	c, err := w.Customers.Get(customerID)
+	if err != nil {
+		return 0, err
+	}
+	return c.Balance(), nil
+}
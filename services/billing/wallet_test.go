@@ -0,0 +1,67 @@
+package billing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gegaryfa/tavern/domain/customer"
+	"github.com/gegaryfa/tavern/domain/customer/memory"
+	"github.com/google/uuid"
+)
+
+func TestWalletBilling_Bill(t *testing.T) {
+	customers := memory.New()
+	w, err := NewWalletBilling(WithCustomerRepository(customers))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := customer.NewCustomer("Percy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := customers.Add(c); err != nil {
+		t.Fatal(err)
+	}
+
+	balance, err := w.TopUp(context.Background(), c.GetID(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance != 10 {
+		t.Errorf("Expected balance 10, got %v", balance)
+	}
+
+	staff := uuid.New()
+	if err := w.Bill(context.Background(), Bill{CustomerID: c.GetID(), Amount: 6, Tip: 1, StaffID: staff}); err != nil {
+		t.Fatal(err)
+	}
+	// The bill does not fit in what is left, nothing is charged
+	if err := w.Bill(context.Background(), Bill{CustomerID: c.GetID(), Amount: 2.5, Tip: 1}); err != customer.ErrInsufficientFunds {
+		t.Errorf("Expected error %v, got %v", customer.ErrInsufficientFunds, err)
+	}
+
+	balance, err = w.Balance(c.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance != 3 {
+		t.Errorf("Expected balance 3, got %v", balance)
+	}
+
+	// Tips paid out of the wallet are reported like any other tip
+	stored, err := customers.Get(c.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored.GetTransactions()) != 3 {
+		t.Fatalf("Expected a top up, a payment and a tip, got %v", stored.GetTransactions())
+	}
+	last := stored.GetTransactions()[2]
+	report, err := w.TipReport(last.GetCreatedAt(), last.GetCreatedAt().Add(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Tips[staff] != 1 {
+		t.Errorf("Expected a tip of 1, got %v", report.Tips)
+	}
+}
@@ -0,0 +1,277 @@
+// Package kitchen queues the placed orders at the stations preparing them, such as the bar, and tracks their preparation
+package kitchen
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	domainorder "github.com/gegaryfa/tavern/domain/order"
+	"github.com/gegaryfa/tavern/domain/ticket"
+	ticketmemory "github.com/gegaryfa/tavern/domain/ticket/memory"
+	"github.com/gegaryfa/tavern/telemetry"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	// ErrNoTicketRepository is returned when a Kitchen is created without a ticket repository
+	ErrNoTicketRepository = errors.New("no ticket repository configured")
+	// ErrNoOrderRepository is returned when a Kitchen is created without an order repository
+	ErrNoOrderRepository = errors.New("no order repository configured")
+)
+
+// KitchenConfiguration is an alias for a function that will take in a pointer to a Kitchen and modify it
+type KitchenConfiguration func(k *Kitchen) error
+
+// Kitchen splits the orders into a ticket per station and moves the orders along as their tickets are prepared
+type Kitchen struct {
+	Tickets ticket.Repository
+	// Orders holds the orders the tickets are prepared for, their status follows the tickets
+	Orders domainorder.Repository
+
+	// router decides the station preparing every line
+	router *ticket.Router
+	// mu serialises the ticket changes so that a ticket is claimed once and the status of its order is
+	// decided on all of its tickets
+	mu     sync.Mutex
+	now    func() time.Time
+	tracer trace.Tracer
+	logger *slog.Logger
+}
+
+// NewKitchen creates a Kitchen, a ticket and an order repository have to be configured. The drinks are sent
+// to the bar and everything else to the kitchen unless a router is configured.
+func NewKitchen(cfgs ...KitchenConfiguration) (*Kitchen, error) {
+	router, err := ticket.NewRouter()
+	if err != nil {
+		return nil, err
+	}
+	k := &Kitchen{
+		router: router,
+		now:    time.Now,
+		tracer: otel.Tracer(telemetry.TracerName),
+		logger: telemetry.NopLogger(),
+	}
+	for _, cfg := range cfgs {
+		if err := cfg(k); err != nil {
+			return nil, err
+		}
+	}
+	if k.Tickets == nil {
+		return nil, ErrNoTicketRepository
+	}
+	if k.Orders == nil {
+		return nil, ErrNoOrderRepository
+	}
+	return k, nil
+}
+
+// WithTicketRepository keeps the tickets in tr
+func WithTicketRepository(tr ticket.Repository) KitchenConfiguration {
+	return func(k *Kitchen) error {
+		k.Tickets = tr
+		return nil
+	}
+}
+
+// WithMemoryTicketRepository keeps the tickets in memory
+func WithMemoryTicketRepository() KitchenConfiguration {
+	return WithTicketRepository(ticketmemory.New())
+}
+
+// WithOrderRepository updates the status of the orders of or as their tickets are prepared
+func WithOrderRepository(or domainorder.Repository) KitchenConfiguration {
+	return func(k *Kitchen) error {
+		k.Orders = or
+		return nil
+	}
+}
+
+// WithRouter decides the stations preparing the products with r
+func WithRouter(r *ticket.Router) KitchenConfiguration {
+	return func(k *Kitchen) error {
+		k.router = r
+		return nil
+	}
+}
+
+// WithClock replaces the clock used to date the tickets, it is meant for tests
+func WithClock(now func() time.Time) KitchenConfiguration {
+	return func(k *Kitchen) error {
+		k.now = now
+		return nil
+	}
+}
+
+// WithTracerProvider creates the spans of the Kitchen from tp instead of the global provider
+func WithTracerProvider(tp trace.TracerProvider) KitchenConfiguration {
+	return func(k *Kitchen) error {
+		k.tracer = tp.Tracer(telemetry.TracerName)
+		return nil
+	}
+}
+
+// WithLogger writes the logs of the Kitchen to logger, by default nothing is logged
+func WithLogger(logger *slog.Logger) KitchenConfiguration {
+	return func(k *Kitchen) error {
+		k.logger = telemetry.ContextLogger(logger)
+		return nil
+	}
+}
+
+// Route queues a ticket at every station preparing lines of the placed order
+func (k *Kitchen) Route(ctx context.Context, placed domainorder.Order) ([]ticket.Ticket, error) {
+	ctx, span := k.tracer.Start(ctx, "Kitchen.Route", trace.WithAttributes(
+		attribute.String("order.id", placed.GetID().String()),
+	))
+	defer span.End()
+
+	stations, routed := k.router.Route(placed.GetLines())
+	tickets := make([]ticket.Ticket, 0, len(stations))
+	now := k.now()
+	for _, s := range stations {
+		t, err := ticket.NewTicket(placed.GetID(), s, routed[s], now)
+		if err == nil {
+			err = k.Tickets.Add(t)
+		}
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return nil, err
+		}
+		tickets = append(tickets, t)
+		k.logger.InfoContext(ctx, "ticket queued",
+			slog.String("order_id", placed.GetID().String()),
+			slog.String("ticket_id", t.GetID().String()),
+			slog.String("station", string(s)),
+		)
+	}
+	return tickets, nil
+}
+
+// Queue returns the tickets the station still has to prepare, oldest first
+func (k *Kitchen) Queue(station ticket.Station) ([]ticket.Ticket, error) {
+	return k.Tickets.Queue(station)
+}
+
+// Claim gives the ticket to the member of staff, a ticket is claimed once
+func (k *Kitchen) Claim(ctx context.Context, ticketID, staffID uuid.UUID) (ticket.Ticket, error) {
+	return k.change(ctx, "Kitchen.Claim", ticketID, func(t *ticket.Ticket) error {
+		return t.Claim(staffID, k.now())
+	})
+}
+
+// Start begins the preparation of the ticket, its order is then being prepared
+func (k *Kitchen) Start(ctx context.Context, ticketID, staffID uuid.UUID) (ticket.Ticket, error) {
+	return k.change(ctx, "Kitchen.Start", ticketID, func(t *ticket.Ticket) error {
+		if err := t.Start(staffID, k.now()); err != nil {
+			return err
+		}
+		return k.updateOrder(t.GetOrderID(), (*domainorder.Order).StartPreparing)
+	})
+}
+
+// Complete ends the preparation of the ticket, the order is ready once all its tickets are done
+func (k *Kitchen) Complete(ctx context.Context, ticketID, staffID uuid.UUID) (ticket.Ticket, error) {
+	completed, err := k.change(ctx, "Kitchen.Complete", ticketID, func(t *ticket.Ticket) error {
+		return t.Complete(staffID, k.now())
+	})
+	if err != nil {
+		return ticket.Ticket{}, err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	tickets, err := k.Tickets.ByOrder(completed.GetOrderID())
+	if err != nil {
+		return ticket.Ticket{}, err
+	}
+	for _, t := range tickets {
+		if t.IsOpen() {
+			return completed, nil
+		}
+	}
+	err = k.updateOrder(completed.GetOrderID(), (*domainorder.Order).MarkReady)
+	switch {
+	case errors.Is(err, domainorder.ErrOrderServed) || errors.Is(err, domainorder.ErrOrderCancelled):
+		// The ticket is done all the same, the order moved on without waiting for it
+		k.logger.WarnContext(ctx, "order not marked ready",
+			slog.String("order_id", completed.GetOrderID().String()),
+			slog.String("error", err.Error()),
+		)
+		return completed, nil
+	case err != nil:
+		return ticket.Ticket{}, err
+	}
+	k.logger.InfoContext(ctx, "order ready",
+		slog.String("order_id", completed.GetOrderID().String()),
+	)
+	return completed, nil
+}
+
+// CancelOrder calls off the tickets of the order that were not prepared yet, such as when the order is cancelled
+func (k *Kitchen) CancelOrder(ctx context.Context, orderID uuid.UUID) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	tickets, err := k.Tickets.ByOrder(orderID)
+	if err != nil {
+		return err
+	}
+	for _, t := range tickets {
+		if !t.IsOpen() {
+			continue
+		}
+		if err := t.Cancel(); err != nil {
+			return err
+		}
+		if err := k.Tickets.Update(t); err != nil {
+			return err
+		}
+		k.logger.InfoContext(ctx, "ticket cancelled",
+			slog.String("order_id", orderID.String()),
+			slog.String("ticket_id", t.GetID().String()),
+			slog.String("station", string(t.GetStation())),
+		)
+	}
+	return nil
+}
+
+// change loads the ticket, applies the change to it and stores it
+func (k *Kitchen) change(ctx context.Context, name string, ticketID uuid.UUID, apply func(*ticket.Ticket) error) (ticket.Ticket, error) {
+	_, span := k.tracer.Start(ctx, name, trace.WithAttributes(
+		attribute.String("ticket.id", ticketID.String()),
+	))
+	defer span.End()
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	t, err := k.Tickets.Get(ticketID)
+	if err == nil {
+		err = apply(&t)
+	}
+	if err == nil {
+		err = k.Tickets.Update(t)
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return ticket.Ticket{}, err
+	}
+	span.SetAttributes(attribute.String("ticket.status", string(t.GetStatus())))
+	return t, nil
+}
+
+// updateOrder applies the change of status to the order and stores it, a concurrent change such as a
+// cancellation is seen instead of being overwritten, see domainorder.Change
+func (k *Kitchen) updateOrder(orderID uuid.UUID, apply func(*domainorder.Order) error) error {
+	_, err := domainorder.Change(k.Orders, orderID, apply)
+	return err
+}
@@ -0,0 +1,122 @@
+package kitchen
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	domainorder "github.com/gegaryfa/tavern/domain/order"
+	ordermemory "github.com/gegaryfa/tavern/domain/order/memory"
+	"github.com/gegaryfa/tavern/domain/ticket"
+	"github.com/google/uuid"
+)
+
+func TestKitchen(t *testing.T) {
+	now := time.Date(2022, 11, 1, 18, 0, 0, 0, time.UTC)
+	orders := ordermemory.New()
+	k, err := NewKitchen(WithMemoryTicketRepository(), WithOrderRepository(orders), WithClock(func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	placed, err := domainorder.NewOrder(uuid.New(), []domainorder.Line{
+		{ProductID: uuid.New(), Name: "Beer", Category: "drinks", Quantity: 2, UnitPrice: 4},
+		{ProductID: uuid.New(), Name: "Burger", Category: "food", Quantity: 1, UnitPrice: 9},
+	}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := orders.Add(placed); err != nil {
+		t.Fatal(err)
+	}
+
+	tickets, err := k.Route(context.Background(), placed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tickets) != 2 {
+		t.Fatalf("Expected a ticket for the bar and the kitchen, got %v", tickets)
+	}
+	queue, err := k.Queue(ticket.StationBar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queue) != 1 || queue[0].GetLines()[0].Name != "Beer" {
+		t.Errorf("Expected the beer to be queued at the bar, got %v", queue)
+	}
+
+	barman, cook := uuid.New(), uuid.New()
+	orderStatus := func() domainorder.Status {
+		o, err := orders.Get(placed.GetID())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return o.GetStatus()
+	}
+
+	type testCase struct {
+		test           string
+		do             func() (ticket.Ticket, error)
+		expectedErr    error
+		expectedStatus domainorder.Status
+	}
+
+	bar, food := queue[0].GetID(), tickets[0].GetID()
+	if tickets[0].GetStation() == ticket.StationBar {
+		food = tickets[1].GetID()
+	}
+	ctx := context.Background()
+
+	testCases := []testCase{
+		{
+			test:           "Claimed ticket leaves the order placed",
+			do:             func() (ticket.Ticket, error) { return k.Claim(ctx, bar, barman) },
+			expectedStatus: domainorder.StatusPlaced,
+		}, {
+			test:           "Ticket is claimed once",
+			do:             func() (ticket.Ticket, error) { return k.Claim(ctx, bar, cook) },
+			expectedErr:    ticket.ErrAlreadyClaimed,
+			expectedStatus: domainorder.StatusPlaced,
+		}, {
+			test:           "Started ticket prepares the order",
+			do:             func() (ticket.Ticket, error) { return k.Start(ctx, bar, barman) },
+			expectedStatus: domainorder.StatusPreparing,
+		}, {
+			test:           "Order is not ready while a ticket is open",
+			do:             func() (ticket.Ticket, error) { return k.Complete(ctx, bar, barman) },
+			expectedStatus: domainorder.StatusPreparing,
+		}, {
+			test: "Order is ready once all its tickets are done",
+			do: func() (ticket.Ticket, error) {
+				if _, err := k.Claim(ctx, food, cook); err != nil {
+					return ticket.Ticket{}, err
+				}
+				if _, err := k.Start(ctx, food, cook); err != nil {
+					return ticket.Ticket{}, err
+				}
+				return k.Complete(ctx, food, cook)
+			},
+			expectedStatus: domainorder.StatusReady,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			if _, err := tc.do(); err != tc.expectedErr {
+				t.Errorf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+			if status := orderStatus(); status != tc.expectedStatus {
+				t.Errorf("Expected order status %s, got %s", tc.expectedStatus, status)
+			}
+		})
+	}
+
+	if queue, _ := k.Queue(ticket.StationBar); len(queue) != 0 {
+		t.Errorf("Expected the bar queue to be empty, got %v", queue)
+	}
+	if _, err := NewKitchen(WithMemoryTicketRepository()); err != ErrNoOrderRepository {
+		t.Errorf("Expected error %v, got %v", ErrNoOrderRepository, err)
+	}
+}
@@ -0,0 +1,178 @@
+package order
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	domainorder "github.com/gegaryfa/tavern/domain/order"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ServeOrder marks the order as brought to the customer, it can no longer be cancelled
+func (o *OrderService) ServeOrder(ctx context.Context, orderID uuid.UUID) (domainorder.Order, error) {
+	return o.change(ctx, "OrderService.ServeOrder", orderID, (*domainorder.Order).Serve)
+}
+
+// CancelOrder calls off an order that was not served yet and puts its products back in stock. Paying the
+// customer back is up to the caller, see RefundOrder.
+func (o *OrderService) CancelOrder(ctx context.Context, orderID uuid.UUID) (domainorder.Order, error) {
+	cancelled, err := o.change(ctx, "OrderService.CancelOrder", orderID, (*domainorder.Order).Cancel)
+	if err != nil {
+		return domainorder.Order{}, err
+	}
+	if cancelled.HasReservedStock() {
+		o.release(ctx, cancelled.GetLines())
+	}
+	o.logger.InfoContext(ctx, "order cancelled",
+		slog.String("customer_id", cancelled.GetCustomerID().String()),
+		slog.String("order_id", orderID.String()),
+	)
+	return cancelled, nil
+}
+
+// StartCancelling marks the order as being cancelled, nothing else happens to it until CancelOrder finishes the
+// cancellation or AbortCancelling takes it back. It returns the status the order was cancelled from, which is
+// StatusCancelling when an interrupted cancellation is resumed.
+func (o *OrderService) StartCancelling(ctx context.Context, orderID uuid.UUID) (domainorder.Order, domainorder.Status, error) {
+	var previous domainorder.Status
+	cancelling, err := o.change(ctx, "OrderService.StartCancelling", orderID, func(placed *domainorder.Order) error {
+		previous = placed.GetStatus()
+		return placed.StartCancelling()
+	})
+	if err != nil {
+		return domainorder.Order{}, "", err
+	}
+	return cancelling, previous, nil
+}
+
+// AbortCancelling puts an order that is being cancelled back at the status it was cancelled from
+func (o *OrderService) AbortCancelling(ctx context.Context, orderID uuid.UUID, previous domainorder.Status) (domainorder.Order, error) {
+	return o.change(ctx, "OrderService.AbortCancelling", orderID, func(placed *domainorder.Order) error {
+		return placed.AbortCancelling(previous)
+	})
+}
+
+// RefundOrder records money given back for the order, the refunds of an order can not exceed its total
+func (o *OrderService) RefundOrder(ctx context.Context, orderID uuid.UUID, amount float64, reason string) (domainorder.Order, error) {
+	refund := domainorder.Refund{Amount: amount, Reason: reason, At: o.now()}
+	return o.change(ctx, "OrderService.RefundOrder", orderID, func(placed *domainorder.Order) error {
+		return placed.Refund(refund)
+	})
+}
+
+// RevertRefund takes back a refund recorded with RefundOrder when paying it back failed
+func (o *OrderService) RevertRefund(ctx context.Context, orderID uuid.UUID, refund domainorder.Refund) (domainorder.Order, error) {
+	return o.change(ctx, "OrderService.RevertRefund", orderID, func(placed *domainorder.Order) error {
+		return placed.RevertRefund(refund)
+	})
+}
+
+// RecordPayers records who paid the bill of the order, see domainorder.Payer
+func (o *OrderService) RecordPayers(ctx context.Context, orderID uuid.UUID, payers []domainorder.Payer) (domainorder.Order, error) {
+	return o.change(ctx, "OrderService.RecordPayers", orderID, func(placed *domainorder.Order) error {
+		placed.SetPayers(payers)
+		return nil
+	})
+}
+
+// change applies the change to the order and stores it, see domainorder.Change
+func (o *OrderService) change(ctx context.Context, name string, orderID uuid.UUID, apply func(*domainorder.Order) error) (domainorder.Order, error) {
+	_, span := o.tracer.Start(ctx, name, trace.WithAttributes(
+		attribute.String("order.id", orderID.String()),
+	))
+	defer span.End()
+
+	placed, err := domainorder.Change(o.Orders, orderID, apply)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return domainorder.Order{}, err
+	}
+	span.SetAttributes(attribute.String("order.status", string(placed.GetStatus())))
+	return placed, nil
+}
+
+// ReservesStock reports whether the orders take the ordered products out of stock, see WithStockReservation
+func (o *OrderService) ReservesStock() bool {
+	return o.reserveStock
+}
+
+// ReserveStock takes the products out of stock ahead of the order, see WithReservedStock. Listing a product
+// several times reserves it several times, nothing stays reserved when a product is out of stock.
+func (o *OrderService) ReserveStock(ctx context.Context, productIDs []uuid.UUID) error {
+	return o.reserve(ctx, stockLines(productIDs))
+}
+
+// ReleaseStock puts products reserved with ReserveStock back in stock when no order was created for them
+func (o *OrderService) ReleaseStock(ctx context.Context, productIDs []uuid.UUID) {
+	o.release(ctx, stockLines(productIDs))
+}
+
+// stockLines counts the products into lines, named after the product IDs
+func stockLines(productIDs []uuid.UUID) []domainorder.Line {
+	var lines []domainorder.Line
+	index := make(map[uuid.UUID]int)
+	for _, id := range productIDs {
+		if i, ok := index[id]; ok {
+			lines[i].Quantity++
+			continue
+		}
+		index[id] = len(lines)
+		lines = append(lines, domainorder.Line{ProductID: id, Name: id.String(), Quantity: 1})
+	}
+	return lines
+}
+
+// reserve takes the ordered quantities out of stock, nothing stays reserved when a product is out of stock
+func (o *OrderService) reserve(ctx context.Context, lines []domainorder.Line) error {
+	o.stock.Lock()
+	defer o.stock.Unlock()
+
+	for i, l := range lines {
+		p, err := o.Products.GetByID(l.ProductID)
+		if err == nil {
+			err = p.Reserve(l.Quantity)
+		}
+		if err == nil {
+			err = o.Products.Update(p)
+		}
+		if err != nil {
+			o.restock(ctx, lines[:i])
+			return fmt.Errorf("%s: %w", l.Name, err)
+		}
+	}
+	return nil
+}
+
+// release puts the ordered quantities back in stock
+func (o *OrderService) release(ctx context.Context, lines []domainorder.Line) {
+	o.stock.Lock()
+	defer o.stock.Unlock()
+
+	o.restock(ctx, lines)
+}
+
+// restock puts the quantities back in stock, the stock lock has to be held. A product that can not be
+// restocked is logged, the stock then has to be corrected by hand.
+func (o *OrderService) restock(ctx context.Context, lines []domainorder.Line) {
+	for _, l := range lines {
+		p, err := o.Products.GetByID(l.ProductID)
+		if err == nil {
+			err = p.Release(l.Quantity)
+		}
+		if err == nil {
+			err = o.Products.Update(p)
+		}
+		if err != nil {
+			o.logger.ErrorContext(ctx, "stock not released",
+				slog.String("product_id", l.ProductID.String()),
+				slog.Int("quantity", l.Quantity),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+}
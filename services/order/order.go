@@ -2,30 +2,93 @@ package order
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
 
 	"github.com/gegaryfa/tavern/domain/customer"
+	custcache "github.com/gegaryfa/tavern/domain/customer/cache"
 	"github.com/gegaryfa/tavern/domain/customer/memory"
 	"github.com/gegaryfa/tavern/domain/customer/mongo"
+	custsql "github.com/gegaryfa/tavern/domain/customer/sql"
+	"github.com/gegaryfa/tavern/domain/idempotency"
+	domainorder "github.com/gegaryfa/tavern/domain/order"
+	ordermemory "github.com/gegaryfa/tavern/domain/order/memory"
+	ordersql "github.com/gegaryfa/tavern/domain/order/sql"
+	"github.com/gegaryfa/tavern/domain/pricing"
 	"github.com/gegaryfa/tavern/domain/product"
+	prodcache "github.com/gegaryfa/tavern/domain/product/cache"
 	prodmemory "github.com/gegaryfa/tavern/domain/product/memory"
+	prodmongo "github.com/gegaryfa/tavern/domain/product/mongo"
+	prodsql "github.com/gegaryfa/tavern/domain/product/sql"
+	"github.com/gegaryfa/tavern/domain/tax"
+	"github.com/gegaryfa/tavern/telemetry"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var (
+	// ErrNoRepositoryToCache is returned when a caching configuration is applied before the repository it wraps
+	ErrNoRepositoryToCache = errors.New("no repository configured to cache")
+	// ErrInvalidLegalAge is returned when the legal age is negative
+	ErrInvalidLegalAge = errors.New("the legal age must not be negative")
+	// ErrNoIdempotencyStore is returned when an order is created with an idempotency key but no store is configured
+	ErrNoIdempotencyStore = errors.New("no idempotency store configured")
+	// ErrInvalidTTL is returned when idempotency keys are remembered for a duration that is not positive
+	ErrInvalidTTL = errors.New("the idempotency keys have to be remembered for a positive duration")
+)
+
+// DefaultLegalAge is the age customers need to order age restricted products unless configured otherwise
+const DefaultLegalAge = 18
+
 // OrderConfiguration is an alias for a function that will take in a pointer to an OrderService and modify it
 type OrderConfiguration func(os *OrderService) error
 
 type OrderService struct {
 	Customers customer.Repository
 	Products  product.Repository
+	Orders    domainorder.Repository
+
+	// metrics records orders and revenue, nil records nothing
+	metrics *telemetry.Metrics
+	// tracer creates the spans of the order flow
+	tracer trace.Tracer
+	// logger writes the structured logs of the order flow
+	logger *slog.Logger
+	// now returns the current time, prices effective at that instant are charged
+	now func() time.Time
+	// pricing applies the promotions to the orders, nil applies none
+	pricing *pricing.Engine
+	// tax charges the taxes on the orders, nil charges none
+	tax *tax.Calculator
+	// legalAge is the age customers need to order age restricted products in the jurisdiction of the tavern
+	legalAge int
+	// reserveStock takes the ordered products out of stock, orders fail when a product is out of stock
+	reserveStock bool
+	// stock serialises the stock changes so that concurrent orders can not oversell a product
+	stock sync.Mutex
+	// idempotency remembers the orders created with an idempotency key for idempotencyTTL
+	idempotency    idempotency.Store
+	idempotencyTTL time.Duration
 }
 
 // See how we can take in a variable amount of OrderConfiguration in the factory method? It is a very neat way
 // of allowing dynamic factories and allows the developer to configure the architecture, given that it is implemented.
 // This trick is very good for unit tests, as you can replace certain parts in service with the wanted repository.
 func NewOrderService(cfg ...OrderConfiguration) (*OrderService, error) {
-	// Create the order service
-	os := &OrderService{}
+	// Create the order service, orders are kept in memory and tracing goes to the global provider unless configured otherwise
+	os := &OrderService{
+		Orders:   ordermemory.New(),
+		tracer:   otel.Tracer(telemetry.TracerName),
+		logger:   telemetry.NopLogger(),
+		now:      time.Now,
+		legalAge: DefaultLegalAge,
+	}
 	// Apply all Configurations passed in
 	for _, cfg := range cfg {
 		// Pass the service into the configuration function
@@ -65,6 +128,19 @@ func WithMongoCustomerRepository(connectionString string) OrderConfiguration {
 	}
 }
 
+// WithSQLCustomerRepository applies a database/sql customer repository to the OrderService
+// The driver (e.g. postgres or sqlite) has to be imported by the caller
+func WithSQLCustomerRepository(driverName, dataSourceName string) OrderConfiguration {
+	return func(os *OrderService) error {
+		cr, err := custsql.New(driverName, dataSourceName)
+		if err != nil {
+			return err
+		}
+		os.Customers = cr
+		return nil
+	}
+}
+
 // WithMemoryProductRepository adds a in prodmemory product repo and adds all input Products
 func WithMemoryProductRepository(products []product.Product) OrderConfiguration {
 	return func(os *OrderService) error {
@@ -83,34 +159,414 @@ func WithMemoryProductRepository(products []product.Product) OrderConfiguration
 	}
 }
 
-func (o *OrderService) CreateOrder(customerID uuid.UUID, productIDs []uuid.UUID) (float64, error) {
+// WithMongoProductRepository applies a mongo product repository to the OrderService
+func WithMongoProductRepository(connectionString string) OrderConfiguration {
+	return func(os *OrderService) error {
+		pr, err := prodmongo.New(context.Background(), connectionString)
+		if err != nil {
+			return err
+		}
+		os.Products = pr
+		return nil
+	}
+}
+
+// WithSQLProductRepository applies a database/sql product repository to the OrderService
+// The driver (e.g. postgres or sqlite) has to be imported by the caller
+func WithSQLProductRepository(driverName, dataSourceName string) OrderConfiguration {
+	return func(os *OrderService) error {
+		pr, err := prodsql.New(driverName, dataSourceName)
+		if err != nil {
+			return err
+		}
+		os.Products = pr
+		return nil
+	}
+}
+
+// WithCachedProductRepository wraps the already configured product repository in a read-through cache
+// holding at most size products for ttl. It has to be applied after the product repository configuration.
+func WithCachedProductRepository(size int, ttl time.Duration) OrderConfiguration {
+	return func(os *OrderService) error {
+		if os.Products == nil {
+			return ErrNoRepositoryToCache
+		}
+		os.Products = prodcache.New(os.Products, size, ttl)
+		return nil
+	}
+}
+
+// WithCachedCustomerRepository wraps the already configured customer repository in a read-through cache
+// holding at most size customers for ttl. It has to be applied after the customer repository configuration.
+func WithCachedCustomerRepository(size int, ttl time.Duration) OrderConfiguration {
+	return func(os *OrderService) error {
+		if os.Customers == nil {
+			return ErrNoRepositoryToCache
+		}
+		os.Customers = custcache.New(os.Customers, size, ttl)
+		return nil
+	}
+}
+
+// WithMetrics records orders and revenue on m and wraps the already configured repositories
+// so that their calls are recorded as well. It has to be applied after the repository configurations.
+func WithMetrics(m *telemetry.Metrics) OrderConfiguration {
+	return func(os *OrderService) error {
+		os.metrics = m
+		if os.Customers != nil {
+			os.Customers = telemetry.NewCustomerRepository(os.Customers, m)
+		}
+		if os.Products != nil {
+			os.Products = telemetry.NewProductRepository(os.Products, m)
+		}
+		return nil
+	}
+}
+
+// WithTracerProvider creates the spans of the OrderService from tp instead of the global provider
+func WithTracerProvider(tp trace.TracerProvider) OrderConfiguration {
+	return func(os *OrderService) error {
+		os.tracer = tp.Tracer(telemetry.TracerName)
+		return nil
+	}
+}
+
+// WithOrderRepository stores the placed orders in or
+func WithOrderRepository(or domainorder.Repository) OrderConfiguration {
+	return func(os *OrderService) error {
+		os.Orders = or
+		return nil
+	}
+}
+
+// WithSQLOrderRepository stores the placed orders in a database/sql order repository
+// The driver (e.g. postgres or sqlite) has to be imported by the caller
+func WithSQLOrderRepository(driverName, dataSourceName string) OrderConfiguration {
+	return func(os *OrderService) error {
+		or, err := ordersql.New(driverName, dataSourceName)
+		if err != nil {
+			return err
+		}
+		os.Orders = or
+		return nil
+	}
+}
+
+// WithPricing applies the promotion rules to every order, the rules are evaluated in the given order
+// and a product unit discounted by a rule is not discounted again by a later one. Rules with invalid settings,
+// such as a percentage over 100, are rejected, see pricing.Validate.
+func WithPricing(rules ...pricing.Rule) OrderConfiguration {
+	return func(os *OrderService) error {
+		if err := pricing.Validate(rules...); err != nil {
+			return err
+		}
+		os.pricing = pricing.NewEngine(rules...)
+		return nil
+	}
+}
+
+// WithTax charges the taxes configured by cfgs on every order
+func WithTax(cfgs ...tax.Configuration) OrderConfiguration {
+	return func(os *OrderService) error {
+		c, err := tax.NewCalculator(cfgs...)
+		if err != nil {
+			return err
+		}
+		os.tax = c
+		return nil
+	}
+}
+
+// WithLegalAge sets the age customers need to order age restricted products, it depends on the jurisdiction
+// the tavern is in
+func WithLegalAge(age int) OrderConfiguration {
+	return func(os *OrderService) error {
+		if age < 0 {
+			return ErrInvalidLegalAge
+		}
+		os.legalAge = age
+		return nil
+	}
+}
+
+// WithStockReservation takes the ordered products out of stock when an order is placed and puts them back
+// when it is cancelled, products that are out of stock can no longer be ordered
+func WithStockReservation() OrderConfiguration {
+	return func(os *OrderService) error {
+		os.reserveStock = true
+		return nil
+	}
+}
+
+// WithIdempotencyStore remembers the orders created with an idempotency key in s for ttl, creating an order
+// again with the same key returns the remembered order
+func WithIdempotencyStore(s idempotency.Store, ttl time.Duration) OrderConfiguration {
+	return func(os *OrderService) error {
+		if ttl <= 0 {
+			return ErrInvalidTTL
+		}
+		os.idempotency = s
+		os.idempotencyTTL = ttl
+		return nil
+	}
+}
+
+// WithClock replaces the clock used to decide which prices are effective, it is meant for tests
+func WithClock(now func() time.Time) OrderConfiguration {
+	return func(os *OrderService) error {
+		os.now = now
+		return nil
+	}
+}
+
+// WithLogger writes the logs of the OrderService to logger, by default nothing is logged
+func WithLogger(logger *slog.Logger) OrderConfiguration {
+	return func(os *OrderService) error {
+		os.logger = telemetry.ContextLogger(logger)
+		return nil
+	}
+}
+
+// CreateOption is an alias for a function that sets an optional property of the creation of a single order
+type CreateOption func(r *createRequest)
+
+// createRequest holds the optional properties of the creation of a single order
+type createRequest struct {
+	idempotencyKey string
+	reservedStock  bool
+	// orderID is the identifier given to the order, a new one is generated when it is nil
+	orderID uuid.UUID
+}
+
+// WithIdempotencyKey creates the order only once for the key, retries return the order created first
+func WithIdempotencyKey(key string) CreateOption {
+	return func(r *createRequest) {
+		r.idempotencyKey = key
+	}
+}
+
+// WithOrderID creates the order with the given identifier, such as one the caller already redeemed a voucher for
+func WithOrderID(id uuid.UUID) CreateOption {
+	return func(r *createRequest) {
+		r.orderID = id
+	}
+}
+
+// WithReservedStock creates the order for products the caller already took out of stock with ReserveStock.
+// The order takes the reservation over, cancelling it puts the products back in stock.
+func WithReservedStock() CreateOption {
+	return func(r *createRequest) {
+		r.reservedStock = true
+	}
+}
+
+// CreateOrder places an order for the customer. Every product is charged the price effective at the time of the order,
+// ordering the same product several times results in a single line with a quantity.
+func (o *OrderService) CreateOrder(ctx context.Context, customerID uuid.UUID, productIDs []uuid.UUID, opts ...CreateOption) (domainorder.Order, error) {
+	req := createRequest{}
+	for _, opt := range opts {
+		opt(&req)
+	}
+	if req.idempotencyKey == "" {
+		return o.createOrder(ctx, customerID, productIDs, req)
+	}
+	if o.idempotency == nil {
+		return domainorder.Order{}, ErrNoIdempotencyStore
+	}
+
+	return idempotency.Once(ctx, o.logger, o.idempotency, req.idempotencyKey, idempotency.Fingerprint(customerID, productIDs),
+		o.now(), o.idempotencyTTL, func() (domainorder.Order, uuid.UUID, error) {
+			// Nothing is persisted when creating the order fails
+			placed, err := o.createOrder(ctx, customerID, productIDs, req)
+			return placed, placed.GetID(), err
+		}, o.Orders.Get)
+}
+
+// createOrder places the order, see CreateOrder
+func (o *OrderService) createOrder(ctx context.Context, customerID uuid.UUID, productIDs []uuid.UUID, req createRequest) (placed domainorder.Order, err error) {
+	ctx, span := o.tracer.Start(ctx, "OrderService.CreateOrder", trace.WithAttributes(
+		attribute.String("customer.id", customerID.String()),
+		attribute.Int("order.product_count", len(productIDs)),
+	))
+	defer func(start time.Time) {
+		o.metrics.ObserveOrder(start, placed.Total(), err)
+		if err != nil {
+			o.logger.WarnContext(ctx, "order failed",
+				slog.String("customer_id", customerID.String()),
+				slog.Int("product_count", len(productIDs)),
+				slog.String("error", err.Error()),
+			)
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}(time.Now())
+
+	placed, err = o.buildOrder(ctx, customerID, productIDs)
+	if err != nil {
+		return domainorder.Order{}, err
+	}
+	if req.orderID != uuid.Nil {
+		placed.SetID(req.orderID)
+	}
+	for _, d := range placed.GetDiscounts() {
+		o.logger.DebugContext(ctx, "promotion applied",
+			slog.String("order_id", placed.GetID().String()),
+			slog.String("rule", d.Rule),
+			slog.String("description", d.Description),
+			slog.Float64("amount", d.Amount),
+		)
+	}
+	reserve := o.reserveStock && !req.reservedStock
+	if reserve {
+		if err := o.reserve(ctx, placed.GetLines()); err != nil {
+			return domainorder.Order{}, err
+		}
+	}
+	placed.SetStockReserved(o.reserveStock || req.reservedStock)
+	if err := o.Orders.Add(placed); err != nil {
+		if reserve {
+			o.release(ctx, placed.GetLines())
+		}
+		return domainorder.Order{}, err
+	}
+
+	o.logger.InfoContext(ctx, "order created",
+		slog.String("customer_id", customerID.String()),
+		slog.String("order_id", placed.GetID().String()),
+		slog.Int("product_count", len(productIDs)),
+		slog.Float64("total", placed.Total()),
+	)
+	span.SetAttributes(
+		attribute.String("order.id", placed.GetID().String()),
+		attribute.Float64("order.discount", placed.Discount()),
+		attribute.Float64("order.tax", placed.Tax()),
+		attribute.Float64("order.total", placed.Total()),
+	)
+
+	return placed, nil
+}
+
+// QuoteOrder prices an order exactly like CreateOrder without placing it, such as to check that it fits in a budget
+func (o *OrderService) QuoteOrder(ctx context.Context, customerID uuid.UUID, productIDs []uuid.UUID) (domainorder.Order, error) {
+	ctx, span := o.tracer.Start(ctx, "OrderService.QuoteOrder", trace.WithAttributes(
+		attribute.String("customer.id", customerID.String()),
+		attribute.Int("order.product_count", len(productIDs)),
+	))
+	defer span.End()
+
+	quote, err := o.buildOrder(ctx, customerID, productIDs)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return domainorder.Order{}, err
+	}
+	return quote, nil
+}
+
+// buildOrder creates the order of the customer with the promotions and taxes applied, without storing it
+func (o *OrderService) buildOrder(ctx context.Context, customerID uuid.UUID, productIDs []uuid.UUID) (domainorder.Order, error) {
 	// get the customer
+	_, getSpan := o.tracer.Start(ctx, "customer.Repository.Get")
 	c, err := o.Customers.Get(customerID)
+	getSpan.End()
 	if err != nil {
-		return 0, err
+		return domainorder.Order{}, err
 	}
 
-	//todo
-	var products []product.Product
-	var price float64
+	now := o.now()
+	var lines []domainorder.Line
+	index := map[uuid.UUID]int{}
 	for _, id := range productIDs {
+		if i, ok := index[id]; ok {
+			lines[i].Quantity++
+			continue
+		}
+
+		_, getSpan := o.tracer.Start(ctx, "product.Repository.GetByID", trace.WithAttributes(
+			attribute.String("product.id", id.String()),
+		))
 		p, err := o.Products.GetByID(id)
+		getSpan.End()
 		if err != nil {
-			return 0, err
+			return domainorder.Order{}, err
 		}
-		products = append(products, p)
-		price += p.GetPrice()
+		if p.IsDiscontinued() {
+			return domainorder.Order{}, fmt.Errorf("%s: %w", p.GetItem().Name, product.ErrProductDiscontinued)
+		}
+		if p.IsAgeRestricted() {
+			if err := c.CheckLegalAge(o.legalAge, now); err != nil {
+				return domainorder.Order{}, fmt.Errorf("%s: %w", p.GetItem().Name, err)
+			}
+		}
+
+		index[id] = len(lines)
+		lines = append(lines, domainorder.Line{
+			ProductID: id,
+			Name:      p.GetItem().Name,
+			Category:  string(p.GetCategory()),
+			Tags:      tags(p),
+			Quantity:  1,
+			UnitPrice: p.PriceAt(now),
+		})
 	}
 
 	// All Products exist in store, now we can create the order
-	log.Printf("Customer: %s has ordered %d Products", c.GetID(), len(products))
+	placed, err := domainorder.NewOrder(c.GetID(), lines, now)
+	if err != nil {
+		return domainorder.Order{}, err
+	}
+	if o.pricing != nil {
+		for _, d := range o.pricing.Price(lines, now).Discounts {
+			if err := placed.ApplyDiscount(d); err != nil {
+				return domainorder.Order{}, fmt.Errorf("%s: %w", d.Rule, err)
+			}
+		}
+	}
+	if o.tax != nil {
+		o.tax.Apply(&placed)
+	}
+	return placed, nil
+}
 
-	return price, nil
+// tags returns the tags of the product as the line records them
+func tags(p product.Product) []string {
+	var tags []string
+	for _, tag := range p.GetTags() {
+		tags = append(tags, string(tag))
+	}
+	return tags
+}
+
+// ApplyDiscount discounts a placed order, such as when a voucher is redeemed, and charges the taxes again
+// on the discounted amount
+func (o *OrderService) ApplyDiscount(ctx context.Context, orderID uuid.UUID, d domainorder.Discount) (domainorder.Order, error) {
+	_, span := o.tracer.Start(ctx, "OrderService.ApplyDiscount", trace.WithAttributes(
+		attribute.String("order.id", orderID.String()),
+		attribute.Float64("discount.amount", d.Amount),
+	))
+	defer span.End()
+
+	placed, err := domainorder.Change(o.Orders, orderID, func(placed *domainorder.Order) error {
+		if err := placed.ApplyDiscount(d); err != nil {
+			return err
+		}
+		if o.tax != nil {
+			o.tax.Apply(placed)
+		}
+		return nil
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return domainorder.Order{}, err
+	}
+	return placed, nil
 }
 
-// AddCustomer will add a new customer
-func (o OrderService) AddCustomer(name string) (uuid.UUID, error) {
-	c, err := customer.NewCustomer(name)
+// AddCustomer will add a new customer, the configurations set the optional properties such as the date of birth
+func (o *OrderService) AddCustomer(name string, cfgs ...customer.CustomerConfiguration) (uuid.UUID, error) {
+	c, err := customer.NewCustomer(name, cfgs...)
 	if err != nil {
 		return uuid.Nil, err
 	}
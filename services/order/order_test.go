@@ -1,11 +1,23 @@
 package order
 
 import (
+	"context"
+	"errors"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/gegaryfa/tavern/domain/customer"
+	"github.com/gegaryfa/tavern/domain/idempotency"
+	idemmemory "github.com/gegaryfa/tavern/domain/idempotency/memory"
+	domainorder "github.com/gegaryfa/tavern/domain/order"
+	"github.com/gegaryfa/tavern/domain/pricing"
 	"github.com/gegaryfa/tavern/domain/product"
+	prodcache "github.com/gegaryfa/tavern/domain/product/cache"
+	"github.com/gegaryfa/tavern/domain/tax"
+	"github.com/gegaryfa/tavern/telemetry"
 	"github.com/google/uuid"
+	_ "modernc.org/sqlite"
 )
 
 func initProducts(t *testing.T) []product.Product {
@@ -56,10 +68,478 @@ func TestOrder_NewOrderService(t *testing.T) {
 		products[0].GetID(),
 	}
 
-	_, err = os.CreateOrder(customer.GetID(), order)
+	_, err = os.CreateOrder(context.Background(), customer.GetID(), order)
 
 	if err != nil {
 		t.Error(err)
 	}
 
 }
+
+func TestOrder_SQLOrderService(t *testing.T) {
+	products := initProducts(t)
+	dsn := filepath.Join(t.TempDir(), "tavern.db")
+
+	os, err := NewOrderService(
+		WithSQLCustomerRepository("sqlite", dsn),
+		WithSQLProductRepository("sqlite", dsn),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range products {
+		if err := os.Products.Add(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	uid, err := os.AddCustomer("Percy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	placed, err := os.CreateOrder(context.Background(), uid, []uuid.UUID{products[0].GetID(), products[1].GetID()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if placed.Total() != products[0].GetPrice()+products[1].GetPrice() {
+		t.Errorf("Expected price %v, got %v", products[0].GetPrice()+products[1].GetPrice(), placed.Total())
+	}
+}
+
+func TestOrder_WithCachedProductRepository(t *testing.T) {
+	if _, err := NewOrderService(WithCachedProductRepository(10, time.Minute)); err != ErrNoRepositoryToCache {
+		t.Errorf("Expected error %v, got %v", ErrNoRepositoryToCache, err)
+	}
+
+	products := initProducts(t)
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+		WithCachedProductRepository(10, time.Minute),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid, err := os.AddCustomer("Percy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := os.CreateOrder(context.Background(), uid, []uuid.UUID{products[0].GetID()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cached, ok := os.Products.(*prodcache.Repository)
+	if !ok {
+		t.Fatalf("Expected a cached product repository, got %T", os.Products)
+	}
+	if stats := cached.Stats(); stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("Expected 1 hit and 1 miss, got %+v", stats)
+	}
+}
+
+func TestOrder_WithMetrics(t *testing.T) {
+	products := initProducts(t)
+	m := telemetry.NewMetrics()
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+		WithMetrics(m),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid, err := os.AddCustomer("Percy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.CreateOrder(context.Background(), uid, []uuid.UUID{products[0].GetID()}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.CreateOrder(context.Background(), uid, []uuid.UUID{uuid.New()}); err != product.ErrProductNotFound {
+		t.Fatalf("Expected error %v, got %v", product.ErrProductNotFound, err)
+	}
+
+	families, err := m.Registry().Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := map[string]float64{}
+	for _, f := range families {
+		for _, metric := range f.GetMetric() {
+			if metric.GetCounter() != nil {
+				values[f.GetName()] += metric.GetCounter().GetValue()
+			}
+		}
+	}
+	if values["tavern_orders_total"] != 1 {
+		t.Errorf("Expected 1 order, got %v", values["tavern_orders_total"])
+	}
+	if values["tavern_order_revenue_total"] != products[0].GetPrice() {
+		t.Errorf("Expected revenue %v, got %v", products[0].GetPrice(), values["tavern_order_revenue_total"])
+	}
+	if values["tavern_order_errors_total"] != 1 {
+		t.Errorf("Expected 1 failed order, got %v", values["tavern_order_errors_total"])
+	}
+}
+
+func TestOrder_DiscontinuedProduct(t *testing.T) {
+	products := initProducts(t)
+	if err := products[2].Discontinue(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid, err := os.AddCustomer("Percy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = os.CreateOrder(context.Background(), uid, []uuid.UUID{products[0].GetID(), products[2].GetID()})
+	if !errors.Is(err, product.ErrProductDiscontinued) {
+		t.Errorf("Expected error %v, got %v", product.ErrProductDiscontinued, err)
+	}
+}
+
+func TestOrder_EffectivePrice(t *testing.T) {
+	products := initProducts(t)
+	happyHour := time.Date(2022, 11, 1, 17, 0, 0, 0, time.UTC)
+	if err := products[0].SchedulePrice(0.99, happyHour, happyHour.Add(2*time.Hour), happyHour); err != nil {
+		t.Fatal(err)
+	}
+
+	now := happyHour.Add(time.Hour)
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+		WithClock(func() time.Time { return now }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid, err := os.AddCustomer("Percy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	beer := products[0].GetID()
+	placed, err := os.CreateOrder(context.Background(), uid, []uuid.UUID{beer, beer, products[1].GetID()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := placed.GetLines()
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %v", lines)
+	}
+	if lines[0].ProductID != beer || lines[0].Quantity != 2 || lines[0].UnitPrice != 0.99 {
+		t.Errorf("Expected 2 beers at the happy hour price 0.99, got %+v", lines[0])
+	}
+	if placed.Total() != 2*0.99+products[1].GetPrice() {
+		t.Errorf("Expected total %v, got %v", 2*0.99+products[1].GetPrice(), placed.Total())
+	}
+
+	// The stored order keeps the charged price once the happy hour is over
+	now = happyHour.Add(3 * time.Hour)
+	stored, err := os.Orders.Get(placed.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.GetLines()[0].UnitPrice != 0.99 {
+		t.Errorf("Expected the stored order to keep the price 0.99, got %v", stored.GetLines()[0].UnitPrice)
+	}
+}
+
+func TestOrder_WithPricing(t *testing.T) {
+	products := initProducts(t)
+	beer, peanuts := products[0].GetID(), products[1].GetID()
+
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+		WithPricing(pricing.Bundle{Label: "beer and peanuts", ProductIDs: []uuid.UUID{beer, peanuts}, Price: 2.5}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid, err := os.AddCustomer("Percy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	placed, err := os.CreateOrder(context.Background(), uid, []uuid.UUID{beer, peanuts})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(placed.GetDiscounts()) != 1 || placed.GetDiscounts()[0].Rule != "beer and peanuts" {
+		t.Fatalf("Expected the bundle to be applied, got %v", placed.GetDiscounts())
+	}
+	if pricing.Round(placed.Total()) != 2.5 {
+		t.Errorf("Expected total 2.5, got %v", placed.Total())
+	}
+
+	// Rules taking more than everything off are rejected
+	_, err = NewOrderService(WithPricing(pricing.CategoryDiscount{Label: "free drinks", Category: "drinks", Percent: 150}))
+	if !errors.Is(err, pricing.ErrInvalidPercent) {
+		t.Errorf("Expected error %v, got %v", pricing.ErrInvalidPercent, err)
+	}
+}
+
+func TestOrder_WithTax(t *testing.T) {
+	products := initProducts(t)
+
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+		WithTax(tax.WithDefaultRate(10)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid, err := os.AddCustomer("Percy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	placed, err := os.CreateOrder(context.Background(), uid, []uuid.UUID{products[0].GetID()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if placed.Tax() != 0.2 {
+		t.Errorf("Expected tax 0.2, got %v", placed.Tax())
+	}
+
+	// Discounting the order charges the tax on what is left
+	discounted, err := os.ApplyDiscount(context.Background(), placed.GetID(), domainorder.Discount{Rule: "voucher", Amount: 0.99})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if discounted.Tax() != 0.1 || pricing.Round(discounted.Total()) != 1.1 {
+		t.Errorf("Expected tax 0.1 and total 1.1, got %v and %v", discounted.Tax(), discounted.Total())
+	}
+}
+
+func TestOrder_WithTagRate(t *testing.T) {
+	wine, err := product.NewProduct("Wine", "Red Wine", 10, product.WithCategory(product.CategoryDrinks), product.WithTags(product.TagAlcoholic))
+	if err != nil {
+		t.Fatal(err)
+	}
+	juice, err := product.NewProduct("Juice", "Orange Juice", 10, product.WithCategory(product.CategoryDrinks))
+	if err != nil {
+		t.Fatal(err)
+	}
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository([]product.Product{wine, juice}),
+		WithTax(tax.WithRate(string(product.CategoryDrinks), 13), tax.WithTagRate(string(product.TagAlcoholic), 24)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid, err := os.AddCustomer("Percy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The wine is taxed as an alcoholic drink and the juice at the rate of the drinks
+	placed, err := os.CreateOrder(context.Background(), uid, []uuid.UUID{wine.GetID(), juice.GetID()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if placed.Tax() != 3.7 || len(placed.GetTaxes()) != 2 || placed.GetTaxes()[1].Tag != string(product.TagAlcoholic) {
+		t.Errorf("Expected 1.3 on the juice and 2.4 on the wine, got %v", placed.GetTaxes())
+	}
+}
+
+func TestOrder_AgeRestrictedProducts(t *testing.T) {
+	now := time.Date(2022, 11, 1, 18, 0, 0, 0, time.UTC)
+	wine, err := product.NewProduct("Wine", "Red Wine", 4.5, product.WithAgeRestriction())
+	if err != nil {
+		t.Fatal(err)
+	}
+	juice, err := product.NewProduct("Juice", "Orange Juice", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository([]product.Product{wine, juice}),
+		WithLegalAge(21),
+		WithClock(func() time.Time { return now }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	adult, err := os.AddCustomer("Percy", customer.WithDateOfBirth(time.Date(1980, 5, 17, 0, 0, 0, 0, time.UTC)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Of age in most of Europe but not where the legal age is 21
+	teen, err := os.AddCustomer("Anna", customer.WithDateOfBirth(time.Date(2003, 5, 17, 0, 0, 0, 0, time.UTC)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	unverified, err := os.AddCustomer("Bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type testCase struct {
+		test        string
+		customer    uuid.UUID
+		products    []uuid.UUID
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{
+			test:        "Adult",
+			customer:    adult,
+			products:    []uuid.UUID{wine.GetID()},
+			expectedErr: nil,
+		}, {
+			test:        "Underage",
+			customer:    teen,
+			products:    []uuid.UUID{juice.GetID(), wine.GetID()},
+			expectedErr: customer.ErrUnderage,
+		}, {
+			test:        "Unverified",
+			customer:    unverified,
+			products:    []uuid.UUID{wine.GetID()},
+			expectedErr: customer.ErrAgeNotVerified,
+		}, {
+			test:        "Unrestricted product",
+			customer:    unverified,
+			products:    []uuid.UUID{juice.GetID()},
+			expectedErr: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			_, err := os.CreateOrder(context.Background(), tc.customer, tc.products)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestOrder_CancelOrder(t *testing.T) {
+	products := initProducts(t)
+	products[0].SetQuantity(3)
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+		WithStockReservation(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid, err := os.AddCustomer("Percy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	beer := products[0].GetID()
+	stock := func() int {
+		p, err := os.Products.GetByID(beer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p.GetQuantity()
+	}
+
+	placed, err := os.CreateOrder(context.Background(), uid, []uuid.UUID{beer, beer})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := stock(); got != 1 {
+		t.Errorf("Expected 1 beer left in stock, got %d", got)
+	}
+	if _, err := os.CreateOrder(context.Background(), uid, []uuid.UUID{beer, beer}); !errors.Is(err, product.ErrOutOfStock) {
+		t.Errorf("Expected error %v, got %v", product.ErrOutOfStock, err)
+	}
+
+	cancelled, err := os.CancelOrder(context.Background(), placed.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cancelled.GetStatus() != domainorder.StatusCancelled {
+		t.Errorf("Expected status %s, got %s", domainorder.StatusCancelled, cancelled.GetStatus())
+	}
+	if got := stock(); got != 3 {
+		t.Errorf("Expected the 2 beers back in stock, got %d", got)
+	}
+
+	served, err := os.CreateOrder(context.Background(), uid, []uuid.UUID{beer})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.ServeOrder(context.Background(), served.GetID()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.CancelOrder(context.Background(), served.GetID()); err != domainorder.ErrOrderServed {
+		t.Errorf("Expected error %v, got %v", domainorder.ErrOrderServed, err)
+	}
+	if got := stock(); got != 2 {
+		t.Errorf("Expected the served beer to stay out of stock, got %d", got)
+	}
+}
+
+func TestOrder_IdempotentCreateOrder(t *testing.T) {
+	products := initProducts(t)
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+		WithIdempotencyStore(idemmemory.New(), time.Hour),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid, err := os.AddCustomer("Percy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	beer := []uuid.UUID{products[0].GetID()}
+
+	first, err := os.CreateOrder(context.Background(), uid, beer, WithIdempotencyKey("pos-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	retry, err := os.CreateOrder(context.Background(), uid, beer, WithIdempotencyKey("pos-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if retry.GetID() != first.GetID() {
+		t.Errorf("Expected the retry to return order %v, got %v", first.GetID(), retry.GetID())
+	}
+	if _, err := os.CreateOrder(context.Background(), uid, []uuid.UUID{products[1].GetID()}, WithIdempotencyKey("pos-1")); err != idempotency.ErrKeyReused {
+		t.Errorf("Expected error %v, got %v", idempotency.ErrKeyReused, err)
+	}
+	other, err := os.CreateOrder(context.Background(), uid, beer, WithIdempotencyKey("pos-2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.GetID() == first.GetID() {
+		t.Errorf("Expected another key to place another order")
+	}
+
+	withoutStore, err := NewOrderService(WithMemoryCustomerRepository(), WithMemoryProductRepository(products))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := withoutStore.CreateOrder(context.Background(), uid, beer, WithIdempotencyKey("pos-1")); err != ErrNoIdempotencyStore {
+		t.Errorf("Expected error %v, got %v", ErrNoIdempotencyStore, err)
+	}
+}
@@ -0,0 +1,51 @@
+package tavern
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	domainorder "github.com/gegaryfa/tavern/domain/order"
+	"github.com/gegaryfa/tavern/domain/voucher"
+)
+
+// placement tracks what placing an order did so far, so that it can be undone when a later step fails
+type placement struct {
+	order domainorder.Order
+	// voucher was redeemed for the order when redeemed is set, voucherAmount is the transaction recorded for it
+	voucher       voucher.Voucher
+	redeemed      bool
+	voucherAmount float64
+	// pointsSpent were redeemed on the rewards of the order
+	pointsSpent int
+}
+
+// undo compensates what placing the order did before a step failed and returns the failure. The order is
+// cancelled, which puts its stock back. Compensations that fail are logged, they then have to be made by hand.
+func (t *Tavern) undo(ctx context.Context, p placement, cause error) error {
+	customerID := p.order.GetCustomerID()
+	logger := t.logger.With(
+		slog.String("customer_id", customerID.String()),
+		slog.String("order_id", p.order.GetID().String()),
+	)
+
+	if p.voucherAmount > 0 {
+		if err := t.reverseVoucher(p.order, p.voucher, p.voucherAmount); err != nil {
+			logger.ErrorContext(ctx, "voucher transaction not reversed", slog.String("error", err.Error()))
+		}
+	}
+	if p.redeemed {
+		t.releaseVoucher(ctx, p.voucher, p.order.GetID())
+	}
+	if p.pointsSpent > 0 {
+		if err := t.returnPoints(customerID, p.order.GetID(), p.pointsSpent); err != nil {
+			logger.ErrorContext(ctx, "points not returned", slog.String("error", err.Error()))
+		}
+	}
+	if _, err := t.OrderService.CancelOrder(ctx, p.order.GetID()); err != nil && !errors.Is(err, domainorder.ErrOrderCancelled) {
+		logger.ErrorContext(ctx, "order not cancelled", slog.String("error", err.Error()))
+	}
+
+	logger.WarnContext(ctx, "order undone", slog.String("error", cause.Error()))
+	return cause
+}
@@ -0,0 +1,200 @@
+package tavern
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/gegaryfa/tavern"
+	"github.com/gegaryfa/tavern/domain/customer"
+	"github.com/gegaryfa/tavern/domain/loyalty"
+	domainorder "github.com/gegaryfa/tavern/domain/order"
+	"github.com/gegaryfa/tavern/domain/pricing"
+	"github.com/google/uuid"
+)
+
+// checkRewards makes sure the customer holds enough points for the rewards before the order is placed
+// and returns what they cost
+func (t *Tavern) checkRewards(customerID uuid.UUID, rewards []uuid.UUID) (int, error) {
+	if t.Loyalty == nil {
+		return 0, ErrNoLoyaltyProgramme
+	}
+	var cost int
+	for _, id := range rewards {
+		points, err := t.Loyalty.Cost(id)
+		if err != nil {
+			return 0, fmt.Errorf("product %s: %w", id, err)
+		}
+		cost += points
+	}
+	c, err := t.OrderService.Customers.Get(customerID)
+	if err != nil {
+		return 0, err
+	}
+	if c.PointsBalance(t.now()) < cost {
+		return 0, customer.ErrInsufficientPoints
+	}
+	return cost, nil
+}
+
+// redeemRewards spends the points of the customer on the rewards and takes their price off the order. It returns
+// the points spent, also when taking the price off failed, so that they can be given back.
+func (t *Tavern) redeemRewards(ctx context.Context, placed domainorder.Order, rewards []uuid.UUID, cost int) (domainorder.Order, int, error) {
+	prices := make(map[uuid.UUID]domainorder.Line, len(placed.GetLines()))
+	for _, l := range placed.GetLines() {
+		prices[l.ProductID] = l
+	}
+	var amount float64
+	names := make([]string, 0, len(rewards))
+	for _, id := range rewards {
+		amount += prices[id].UnitPrice
+		names = append(names, prices[id].Name)
+	}
+	reference := fmt.Sprintf("rewards for order %s", placed.GetID())
+
+	_, err := customer.Change(t.OrderService.Customers, placed.GetCustomerID(), func(c *customer.Customer) error {
+		return c.RedeemPoints(cost, reference, t.now())
+	})
+	if err != nil {
+		return domainorder.Order{}, 0, err
+	}
+
+	// Promotions may already have taken part of the rewards off, the order can not go below zero
+	amount = pricing.Round(amount)
+	if amount > placed.Net() {
+		amount = pricing.Round(placed.Net())
+	}
+	if amount > 0 {
+		placed, err = t.OrderService.ApplyDiscount(ctx, placed.GetID(), domainorder.Discount{
+			Rule:        "loyalty",
+			Description: fmt.Sprintf("%s for %d points", strings.Join(names, ", "), cost),
+			Amount:      amount,
+			Points:      cost,
+		})
+		if err != nil {
+			return domainorder.Order{}, cost, err
+		}
+	}
+
+	t.logger.InfoContext(ctx, "points redeemed",
+		slog.String("customer_id", placed.GetCustomerID().String()),
+		slog.String("order_id", placed.GetID().String()),
+		slog.Int("points", cost),
+		slog.Float64("amount", amount),
+	)
+	return placed, cost, nil
+}
+
+// returnPoints gives the customer back the points redeemed on an order that was called off
+func (t *Tavern) returnPoints(customerID, orderID uuid.UUID, points int) error {
+	now := t.now()
+	var expiresAt time.Time
+	if t.Loyalty != nil {
+		expiresAt = t.Loyalty.ExpiresAt(now)
+	}
+	reference := fmt.Sprintf("rewards for order %s", orderID)
+	_, err := customer.Change(t.OrderService.Customers, customerID, func(c *customer.Customer) error {
+		// The points may have been returned by a cancellation that failed later on
+		if c.HasPoints(customer.PointsReturned, reference) {
+			return nil
+		}
+		return c.ReturnPoints(points, reference, now, expiresAt)
+	})
+	return err
+}
+
+// earnPoints grants the customer the points earned by paying amount for the lines and returns them. The payment
+// already went through, so failing to record the points is logged instead of failing the order.
+func (t *Tavern) earnPoints(ctx context.Context, customerID uuid.UUID, orderIDs []uuid.UUID, lines []domainorder.Line, amount float64) int {
+	if t.Loyalty == nil {
+		return 0
+	}
+	logger := t.logger.With(slog.String("customer_id", customerID.String()))
+
+	var before loyalty.Tier
+	var points int
+	now := t.now()
+	c, err := customer.Change(t.OrderService.Customers, customerID, func(c *customer.Customer) error {
+		before = t.Loyalty.Tier(c.LifetimePoints())
+		points = t.Loyalty.Earn(lines, amount, before)
+		if points == 0 {
+			return nil
+		}
+		c.ExpirePoints(now)
+		return c.EarnPoints(points, orderReference(orderIDs), now, t.Loyalty.ExpiresAt(now))
+	})
+	if err != nil {
+		logger.ErrorContext(ctx, "points not earned", slog.String("error", err.Error()))
+		return 0
+	}
+	if points == 0 {
+		return 0
+	}
+
+	logger.InfoContext(ctx, "points earned",
+		slog.Int("points", points),
+		slog.Int("balance", c.PointsBalance(now)),
+	)
+	if after := t.Loyalty.Tier(c.LifetimePoints()); after != before {
+		logger.InfoContext(ctx, "customer promoted",
+			slog.String("from", string(before)),
+			slog.String("to", string(after)),
+		)
+	}
+	return points
+}
+
+// reversePoints takes back the points the payers earned on a cancelled order and gives back the points spent on
+// its rewards. A bill paying for several orders earned points on all of them, the order takes back its share.
+// Points already reversed or returned for the order are skipped, so that a cancellation can be resumed.
+func (t *Tavern) reversePoints(ctx context.Context, cancelled domainorder.Order) error {
+	reference := fmt.Sprintf("cancelled order %s", cancelled.GetID())
+	now := t.now()
+	var paid int
+	for _, p := range cancelled.GetPayers() {
+		paid += tavern.Cents(p.Amount)
+	}
+	for _, p := range cancelled.GetPayers() {
+		if p.Points == 0 || paid == 0 {
+			continue
+		}
+		points := int(math.Round(float64(p.Points) * float64(tavern.Cents(cancelled.Total())) / float64(paid)))
+		if points == 0 {
+			continue
+		}
+		_, err := customer.Change(t.OrderService.Customers, p.CustomerID, func(c *customer.Customer) error {
+			if c.HasPoints(customer.PointsReversed, reference) {
+				return nil
+			}
+			return c.ReversePoints(points, reference, now)
+		})
+		if err != nil {
+			return err
+		}
+		t.logger.InfoContext(ctx, "points reversed",
+			slog.String("customer_id", p.CustomerID.String()),
+			slog.String("order_id", cancelled.GetID().String()),
+			slog.Int("points", points),
+		)
+	}
+	for _, d := range cancelled.GetDiscounts() {
+		if d.Points == 0 {
+			continue
+		}
+		if err := t.returnPoints(cancelled.GetCustomerID(), cancelled.GetID(), d.Points); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// orderReference describes the orders points were earned on
+func orderReference(orderIDs []uuid.UUID) string {
+	if len(orderIDs) == 1 {
+		return fmt.Sprintf("order %s", orderIDs[0])
+	}
+	return fmt.Sprintf("%d orders from %s", len(orderIDs), orderIDs[0])
+}
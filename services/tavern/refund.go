@@ -0,0 +1,264 @@
+package tavern
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/gegaryfa/tavern"
+	"github.com/gegaryfa/tavern/domain/customer"
+	domainorder "github.com/gegaryfa/tavern/domain/order"
+	"github.com/gegaryfa/tavern/domain/pricing"
+	"github.com/gegaryfa/tavern/domain/tab"
+	"github.com/gegaryfa/tavern/domain/voucher"
+	"github.com/gegaryfa/tavern/services/billing"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrRefundOnTab is returned when refunding an order on an open tab, it can be cancelled or refunded once the tab
+// is settled
+var ErrRefundOnTab = errors.New("orders on an open tab are refunded once the tab is settled")
+
+// CancelOrder calls off an order that was not served yet, the stations stop preparing it. The order is taken
+// off the open tab it was put on, otherwise what is left of its total is refunded to whoever paid for it. The
+// vouchers redeemed for the order are released and the loyalty points earned or spent on it are given back,
+// the tip and the service charge are not refunded.
+//
+// The order is marked as being cancelled first, so that it can not be served while it is refunded. When it
+// can not be taken off the tab or refunded it is put back as it was, a failure after that leaves it being
+// cancelled and cancelling it again resumes where it stopped.
+func (t *Tavern) CancelOrder(ctx context.Context, orderID uuid.UUID) (cancelled domainorder.Order, err error) {
+	ctx, span := t.tracer.Start(ctx, "Tavern.CancelOrder", trace.WithAttributes(
+		attribute.String("order.id", orderID.String()),
+	))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	placed, previous, err := t.OrderService.StartCancelling(ctx, orderID)
+	if err != nil {
+		return domainorder.Order{}, err
+	}
+	resuming := previous == domainorder.StatusCancelling
+	abort := func(err error) (domainorder.Order, error) {
+		if !resuming {
+			t.abortCancelling(ctx, orderID, previous)
+		}
+		return domainorder.Order{}, err
+	}
+
+	tb, onTab, err := t.openTabOf(placed)
+	if err != nil {
+		return abort(err)
+	}
+	switch {
+	case onTab:
+		tb, err = t.Tabs.RemoveOrder(tb.GetID(), orderID)
+		if err != nil {
+			return abort(err)
+		}
+		t.logger.InfoContext(ctx, "order taken off the tab",
+			slog.String("customer_id", placed.GetCustomerID().String()),
+			slog.String("order_id", orderID.String()),
+			slog.String("tab_id", tb.GetID().String()),
+			slog.Float64("tab_total", tb.Total()),
+		)
+	// A resumed cancellation was taken off its tab or refunded already, unless its first attempt could not be
+	// put back. Only the orders that were paid are refunded then, the tab of the others was not settled.
+	case t.BillingService != nil && tavern.Cents(placed.Refundable()) > 0 && (!resuming || len(placed.GetPayers()) > 0):
+		if _, err := t.refund(ctx, placed, placed.Refundable(), "cancelled"); err != nil {
+			return abort(err)
+		}
+	}
+
+	if err := t.releaseVouchers(ctx, placed); err != nil {
+		return domainorder.Order{}, err
+	}
+	if err := t.reversePoints(ctx, placed); err != nil {
+		return domainorder.Order{}, err
+	}
+	if t.Kitchen != nil {
+		if err := t.Kitchen.CancelOrder(ctx, orderID); err != nil {
+			return domainorder.Order{}, err
+		}
+	}
+	return t.OrderService.CancelOrder(ctx, orderID)
+}
+
+// abortCancelling puts an order that could not be taken off its tab or refunded back as it was. Failing to is
+// logged, cancelling the order again then finishes the cancellation.
+func (t *Tavern) abortCancelling(ctx context.Context, orderID uuid.UUID, previous domainorder.Status) {
+	if _, err := t.OrderService.AbortCancelling(ctx, orderID, previous); err != nil {
+		t.logger.ErrorContext(ctx, "cancellation not aborted",
+			slog.String("order_id", orderID.String()),
+			slog.String("status", string(previous)),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// RefundOrder gives part of what the customer paid for an order back, such as for a spilled drink.
+// The refunds of an order can not exceed its total.
+func (t *Tavern) RefundOrder(ctx context.Context, orderID uuid.UUID, amount float64, reason string) (refunded domainorder.Order, err error) {
+	ctx, span := t.tracer.Start(ctx, "Tavern.RefundOrder", trace.WithAttributes(
+		attribute.String("order.id", orderID.String()),
+		attribute.Float64("refund.amount", amount),
+	))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	if t.BillingService == nil {
+		return domainorder.Order{}, ErrNoBillingService
+	}
+	placed, err := t.OrderService.Orders.Get(orderID)
+	if err != nil {
+		return domainorder.Order{}, err
+	}
+	if _, onTab, err := t.openTabOf(placed); err != nil {
+		return domainorder.Order{}, err
+	} else if onTab {
+		return domainorder.Order{}, ErrRefundOnTab
+	}
+	return t.refund(ctx, placed, amount, reason)
+}
+
+// refund records the refund on the order, which makes sure it does not exceed the total, and pays the payers of
+// the order back in proportion to what they paid. Orders without payers are refunded to their customer. When a
+// payer can not be paid back only what was paid stays recorded, so that the rest can be refunded again.
+func (t *Tavern) refund(ctx context.Context, placed domainorder.Order, amount float64, reason string) (domainorder.Order, error) {
+	amount = pricing.Round(amount)
+	var tax float64
+	if placed.Total() > 0 {
+		tax = pricing.Round(placed.Tax() * amount / placed.Total())
+	}
+	refunded, err := t.OrderService.RefundOrder(ctx, placed.GetID(), amount, reason)
+	if err != nil {
+		return domainorder.Order{}, err
+	}
+	recorded := refunded.GetRefunds()[len(refunded.GetRefunds())-1]
+
+	payers := placed.GetPayers()
+	if len(payers) == 0 {
+		payers = []domainorder.Payer{{CustomerID: placed.GetCustomerID(), Amount: placed.Total()}}
+	}
+	weights := make([]float64, len(payers))
+	for i, p := range payers {
+		weights[i] = p.Amount
+	}
+	amounts := billing.Allocate(tavern.Cents(amount), weights)
+	taxes := billing.Allocate(tavern.Cents(tax), weights)
+	var paid int
+	for i, p := range payers {
+		if amounts[i] == 0 {
+			continue
+		}
+		err := t.BillingService.Refund(ctx, billing.Refund{
+			CustomerID: p.CustomerID,
+			OrderIDs:   []uuid.UUID{placed.GetID()},
+			Amount:     float64(amounts[i]) / 100,
+			Tax:        float64(taxes[i]) / 100,
+			Reason:     reason,
+		})
+		if err != nil {
+			t.revertRefund(ctx, placed.GetID(), recorded, paid)
+			return domainorder.Order{}, fmt.Errorf("payer %s: %w", p.CustomerID, err)
+		}
+		paid += amounts[i]
+	}
+
+	t.logger.InfoContext(ctx, "order refunded",
+		slog.String("customer_id", placed.GetCustomerID().String()),
+		slog.String("order_id", placed.GetID().String()),
+		slog.Float64("amount", amount),
+		slog.Int("payer_count", len(payers)),
+		slog.String("reason", reason),
+	)
+	return refunded, nil
+}
+
+// revertRefund replaces a refund recorded on the order with the cents that were actually paid back. Failing to
+// is logged, the refunds of the order then have to be corrected by hand.
+func (t *Tavern) revertRefund(ctx context.Context, orderID uuid.UUID, recorded domainorder.Refund, paid int) {
+	_, err := t.OrderService.RevertRefund(ctx, orderID, recorded)
+	if err == nil && paid > 0 {
+		_, err = t.OrderService.RefundOrder(ctx, orderID, float64(paid)/100, recorded.Reason)
+	}
+	if err != nil {
+		t.logger.ErrorContext(ctx, "refund not reverted",
+			slog.String("order_id", orderID.String()),
+			slog.Float64("amount", recorded.Amount),
+			slog.Float64("paid", float64(paid)/100),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// openTabOf returns the open tab the order was put on, if any
+func (t *Tavern) openTabOf(o domainorder.Order) (tab.Tab, bool, error) {
+	if t.Tabs == nil {
+		return tab.Tab{}, false, nil
+	}
+	tb, err := t.Tabs.GetOpen(o.GetCustomerID())
+	if errors.Is(err, tab.ErrTabNotFound) {
+		return tab.Tab{}, false, nil
+	}
+	if err != nil {
+		return tab.Tab{}, false, err
+	}
+	return tb, tb.Contains(o.GetID()), nil
+}
+
+// releaseVouchers gives back the redemptions of the vouchers of a cancelled order and reverses their
+// transactions on the customer, what was given back already is skipped
+func (t *Tavern) releaseVouchers(ctx context.Context, cancelled domainorder.Order) error {
+	if t.Vouchers == nil {
+		return nil
+	}
+	for _, d := range cancelled.GetDiscounts() {
+		if d.Voucher == "" {
+			continue
+		}
+		v, err := t.Vouchers.Get(d.Voucher)
+		if err != nil {
+			return err
+		}
+		// The redemption was released already when a cancellation is resumed
+		if err := t.Vouchers.Release(v.GetCode(), cancelled.GetID()); err != nil && !errors.Is(err, voucher.ErrNotRedeemed) {
+			return err
+		}
+		if err := t.reverseVoucher(cancelled, v, d.Amount); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reverseVoucher records a transaction of the customer giving back the value of the voucher taken off the order
+func (t *Tavern) reverseVoucher(cancelled domainorder.Order, v voucher.Voucher, amount float64) error {
+	reference := fmt.Sprintf("voucher %s for cancelled order %s", v.GetCode(), cancelled.GetID())
+	_, err := customer.Change(t.OrderService.Customers, cancelled.GetCustomerID(), func(c *customer.Customer) error {
+		for _, tr := range c.GetTransactions() {
+			if tr.GetKind() == tavern.TransactionVoucher && tr.GetReference() == reference {
+				return nil
+			}
+		}
+		c.AddTransaction(tavern.NewTransaction(tavern.Cents(amount), c.GetID(), v.GetID(), t.now()).
+			WithReference(reference).
+			WithKind(tavern.TransactionVoucher))
+		return nil
+	})
+	return err
+}
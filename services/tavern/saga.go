@@ -0,0 +1,310 @@
+package tavern
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	domainorder "github.com/gegaryfa/tavern/domain/order"
+	"github.com/gegaryfa/tavern/domain/saga"
+	"github.com/gegaryfa/tavern/services/billing"
+	"github.com/gegaryfa/tavern/services/order"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrNoSagaStore is returned when resuming the ordering sagas but the Tavern has no saga store
+	ErrNoSagaStore = errors.New("no saga store configured")
+	// ErrNotSagaOrder is returned when an order redeeming vouchers or rewards, put on a tab or split is placed
+	// by a Tavern ordering through the saga
+	ErrNotSagaOrder = errors.New("the order can not be placed through the ordering saga")
+)
+
+// orderingSaga is the saga placing and billing an order, see WithSagaStore
+const orderingSaga = "ordering"
+
+// The keys of the data shared by the steps of the ordering saga
+const (
+	sagaCustomer   = "customer_id"
+	sagaProducts   = "product_ids"
+	sagaTip        = "tip"
+	sagaTipPercent = "tip_percent"
+	sagaStaff      = "staff_id"
+	sagaPartySize  = "party_size"
+	sagaReserved   = "reserved"
+	sagaOrder      = "order_id"
+	sagaCharged    = "charged"
+	sagaConfirmed  = "confirmed"
+)
+
+// WithSagaStore places and bills the orders through the ordering saga, keeping its progress in s. The saga
+// reserves the stock when the order service takes orders out of stock, creates the order, charges the customer
+// and confirms the order by sending it to the stations. When a step fails the steps done so far are compensated:
+// the customer is refunded, the order cancelled and the stock released. Orders redeeming vouchers or rewards,
+// put on a tab or split are not supported by the saga and fail with ErrNotSagaOrder.
+func WithSagaStore(s saga.Store) TavernConfiguration {
+	return func(t *Tavern) error {
+		c, err := saga.NewCoordinator(
+			saga.WithStore(s),
+			saga.WithSaga(t.orderingSaga()),
+			saga.WithClock(func() time.Time { return t.now() }),
+		)
+		if err != nil {
+			return err
+		}
+		t.sagas = c
+		return nil
+	}
+}
+
+// ResumeOrders carries on with the orders interrupted by a crash, they are either charged or compensated.
+// It is meant to be called once when the tavern starts, before taking orders.
+func (t *Tavern) ResumeOrders(ctx context.Context) error {
+	if t.sagas == nil {
+		return ErrNoSagaStore
+	}
+	return t.sagas.Resume(ctx)
+}
+
+// sagaSupported tells whether the order can be placed through the ordering saga
+func (r orderRequest) sagaSupported() bool {
+	return r.voucherCode == "" && r.tabID == uuid.Nil && r.split == nil && len(r.rewards) == 0
+}
+
+// orderSaga places and bills the order through the ordering saga, see WithSagaStore
+func (t *Tavern) orderSaga(ctx context.Context, customer uuid.UUID, products []uuid.UUID, req orderRequest) (domainorder.Order, error) {
+	ids := make([]string, len(products))
+	for i, id := range products {
+		ids[i] = id.String()
+	}
+	state, err := t.sagas.Start(ctx, orderingSaga, saga.Data{
+		sagaCustomer:   customer.String(),
+		sagaProducts:   strings.Join(ids, ","),
+		sagaTip:        strconv.FormatFloat(req.tip, 'f', -1, 64),
+		sagaTipPercent: strconv.FormatFloat(req.tipPercent, 'f', -1, 64),
+		sagaStaff:      req.staffID.String(),
+		sagaPartySize:  strconv.Itoa(req.partySize),
+	})
+	if err != nil {
+		// The order created before the saga failed is returned with the failure, see place
+		if orderID, perr := uuid.Parse(state.Data[sagaOrder]); perr == nil {
+			placed := domainorder.Order{}
+			placed.SetID(orderID)
+			return placed, err
+		}
+		return domainorder.Order{}, err
+	}
+	orderID, err := uuid.Parse(state.Data[sagaOrder])
+	if err != nil {
+		return domainorder.Order{}, err
+	}
+	return t.OrderService.Orders.Get(orderID)
+}
+
+// orderingSaga defines the steps placing and billing an order. Every step records what it did in the data so
+// that it is not done twice when the saga is resumed, except for a crash right after the step.
+func (t *Tavern) orderingSaga() saga.Definition {
+	return saga.Definition{
+		Name: orderingSaga,
+		Steps: []saga.Step{
+			{
+				Name: "reserve stock",
+				Action: func(ctx context.Context, data saga.Data) error {
+					if !t.OrderService.ReservesStock() || data[sagaReserved] != "" {
+						return nil
+					}
+					products, err := sagaProductIDs(data)
+					if err != nil {
+						return err
+					}
+					if err := t.OrderService.ReserveStock(ctx, products); err != nil {
+						return err
+					}
+					data[sagaReserved] = "true"
+					return nil
+				},
+				// Once the order is created it holds the stock, cancelling it puts the stock back
+				Compensate: func(ctx context.Context, data saga.Data) error {
+					if data[sagaReserved] == "" || data[sagaOrder] != "" {
+						return nil
+					}
+					products, err := sagaProductIDs(data)
+					if err != nil {
+						return err
+					}
+					t.OrderService.ReleaseStock(ctx, products)
+					delete(data, sagaReserved)
+					return nil
+				},
+			},
+			{
+				Name: "create order",
+				Action: func(ctx context.Context, data saga.Data) error {
+					if data[sagaOrder] != "" {
+						return nil
+					}
+					customer, products, err := sagaCustomerAndProducts(data)
+					if err != nil {
+						return err
+					}
+					var opts []order.CreateOption
+					if data[sagaReserved] != "" {
+						opts = append(opts, order.WithReservedStock())
+					}
+					placed, err := t.OrderService.CreateOrder(ctx, customer, products, opts...)
+					if err != nil {
+						return err
+					}
+					data[sagaOrder] = placed.GetID().String()
+					return nil
+				},
+				Compensate: func(ctx context.Context, data saga.Data) error {
+					orderID, err := uuid.Parse(data[sagaOrder])
+					if err != nil {
+						return err
+					}
+					_, err = t.OrderService.CancelOrder(ctx, orderID)
+					if errors.Is(err, domainorder.ErrOrderCancelled) {
+						return nil
+					}
+					return err
+				},
+			},
+			{
+				Name: "charge",
+				Action: func(ctx context.Context, data saga.Data) error {
+					if t.BillingService == nil || data[sagaCharged] != "" {
+						return nil
+					}
+					placed, bill, err := t.sagaBill(data)
+					if err != nil {
+						return err
+					}
+					t.logger.InfoContext(ctx, "bill the customer",
+						slog.String("customer_id", bill.CustomerID.String()),
+						slog.String("order_id", placed.GetID().String()),
+						slog.Float64("total", placed.Total()),
+						slog.Float64("service_charge", bill.ServiceCharge),
+						slog.Float64("tip", bill.Tip),
+					)
+					if err := t.charge(ctx, bill, placed.GetLines(), nil); err != nil {
+						return err
+					}
+					data[sagaCharged] = "true"
+					return nil
+				},
+				// The customer is refunded when the order can not be confirmed
+				Compensate: func(ctx context.Context, data saga.Data) error {
+					if data[sagaCharged] == "" {
+						return nil
+					}
+					_, bill, err := t.sagaBill(data)
+					if err != nil {
+						return err
+					}
+					err = t.BillingService.Refund(ctx, billing.Refund{
+						CustomerID:    bill.CustomerID,
+						OrderIDs:      bill.OrderIDs,
+						Amount:        bill.Amount,
+						Tax:           bill.Tax,
+						ServiceCharge: bill.ServiceCharge,
+						Tip:           bill.Tip,
+						Reason:        "order compensated",
+					})
+					if err != nil {
+						return err
+					}
+					delete(data, sagaCharged)
+					return nil
+				},
+			},
+			{
+				Name: "confirm",
+				// The paid order is sent to the stations, it is placed even when it can not be sent, see Tavern.Order
+				Action: func(ctx context.Context, data saga.Data) error {
+					if data[sagaConfirmed] != "" {
+						return nil
+					}
+					orderID, err := uuid.Parse(data[sagaOrder])
+					if err != nil {
+						return err
+					}
+					placed, err := t.OrderService.Orders.Get(orderID)
+					if err != nil {
+						return err
+					}
+					t.route(ctx, placed)
+					data[sagaConfirmed] = "true"
+					t.logger.InfoContext(ctx, "order confirmed",
+						slog.String("customer_id", placed.GetCustomerID().String()),
+						slog.String("order_id", orderID.String()),
+					)
+					return nil
+				},
+			},
+		},
+	}
+}
+
+// sagaBill rebuilds the bill of the order created by the saga
+func (t *Tavern) sagaBill(data saga.Data) (domainorder.Order, billing.Bill, error) {
+	orderID, err := uuid.Parse(data[sagaOrder])
+	if err != nil {
+		return domainorder.Order{}, billing.Bill{}, err
+	}
+	placed, err := t.OrderService.Orders.Get(orderID)
+	if err != nil {
+		return domainorder.Order{}, billing.Bill{}, err
+	}
+	req, err := sagaRequest(data)
+	if err != nil {
+		return domainorder.Order{}, billing.Bill{}, err
+	}
+	bill := t.bill(placed.GetCustomerID(), []uuid.UUID{orderID}, placed.Total(), placed.Tax(), req)
+	return placed, bill, nil
+}
+
+func sagaCustomerAndProducts(data saga.Data) (uuid.UUID, []uuid.UUID, error) {
+	customer, err := uuid.Parse(data[sagaCustomer])
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+	products, err := sagaProductIDs(data)
+	return customer, products, err
+}
+
+func sagaProductIDs(data saga.Data) ([]uuid.UUID, error) {
+	var products []uuid.UUID
+	for _, s := range strings.Split(data[sagaProducts], ",") {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, id)
+	}
+	return products, nil
+}
+
+// sagaRequest reads back the properties of the order that make up its bill
+func sagaRequest(data saga.Data) (orderRequest, error) {
+	var (
+		req orderRequest
+		err error
+	)
+	if req.tip, err = strconv.ParseFloat(data[sagaTip], 64); err != nil {
+		return orderRequest{}, err
+	}
+	if req.tipPercent, err = strconv.ParseFloat(data[sagaTipPercent], 64); err != nil {
+		return orderRequest{}, err
+	}
+	if req.staffID, err = uuid.Parse(data[sagaStaff]); err != nil {
+		return orderRequest{}, err
+	}
+	if req.partySize, err = strconv.Atoi(data[sagaPartySize]); err != nil {
+		return orderRequest{}, err
+	}
+	return req, nil
+}
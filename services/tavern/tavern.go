@@ -1,10 +1,49 @@
 package tavern
 
 import (
-	"log"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
 
+	"github.com/gegaryfa/tavern"
+	"github.com/gegaryfa/tavern/domain/customer"
+	"github.com/gegaryfa/tavern/domain/idempotency"
+	"github.com/gegaryfa/tavern/domain/loyalty"
+	domainorder "github.com/gegaryfa/tavern/domain/order"
+	"github.com/gegaryfa/tavern/domain/pricing"
+	"github.com/gegaryfa/tavern/domain/saga"
+	"github.com/gegaryfa/tavern/domain/tab"
+	"github.com/gegaryfa/tavern/domain/voucher"
+	"github.com/gegaryfa/tavern/services/billing"
+	"github.com/gegaryfa/tavern/services/kitchen"
 	"github.com/gegaryfa/tavern/services/order"
+	"github.com/gegaryfa/tavern/telemetry"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	// ErrNoVoucherRepository is returned when an order redeems a voucher but the Tavern has no voucher repository
+	ErrNoVoucherRepository = errors.New("no voucher repository configured")
+	// ErrNoBillingService is returned when an order holds a tip but the Tavern has no billing service
+	ErrNoBillingService = errors.New("no billing service configured")
+	// ErrInvalidTip is returned when a tip is negative
+	ErrInvalidTip = errors.New("a tip must not be negative")
+	// ErrNoTabRepository is returned when opening or ordering on a tab but the Tavern has no tab repository
+	ErrNoTabRepository = errors.New("no tab repository configured")
+	// ErrTipOnTab is returned when an order put on a tab holds a tip, tips are given when the tab is settled
+	ErrTipOnTab = errors.New("tips are given when settling the tab")
+	// ErrSplitOnTab is returned when an order put on a tab is split, tabs are split when they are settled
+	ErrSplitOnTab = errors.New("tabs are split when settling them")
+	// ErrInvalidServiceCharge is returned when a service charge has a negative percentage or no party size
+	ErrInvalidServiceCharge = errors.New("a service charge needs a positive party size and percentage")
+	// ErrNoLoyaltyProgramme is returned when an order redeems points but the Tavern has no loyalty programme
+	ErrNoLoyaltyProgramme = errors.New("no loyalty programme configured")
 )
 
 // TavernConfiguration is an alias that takes a pointer and modifies the Tavern
@@ -13,15 +52,42 @@ type TavernConfiguration func(os *Tavern) error
 type Tavern struct {
 	// orderservice is used to handle orders
 	OrderService *order.OrderService
-	// BillingService is used to handle billing
-	// This is up to you to implement
-	BillingService interface{}
+	// BillingService is used to handle billing, orders are not billed when it is nil
+	BillingService billing.Service
+	// Vouchers holds the discount codes customers can redeem when ordering
+	Vouchers voucher.Repository
+	// Tabs holds the running bills of the customers
+	Tabs tab.Repository
+	// Loyalty decides the points customers earn on paid orders and the rewards they redeem them for,
+	// no points are earned when it is nil
+	Loyalty *loyalty.Programme
+	// Kitchen queues the placed orders at the stations preparing them, nothing is queued when it is nil
+	Kitchen *kitchen.Kitchen
+
+	// tracer creates the spans of the tavern
+	tracer trace.Tracer
+	// logger writes the structured logs of the tavern
+	logger *slog.Logger
+	// now returns the current time, vouchers are redeemed and tabs opened and closed at that instant
+	now func() time.Time
+	// serviceCharge is the percentage added to the bill of parties of at least serviceChargePartySize guests
+	serviceCharge          float64
+	serviceChargePartySize int
+	// idempotency remembers the orders placed with an idempotency key for idempotencyTTL
+	idempotency    idempotency.Store
+	idempotencyTTL time.Duration
+	// sagas runs the ordering saga, orders are placed without it when it is nil
+	sagas *saga.Coordinator
 }
 
 // NewTavern takes a variable amount of TavernConfigurations and builds a Tavern
 func NewTavern(cfgs ...TavernConfiguration) (*Tavern, error) {
-	// Create the Tavern
-	t := &Tavern{}
+	// Create the Tavern, tracing goes to the global provider unless configured otherwise
+	t := &Tavern{
+		tracer: otel.Tracer(telemetry.TracerName),
+		logger: telemetry.NopLogger(),
+		now:    time.Now,
+	}
 	// Apply all Configurations passed in
 	for _, cfg := range cfgs {
 		// Pass the service into the configuration function
@@ -42,15 +108,638 @@ func WithOrderService(os *order.OrderService) TavernConfiguration {
 	}
 }
 
+// WithTracerProvider creates the spans of the Tavern from tp instead of the global provider
+func WithTracerProvider(tp trace.TracerProvider) TavernConfiguration {
+	return func(t *Tavern) error {
+		t.tracer = tp.Tracer(telemetry.TracerName)
+		return nil
+	}
+}
+
+// WithLogger writes the logs of the Tavern to logger, by default nothing is logged
+func WithLogger(logger *slog.Logger) TavernConfiguration {
+	return func(t *Tavern) error {
+		t.logger = telemetry.ContextLogger(logger)
+		return nil
+	}
+}
+
+// WithBillingService bills every order through bs
+func WithBillingService(bs billing.Service) TavernConfiguration {
+	return func(t *Tavern) error {
+		t.BillingService = bs
+		return nil
+	}
+}
+
+// WithServiceCharge adds percent of the order total to the bill of parties of at least partySize guests
+func WithServiceCharge(partySize int, percent float64) TavernConfiguration {
+	return func(t *Tavern) error {
+		if partySize <= 0 || percent <= 0 {
+			return ErrInvalidServiceCharge
+		}
+		t.serviceChargePartySize = partySize
+		t.serviceCharge = percent
+		return nil
+	}
+}
+
+// WithVoucherRepository lets customers redeem the vouchers of vr when ordering
+func WithVoucherRepository(vr voucher.Repository) TavernConfiguration {
+	return func(t *Tavern) error {
+		t.Vouchers = vr
+		return nil
+	}
+}
+
+// WithTabRepository lets customers run tabs stored in tr
+func WithTabRepository(tr tab.Repository) TavernConfiguration {
+	return func(t *Tavern) error {
+		t.Tabs = tr
+		return nil
+	}
+}
+
+// WithLoyaltyProgramme lets customers earn points on paid orders and redeem them for the rewards of p
+func WithLoyaltyProgramme(p *loyalty.Programme) TavernConfiguration {
+	return func(t *Tavern) error {
+		t.Loyalty = p
+		return nil
+	}
+}
+
+// WithKitchen queues the placed orders at the stations of k
+func WithKitchen(k *kitchen.Kitchen) TavernConfiguration {
+	return func(t *Tavern) error {
+		t.Kitchen = k
+		return nil
+	}
+}
+
+// WithIdempotencyStore remembers the orders placed with an idempotency key in s for ttl
+func WithIdempotencyStore(s idempotency.Store, ttl time.Duration) TavernConfiguration {
+	return func(t *Tavern) error {
+		if ttl <= 0 {
+			return order.ErrInvalidTTL
+		}
+		t.idempotency = s
+		t.idempotencyTTL = ttl
+		return nil
+	}
+}
+
+// WithClock replaces the clock used to redeem vouchers and open and close tabs, it is meant for tests
+func WithClock(now func() time.Time) TavernConfiguration {
+	return func(t *Tavern) error {
+		t.now = now
+		return nil
+	}
+}
+
+// OrderOption is an alias for a function that sets an optional property of a single order
+type OrderOption func(r *orderRequest)
+
+// orderRequest holds the optional properties of a single order
+type orderRequest struct {
+	voucherCode string
+	// tip is a fixed amount, tipPercent a percentage of the order total, both are added up
+	tip        float64
+	tipPercent float64
+	staffID    uuid.UUID
+	partySize  int
+	// tabID puts the order on the tab instead of billing it
+	tabID uuid.UUID
+	// split shares the bill between several customers instead of billing the one who ordered
+	split billing.Split
+	// rewards are ordered on top of the products and paid for with loyalty points
+	rewards []uuid.UUID
+	// idempotencyKey places and bills the order only once, retries with the same key return the order placed first
+	idempotencyKey string
+}
+
+// WithVoucherCode redeems the voucher with the code for the order
+func WithVoucherCode(code string) OrderOption {
+	return func(r *orderRequest) {
+		r.voucherCode = code
+	}
+}
+
+// WithTip adds a fixed tip to the bill
+func WithTip(amount float64) OrderOption {
+	return func(r *orderRequest) {
+		r.tip = amount
+	}
+}
+
+// WithTipPercent adds a tip of percent of the order total to the bill
+func WithTipPercent(percent float64) OrderOption {
+	return func(r *orderRequest) {
+		r.tipPercent = percent
+	}
+}
+
+// WithStaff gives the tip to a member of staff instead of the tip pool
+func WithStaff(id uuid.UUID) OrderOption {
+	return func(r *orderRequest) {
+		r.staffID = id
+	}
+}
+
+// WithPartySize tells how many guests the order is for, large parties pay the service charge
+func WithPartySize(n int) OrderOption {
+	return func(r *orderRequest) {
+		r.partySize = n
+	}
+}
+
+// WithSplit shares the bill between several customers, every one of them is billed their share together with
+// the same share of the tip and the service charge
+func WithSplit(split billing.Split) OrderOption {
+	return func(r *orderRequest) {
+		r.split = split
+	}
+}
+
+// WithTab puts the order on the tab of the customer instead of billing it right away
+func WithTab(id uuid.UUID) OrderOption {
+	return func(r *orderRequest) {
+		r.tabID = id
+	}
+}
+
+// WithRewards orders the products on top of the others and pays for them with the loyalty points of the customer
+func WithRewards(productIDs ...uuid.UUID) OrderOption {
+	return func(r *orderRequest) {
+		r.rewards = append(r.rewards, productIDs...)
+	}
+}
+
+// WithIdempotencyKey places and bills the order only once for the key, such as when the point of sale retries
+// after a timeout. Retries return the order placed first.
+func WithIdempotencyKey(key string) OrderOption {
+	return func(r *orderRequest) {
+		r.idempotencyKey = key
+	}
+}
+
 // Order performs an order for a customer
-func (t *Tavern) Order(customer uuid.UUID, products []uuid.UUID) error {
-	price, err := t.OrderService.CreateOrder(customer, products)
+func (t *Tavern) Order(ctx context.Context, customer uuid.UUID, products []uuid.UUID, opts ...OrderOption) (placed domainorder.Order, err error) {
+	ctx, span := t.tracer.Start(ctx, "Tavern.Order", trace.WithAttributes(
+		attribute.String("customer.id", customer.String()),
+	))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	req := orderRequest{}
+	for _, opt := range opts {
+		opt(&req)
+	}
+	if req.idempotencyKey != "" {
+		return t.orderOnce(ctx, customer, products, req)
+	}
+	if placed, err = t.order(ctx, customer, products, req); err != nil {
+		return domainorder.Order{}, err
+	}
+	return placed, nil
+}
+
+// order places the order and sends it to the stations preparing it, see Order. The ordering saga sends the
+// orders itself, see WithSagaStore.
+func (t *Tavern) order(ctx context.Context, customer uuid.UUID, products []uuid.UUID, req orderRequest) (domainorder.Order, error) {
+	placed, err := t.place(ctx, customer, products, req)
+	if err != nil || t.sagas != nil {
+		return placed, err
+	}
+	t.route(ctx, placed)
+	return placed, nil
+}
+
+// route sends the order to the stations preparing it. The order is placed even when it can not be sent, the
+// failure is logged.
+func (t *Tavern) route(ctx context.Context, placed domainorder.Order) {
+	if t.Kitchen == nil {
+		return
+	}
+	if _, err := t.Kitchen.Route(ctx, placed); err != nil {
+		t.logger.ErrorContext(ctx, "order not sent to the stations",
+			slog.String("order_id", placed.GetID().String()),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// place places the order and bills it or puts it on the tab. When a step fails once the order was placed, the
+// undone order is returned with the failure.
+func (t *Tavern) place(ctx context.Context, customer uuid.UUID, products []uuid.UUID, req orderRequest) (placed domainorder.Order, err error) {
+	if req.tip < 0 || req.tipPercent < 0 {
+		return domainorder.Order{}, ErrInvalidTip
+	}
+	if (req.tip > 0 || req.tipPercent > 0 || req.split != nil) && t.BillingService == nil {
+		return domainorder.Order{}, ErrNoBillingService
+	}
+	if t.sagas != nil {
+		if !req.sagaSupported() {
+			return domainorder.Order{}, ErrNotSagaOrder
+		}
+		return t.orderSaga(ctx, customer, products, req)
+	}
+
+	ordered := products
+	var cost int
+	if len(req.rewards) > 0 {
+		cost, err = t.checkRewards(customer, req.rewards)
+		if err != nil {
+			return domainorder.Order{}, err
+		}
+		ordered = append(append([]uuid.UUID{}, products...), req.rewards...)
+	}
+
+	if req.tabID != uuid.Nil {
+		if err := t.checkTab(ctx, customer, ordered, req); err != nil {
+			return domainorder.Order{}, err
+		}
+	}
+
+	// The voucher is redeemed before the order is placed so that concurrent orders can not exceed its limits,
+	// for the identifier the order is then created with
+	orderID := uuid.New()
+	var v voucher.Voucher
+	if req.voucherCode != "" {
+		if t.Vouchers == nil {
+			return domainorder.Order{}, ErrNoVoucherRepository
+		}
+		v, err = t.Vouchers.Redeem(req.voucherCode, customer, orderID, t.now())
+		if err != nil {
+			return domainorder.Order{}, fmt.Errorf("voucher %s: %w", voucher.NormalizeCode(req.voucherCode), err)
+		}
+	}
+
+	placed, err = t.OrderService.CreateOrder(ctx, customer, ordered, order.WithOrderID(orderID))
+	if err != nil {
+		if req.voucherCode != "" {
+			t.releaseVoucher(ctx, v, orderID)
+		}
+		return domainorder.Order{}, err
+	}
+	// Every step from here on is undone when a later one fails
+	p := placement{order: placed, voucher: v, redeemed: req.voucherCode != ""}
+	// Rewards are taken off before the voucher so that it only discounts what is paid for
+	if len(req.rewards) > 0 {
+		placed, p.pointsSpent, err = t.redeemRewards(ctx, placed, req.rewards, cost)
+		if err != nil {
+			return p.order, t.undo(ctx, p, err)
+		}
+		p.order = placed
+	}
+	if req.voucherCode != "" {
+		placed, p.voucherAmount, err = t.applyVoucher(ctx, placed, v)
+		if err != nil {
+			return p.order, t.undo(ctx, p, err)
+		}
+		p.order = placed
+	}
+
+	if req.tabID != uuid.Nil {
+		// The order was checked to fit in the tab, it can only fail to when orders are put on it concurrently
+		tb, err := t.Tabs.AddOrder(req.tabID, placed)
+		if err != nil {
+			return p.order, t.undo(ctx, p, err)
+		}
+		t.logger.InfoContext(ctx, "order put on the tab",
+			slog.String("customer_id", customer.String()),
+			slog.String("order_id", placed.GetID().String()),
+			slog.String("tab_id", tb.GetID().String()),
+			slog.Float64("total", placed.Total()),
+			slog.Float64("tab_total", tb.Total()),
+		)
+		return placed, nil
+	}
+
+	bill := t.bill(customer, []uuid.UUID{placed.GetID()}, placed.Total(), placed.Tax(), req)
+	t.logger.InfoContext(ctx, "bill the customer",
+		slog.String("customer_id", customer.String()),
+		slog.String("order_id", placed.GetID().String()),
+		slog.Float64("total", placed.Total()),
+		slog.Float64("service_charge", bill.ServiceCharge),
+		slog.Float64("tip", bill.Tip),
+	)
+	if t.BillingService == nil {
+		return placed, nil
+	}
+	if err := t.charge(ctx, bill, placed.GetLines(), req.split); err != nil {
+		return p.order, t.undo(ctx, p, err)
+	}
+	return placed, nil
+}
+
+// orderOnce places the order unless it was already placed with the same idempotency key. An order that failed
+// once placed keeps the key, retries get idempotency.ErrRequestFailed instead of placing and billing it again.
+func (t *Tavern) orderOnce(ctx context.Context, customer uuid.UUID, products []uuid.UUID, req orderRequest) (domainorder.Order, error) {
+	if t.idempotency == nil {
+		return domainorder.Order{}, order.ErrNoIdempotencyStore
+	}
+	fingerprint := idempotency.Fingerprint(customer, products, req.voucherCode, req.tip, req.tipPercent, req.staffID,
+		req.partySize, req.tabID, req.split, req.rewards)
+	return idempotency.Once(ctx, t.logger, t.idempotency, req.idempotencyKey, fingerprint, t.now(), t.idempotencyTTL,
+		func() (domainorder.Order, uuid.UUID, error) {
+			placed, err := t.order(ctx, customer, products, req)
+			return placed, placed.GetID(), err
+		}, t.OrderService.Orders.Get)
+}
+
+// charge bills the customer, or every payer of the split their share of the bill. Every payer earns
+// loyalty points on what they paid for the lines, the payers are recorded on the orders of the bill.
+func (t *Tavern) charge(ctx context.Context, bill billing.Bill, lines []domainorder.Line, split billing.Split) error {
+	if split == nil {
+		if err := t.BillingService.Bill(ctx, bill); err != nil {
+			return err
+		}
+		points := t.earnPoints(ctx, bill.CustomerID, bill.OrderIDs, lines, bill.Amount)
+		t.recordPayers(ctx, bill.OrderIDs, []domainorder.Payer{{CustomerID: bill.CustomerID, Amount: bill.Amount, Points: points}})
+		return nil
+	}
+
+	shares, err := split.Shares(bill.Amount, lines)
 	if err != nil {
 		return err
 	}
-	log.Printf("Bill the Customer: %0.0f", price)
+	weights := make([]float64, len(shares))
+	var sum int
+	for i, share := range shares {
+		weights[i] = share.Amount
+		sum += tavern.Cents(share.Amount)
+	}
+	if sum != tavern.Cents(bill.Amount) {
+		return billing.ErrSplitMismatch
+	}
+	// Make sure every payer exists before billing any of them
+	for _, share := range shares {
+		if _, err := t.OrderService.Customers.Get(share.CustomerID); err != nil {
+			return fmt.Errorf("payer %s: %w", share.CustomerID, err)
+		}
+	}
 
-	// Bill the customer
-	//err = t.BillingService.Bill(customer, price)
+	taxes := billing.Allocate(tavern.Cents(bill.Tax), weights)
+	serviceCharges := billing.Allocate(tavern.Cents(bill.ServiceCharge), weights)
+	tips := billing.Allocate(tavern.Cents(bill.Tip), weights)
+	bills := make([]billing.Bill, len(shares))
+	for i, share := range shares {
+		bills[i] = billing.Bill{
+			CustomerID:    share.CustomerID,
+			OrderIDs:      bill.OrderIDs,
+			Amount:        share.Amount,
+			Tax:           float64(taxes[i]) / 100,
+			ServiceCharge: float64(serviceCharges[i]) / 100,
+			Tip:           float64(tips[i]) / 100,
+			StaffID:       bill.StaffID,
+		}
+		if err := t.BillingService.Bill(ctx, bills[i]); err != nil {
+			t.refundPayers(ctx, bills[:i])
+			return fmt.Errorf("payer %s: %w", share.CustomerID, err)
+		}
+	}
+	payers := make([]domainorder.Payer, 0, len(bills))
+	for _, b := range bills {
+		points := t.earnPoints(ctx, b.CustomerID, b.OrderIDs, lines, b.Amount)
+		payers = append(payers, domainorder.Payer{CustomerID: b.CustomerID, Amount: b.Amount, Points: points})
+	}
+	t.recordPayers(ctx, bill.OrderIDs, payers)
+	return nil
+}
+
+// recordPayers records who paid on the orders so that refunds go back to them. The payment already went
+// through, so failing to record the payers is logged and the orders are then refunded to their customer.
+func (t *Tavern) recordPayers(ctx context.Context, orderIDs []uuid.UUID, payers []domainorder.Payer) {
+	for _, id := range orderIDs {
+		if _, err := t.OrderService.RecordPayers(ctx, id, payers); err != nil {
+			t.logger.ErrorContext(ctx, "payers not recorded",
+				slog.String("order_id", id.String()),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+}
+
+// refundPayers gives the payers of a split back what they were billed when a later payer failed. The bill
+// fails anyway, so refunds that fail are logged.
+func (t *Tavern) refundPayers(ctx context.Context, bills []billing.Bill) {
+	for _, b := range bills {
+		refund := billing.Refund{
+			CustomerID:    b.CustomerID,
+			OrderIDs:      b.OrderIDs,
+			Amount:        b.Amount,
+			Tax:           b.Tax,
+			ServiceCharge: b.ServiceCharge,
+			Tip:           b.Tip,
+			Reason:        "split not paid",
+		}
+		if tavern.Cents(refund.Total()) == 0 {
+			continue
+		}
+		if err := t.BillingService.Refund(ctx, refund); err != nil {
+			t.logger.ErrorContext(ctx, "payer not refunded",
+				slog.String("customer_id", b.CustomerID.String()),
+				slog.Float64("amount", refund.Total()),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+}
+
+// checkTab makes sure the order can be put on the tab before it is placed
+func (t *Tavern) checkTab(ctx context.Context, customer uuid.UUID, products []uuid.UUID, req orderRequest) error {
+	if t.Tabs == nil {
+		return ErrNoTabRepository
+	}
+	if req.tip > 0 || req.tipPercent > 0 {
+		return ErrTipOnTab
+	}
+	if req.split != nil {
+		return ErrSplitOnTab
+	}
+	tb, err := t.Tabs.Get(req.tabID)
+	if err != nil {
+		return err
+	}
+	if !tb.IsOpen() {
+		return tab.ErrTabClosed
+	}
+	if tb.GetCustomerID() != customer {
+		return tab.ErrWrongCustomer
+	}
+	// Vouchers only lower the total so the quote is the most the order can cost
+	quote, err := t.OrderService.QuoteOrder(ctx, customer, products)
+	if err != nil {
+		return err
+	}
+	if !tb.CanAfford(quote.Total()) {
+		return tab.ErrCreditLimitExceeded
+	}
 	return nil
 }
+
+// OpenTab opens a tab for the customer with a credit limit, a customer can only run one tab at a time
+func (t *Tavern) OpenTab(ctx context.Context, customer uuid.UUID, limit float64) (tab.Tab, error) {
+	if t.Tabs == nil {
+		return tab.Tab{}, ErrNoTabRepository
+	}
+	if _, err := t.OrderService.Customers.Get(customer); err != nil {
+		return tab.Tab{}, err
+	}
+	tb, err := tab.NewTab(customer, limit, t.now())
+	if err != nil {
+		return tab.Tab{}, err
+	}
+	if err := t.Tabs.Add(tb); err != nil {
+		return tab.Tab{}, err
+	}
+	t.logger.InfoContext(ctx, "tab opened",
+		slog.String("customer_id", customer.String()),
+		slog.String("tab_id", tb.GetID().String()),
+		slog.Float64("limit", limit),
+	)
+	return tb, nil
+}
+
+// SettleTab bills all the orders of the tab in one payment and closes it. The tip, party size and split options
+// apply to the whole tab, the other options are ignored.
+func (t *Tavern) SettleTab(ctx context.Context, id uuid.UUID, opts ...OrderOption) (settled tab.Tab, err error) {
+	ctx, span := t.tracer.Start(ctx, "Tavern.SettleTab", trace.WithAttributes(
+		attribute.String("tab.id", id.String()),
+	))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	if t.Tabs == nil {
+		return tab.Tab{}, ErrNoTabRepository
+	}
+	if t.BillingService == nil {
+		return tab.Tab{}, ErrNoBillingService
+	}
+	req := orderRequest{}
+	for _, opt := range opts {
+		opt(&req)
+	}
+	if req.tip < 0 || req.tipPercent < 0 {
+		return tab.Tab{}, ErrInvalidTip
+	}
+
+	tb, err := t.Tabs.Get(id)
+	if err != nil {
+		return tab.Tab{}, err
+	}
+	if !tb.IsOpen() {
+		return tab.Tab{}, tab.ErrTabClosed
+	}
+
+	var lines []domainorder.Line
+	var tax float64
+	for _, id := range tb.OrderIDs() {
+		o, err := t.OrderService.Orders.Get(id)
+		if err != nil {
+			return tab.Tab{}, err
+		}
+		lines = append(lines, o.GetLines()...)
+		tax += o.Tax()
+	}
+	bill := t.bill(tb.GetCustomerID(), tb.OrderIDs(), tb.Total(), tax, req)
+	// The tab is closed before it is paid so that it is paid once, with the orders it was billed for
+	tb, err = t.Tabs.Close(id, tb.GetEntries(), t.now())
+	if err != nil {
+		return tab.Tab{}, err
+	}
+	if err := t.charge(ctx, bill, lines, req.split); err != nil {
+		if _, reopenErr := t.Tabs.Reopen(id); reopenErr != nil {
+			t.logger.ErrorContext(ctx, "tab not reopened",
+				slog.String("customer_id", tb.GetCustomerID().String()),
+				slog.String("tab_id", id.String()),
+				slog.String("error", reopenErr.Error()),
+			)
+		}
+		return tab.Tab{}, err
+	}
+
+	t.logger.InfoContext(ctx, "tab settled",
+		slog.String("customer_id", tb.GetCustomerID().String()),
+		slog.String("tab_id", tb.GetID().String()),
+		slog.Int("order_count", len(tb.GetEntries())),
+		slog.Float64("total", bill.Total()),
+	)
+	return tb, nil
+}
+
+// bill creates the bill of placed orders, with the service charge for large parties and the tip
+func (t *Tavern) bill(customer uuid.UUID, orderIDs []uuid.UUID, amount, tax float64, req orderRequest) billing.Bill {
+	total := pricing.Round(amount)
+	bill := billing.Bill{
+		CustomerID: customer,
+		OrderIDs:   orderIDs,
+		Amount:     total,
+		Tax:        pricing.Round(tax),
+		Tip:        pricing.Round(req.tip + total*req.tipPercent/100),
+		StaffID:    req.staffID,
+	}
+	if t.serviceChargePartySize > 0 && req.partySize >= t.serviceChargePartySize {
+		bill.ServiceCharge = pricing.Round(total * t.serviceCharge / 100)
+	}
+	return bill
+}
+
+// applyVoucher takes the value of the redeemed voucher off the order and records it as a transaction of the
+// customer, it returns the amount recorded. Nothing is recorded when the voucher is worth nothing on the order.
+func (t *Tavern) applyVoucher(ctx context.Context, placed domainorder.Order, v voucher.Voucher) (domainorder.Order, float64, error) {
+	amount := pricing.Round(v.Discount(placed.Net()))
+	if tavern.Cents(amount) > 0 {
+		var err error
+		placed, err = t.OrderService.ApplyDiscount(ctx, placed.GetID(), domainorder.Discount{
+			Rule:        "voucher",
+			Description: fmt.Sprintf("voucher %s", v.GetCode()),
+			Amount:      amount,
+			Voucher:     v.GetCode(),
+		})
+		if err != nil {
+			return domainorder.Order{}, 0, err
+		}
+
+		_, err = customer.Change(t.OrderService.Customers, placed.GetCustomerID(), func(c *customer.Customer) error {
+			c.AddTransaction(tavern.NewTransaction(tavern.Cents(amount), v.GetID(), c.GetID(), placed.GetCreatedAt()).
+				WithReference(fmt.Sprintf("voucher %s for order %s", v.GetCode(), placed.GetID())).
+				WithKind(tavern.TransactionVoucher))
+			return nil
+		})
+		if err != nil {
+			return domainorder.Order{}, 0, err
+		}
+	} else {
+		amount = 0
+	}
+
+	t.logger.InfoContext(ctx, "voucher redeemed",
+		slog.String("customer_id", placed.GetCustomerID().String()),
+		slog.String("order_id", placed.GetID().String()),
+		slog.String("voucher", v.GetCode()),
+		slog.Float64("amount", amount),
+	)
+	return placed, amount, nil
+}
+
+// releaseVoucher gives back the redemption made for an order that could not be placed or was cancelled
+func (t *Tavern) releaseVoucher(ctx context.Context, v voucher.Voucher, orderID uuid.UUID) {
+	if err := t.Vouchers.Release(v.GetCode(), orderID); err != nil {
+		t.logger.ErrorContext(ctx, "voucher release failed",
+			slog.String("order_id", orderID.String()),
+			slog.String("voucher", v.GetCode()),
+			slog.String("error", err.Error()),
+		)
+	}
+}
@@ -1,12 +1,35 @@
 package tavern
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gegaryfa/tavern/domain/customer"
+	"github.com/gegaryfa/tavern/domain/idempotency"
+	idemmemory "github.com/gegaryfa/tavern/domain/idempotency/memory"
+	"github.com/gegaryfa/tavern/domain/loyalty"
+	domainorder "github.com/gegaryfa/tavern/domain/order"
 	"github.com/gegaryfa/tavern/domain/product"
+	"github.com/gegaryfa/tavern/domain/saga"
+	sagamemory "github.com/gegaryfa/tavern/domain/saga/memory"
+	"github.com/gegaryfa/tavern/domain/tab"
+	tabmemory "github.com/gegaryfa/tavern/domain/tab/memory"
+	"github.com/gegaryfa/tavern/domain/ticket"
+	"github.com/gegaryfa/tavern/domain/voucher"
+	vouchermemory "github.com/gegaryfa/tavern/domain/voucher/memory"
+	"github.com/gegaryfa/tavern/services/billing"
+	"github.com/gegaryfa/tavern/services/kitchen"
 	"github.com/gegaryfa/tavern/services/order"
+	"github.com/gegaryfa/tavern/telemetry"
 	"github.com/google/uuid"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 func initProducts(t *testing.T) []product.Product {
@@ -58,7 +81,7 @@ func TestTavern_Order(t *testing.T) {
 		products[0].GetID(),
 	}
 	// Execute Order
-	err = tavern.Order(cust.GetID(), order)
+	_, err = tavern.Order(context.Background(), cust.GetID(), order)
 	if err != nil {
 		t.Error(err)
 	}
@@ -91,9 +114,1018 @@ func Test_MongoTavern(t *testing.T) {
 	}
 
 	// Execute Order
-	err = tavern.Order(uid, order)
+	_, err = tavern.Order(context.Background(), uid, order)
 	if err != nil {
 		t.Error(err)
 	}
 
 }
+
+func TestTavern_OrderTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	products := initProducts(t)
+
+	os, err := order.NewOrderService(
+		order.WithMemoryCustomerRepository(),
+		order.WithMemoryProductRepository(products),
+		order.WithTracerProvider(tp),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tavern, err := NewTavern(WithOrderService(os), WithTracerProvider(tp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid, err := os.AddCustomer("Percy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = tavern.Order(context.Background(), uid, []uuid.UUID{products[0].GetID()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, s := range recorder.Ended() {
+		spans[s.Name()] = s
+	}
+	root, ok := spans["Tavern.Order"]
+	if !ok {
+		t.Fatalf("Expected a Tavern.Order span, got %v", spans)
+	}
+	create, ok := spans["OrderService.CreateOrder"]
+	if !ok {
+		t.Fatalf("Expected an OrderService.CreateOrder span, got %v", spans)
+	}
+	if create.Parent().SpanID() != root.SpanContext().SpanID() {
+		t.Errorf("Expected CreateOrder to be a child of Tavern.Order")
+	}
+	get, ok := spans["product.Repository.GetByID"]
+	if !ok {
+		t.Fatalf("Expected a product.Repository.GetByID span, got %v", spans)
+	}
+	if get.Parent().SpanID() != create.SpanContext().SpanID() {
+		t.Errorf("Expected GetByID to be a child of CreateOrder")
+	}
+}
+
+func TestTavern_OrderLogging(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	products := initProducts(t)
+
+	os, err := order.NewOrderService(
+		order.WithMemoryCustomerRepository(),
+		order.WithMemoryProductRepository(products),
+		order.WithLogger(logger),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tavern, err := NewTavern(WithOrderService(os), WithLogger(logger))
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid, err := os.AddCustomer("Percy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := telemetry.WithRequestID(context.Background(), "req-1")
+	if _, err := tavern.Order(ctx, uid, []uuid.UUID{products[0].GetID()}); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 log records, got %d: %s", len(lines), buf.String())
+	}
+	for _, line := range lines {
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatal(err)
+		}
+		if record["request_id"] != "req-1" {
+			t.Errorf("Expected request_id req-1 in %s", line)
+		}
+		if record["customer_id"] != uid.String() {
+			t.Errorf("Expected customer_id %v in %s", uid, line)
+		}
+	}
+}
+
+func TestTavern_OrderWithVoucher(t *testing.T) {
+	products := initProducts(t)
+	os, err := order.NewOrderService(
+		order.WithMemoryCustomerRepository(),
+		order.WithMemoryProductRepository(products),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vouchers := vouchermemory.New()
+	v, err := voucher.NewVoucher("WELCOME", voucher.WithAmount(1), voucher.WithPerCustomerLimit(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := vouchers.Add(v); err != nil {
+		t.Fatal(err)
+	}
+	tavern, err := NewTavern(WithOrderService(os), WithVoucherRepository(vouchers))
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid, err := os.AddCustomer("Percy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A failing order gives the redemption back
+	_, err = tavern.Order(context.Background(), uid, []uuid.UUID{uuid.New()}, WithVoucherCode("welcome"))
+	if err != product.ErrProductNotFound {
+		t.Fatalf("Expected error %v, got %v", product.ErrProductNotFound, err)
+	}
+
+	placed, err := tavern.Order(context.Background(), uid, []uuid.UUID{products[0].GetID()}, WithVoucherCode("welcome"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if placed.Total() != 0.99 {
+		t.Errorf("Expected total 0.99, got %v", placed.Total())
+	}
+	stored, err := os.Orders.Get(placed.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored.GetDiscounts()) != 1 || stored.GetDiscounts()[0].Voucher != "WELCOME" {
+		t.Errorf("Expected the voucher to be recorded on the order, got %v", stored.GetDiscounts())
+	}
+	c, err := os.Customers.Get(uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.GetTransactions()) != 1 || c.GetTransactions()[0].GetAmount() != 100 {
+		t.Errorf("Expected a transaction of 100 cents, got %v", c.GetTransactions())
+	}
+
+	_, err = tavern.Order(context.Background(), uid, []uuid.UUID{products[0].GetID()}, WithVoucherCode("welcome"))
+	if !errors.Is(err, voucher.ErrCustomerLimitReached) {
+		t.Errorf("Expected error %v, got %v", voucher.ErrCustomerLimitReached, err)
+	}
+}
+
+func TestTavern_OrderWithTipAndServiceCharge(t *testing.T) {
+	products := initProducts(t)
+	os, err := order.NewOrderService(
+		order.WithMemoryCustomerRepository(),
+		order.WithMemoryProductRepository(products),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs, err := billing.NewCustomerBilling(billing.WithCustomerRepository(os.Customers))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tavern, err := NewTavern(WithOrderService(os), WithBillingService(bs), WithServiceCharge(6, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid, err := os.AddCustomer("Percy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	staff := uuid.New()
+
+	// Beer and peenuts for 2.98, 10% service charge and a 20% tip on top of it
+	_, err = tavern.Order(context.Background(), uid, []uuid.UUID{products[0].GetID(), products[1].GetID()},
+		WithPartySize(8), WithTipPercent(20), WithStaff(staff))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A small party pays no service charge
+	_, err = tavern.Order(context.Background(), uid, []uuid.UUID{products[0].GetID()}, WithPartySize(2), WithTip(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := bs.TipReport(time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Tips[staff] != 0.6 || report.Tips[uuid.Nil] != 1 {
+		t.Errorf("Expected tips of 0.6 and 1, got %v", report.Tips)
+	}
+
+	c, err := os.Customers.Get(uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var serviceCharges int
+	for _, tr := range c.GetTransactions() {
+		if tr.GetKind() == "service_charge" {
+			serviceCharges += tr.GetAmount()
+		}
+	}
+	if serviceCharges != 30 {
+		t.Errorf("Expected a service charge of 30 cents, got %d", serviceCharges)
+	}
+
+	if _, err := tavern.Order(context.Background(), uid, []uuid.UUID{products[0].GetID()}, WithTip(-1)); err != ErrInvalidTip {
+		t.Errorf("Expected error %v, got %v", ErrInvalidTip, err)
+	}
+}
+
+func TestTavern_Tab(t *testing.T) {
+	products := initProducts(t)
+	os, err := order.NewOrderService(
+		order.WithMemoryCustomerRepository(),
+		order.WithMemoryProductRepository(products),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs, err := billing.NewCustomerBilling(billing.WithCustomerRepository(os.Customers))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tavern, err := NewTavern(WithOrderService(os), WithBillingService(bs), WithTabRepository(tabmemory.New()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid, err := os.AddCustomer("Percy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tb, err := tavern.OpenTab(context.Background(), uid, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tavern.OpenTab(context.Background(), uid, 5); err != tab.ErrTabAlreadyOpen {
+		t.Errorf("Expected error %v, got %v", tab.ErrTabAlreadyOpen, err)
+	}
+
+	// Two beers fit in the limit, a third one does not
+	beer := []uuid.UUID{products[0].GetID()}
+	for i := 0; i < 2; i++ {
+		if _, err := tavern.Order(context.Background(), uid, beer, WithTab(tb.GetID())); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := tavern.Order(context.Background(), uid, beer, WithTab(tb.GetID())); err != tab.ErrCreditLimitExceeded {
+		t.Errorf("Expected error %v, got %v", tab.ErrCreditLimitExceeded, err)
+	}
+	if _, err := tavern.Order(context.Background(), uid, beer, WithTab(tb.GetID()), WithTip(1)); err != ErrTipOnTab {
+		t.Errorf("Expected error %v, got %v", ErrTipOnTab, err)
+	}
+
+	// Nothing is billed until the tab is settled
+	c, err := os.Customers.Get(uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.GetTransactions()) != 0 {
+		t.Fatalf("Expected no transactions before settling, got %v", c.GetTransactions())
+	}
+
+	// A settle that fails to bill leaves the tab open
+	if _, err := tavern.SettleTab(context.Background(), tb.GetID(), WithSplit(billing.Even{Payers: []uuid.UUID{uid, uuid.New()}})); !errors.Is(err, customer.ErrCustomerNotFound) {
+		t.Errorf("Expected error %v, got %v", customer.ErrCustomerNotFound, err)
+	}
+	if open, err := tavern.Tabs.Get(tb.GetID()); err != nil || !open.IsOpen() {
+		t.Fatalf("Expected the tab to be reopened, got %v %v", open, err)
+	}
+
+	settled, err := tavern.SettleTab(context.Background(), tb.GetID(), WithTip(0.5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settled.IsOpen() {
+		t.Errorf("Expected the tab to be closed")
+	}
+	c, err = os.Customers.Get(uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.GetTransactions()) != 2 || c.GetTransactions()[0].GetAmount() != 398 || c.GetTransactions()[1].GetAmount() != 50 {
+		t.Errorf("Expected a payment of 398 cents and a tip of 50 cents, got %v", c.GetTransactions())
+	}
+
+	if _, err := tavern.SettleTab(context.Background(), tb.GetID()); err != tab.ErrTabClosed {
+		t.Errorf("Expected error %v, got %v", tab.ErrTabClosed, err)
+	}
+	if _, err := tavern.Order(context.Background(), uid, beer, WithTab(tb.GetID())); err != tab.ErrTabClosed {
+		t.Errorf("Expected error %v, got %v", tab.ErrTabClosed, err)
+	}
+}
+
+func TestTavern_SplitBill(t *testing.T) {
+	products := initProducts(t)
+	os, err := order.NewOrderService(
+		order.WithMemoryCustomerRepository(),
+		order.WithMemoryProductRepository(products),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs, err := billing.NewCustomerBilling(billing.WithCustomerRepository(os.Customers))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tavern, err := NewTavern(WithOrderService(os), WithBillingService(bs), WithTabRepository(tabmemory.New()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	percy, err := os.AddCustomer("Percy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	anna, err := os.AddCustomer("Anna")
+	if err != nil {
+		t.Fatal(err)
+	}
+	paid := func(id uuid.UUID) int {
+		c, err := os.Customers.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var cents int
+		for _, tr := range c.GetTransactions() {
+			if tr.GetTo() == id {
+				cents -= tr.GetAmount()
+			} else {
+				cents += tr.GetAmount()
+			}
+		}
+		return cents
+	}
+
+	// Beer and peenuts for 2.98 split evenly, with a tip of 1
+	split, err := tavern.Order(context.Background(), percy, []uuid.UUID{products[0].GetID(), products[1].GetID()},
+		WithSplit(billing.Even{Payers: []uuid.UUID{percy, anna}}), WithTip(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if paid(percy) != 199 || paid(anna) != 199 {
+		t.Errorf("Expected both to pay 199 cents, got %d and %d", paid(percy), paid(anna))
+	}
+	// Cancelling the order pays both back their share, without the tip
+	if _, err := tavern.CancelOrder(context.Background(), split.GetID()); err != nil {
+		t.Fatal(err)
+	}
+	if paid(percy) != 50 || paid(anna) != 50 {
+		t.Errorf("Expected both to have paid 50 cents, got %d and %d", paid(percy), paid(anna))
+	}
+
+	_, err = tavern.Order(context.Background(), percy, []uuid.UUID{products[0].GetID()},
+		WithSplit(billing.ByAmount{Amounts: []billing.Share{{CustomerID: percy, Amount: 1}, {CustomerID: anna, Amount: 1}}}))
+	if err != billing.ErrSplitMismatch {
+		t.Errorf("Expected error %v, got %v", billing.ErrSplitMismatch, err)
+	}
+	_, err = tavern.Order(context.Background(), percy, []uuid.UUID{products[0].GetID()},
+		WithSplit(billing.Even{Payers: []uuid.UUID{percy, uuid.New()}}))
+	if !errors.Is(err, customer.ErrCustomerNotFound) {
+		t.Errorf("Expected error %v, got %v", customer.ErrCustomerNotFound, err)
+	}
+
+	// A tab is split by what everyone had
+	tb, err := tavern.OpenTab(context.Background(), percy, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []uuid.UUID{products[0].GetID(), products[2].GetID(), products[0].GetID()} {
+		if _, err := tavern.Order(context.Background(), percy, []uuid.UUID{p}, WithTab(tb.GetID())); err != nil {
+			t.Fatal(err)
+		}
+	}
+	before := [2]int{paid(percy), paid(anna)}
+	_, err = tavern.SettleTab(context.Background(), tb.GetID(), WithSplit(billing.ByLine{Payers: map[int]uuid.UUID{
+		0: percy,
+		1: anna,
+		2: percy,
+	}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if paid(percy)-before[0] != 398 || paid(anna)-before[1] != 99 {
+		t.Errorf("Expected 398 and 99 cents, got %d and %d", paid(percy)-before[0], paid(anna)-before[1])
+	}
+}
+
+func TestTavern_Loyalty(t *testing.T) {
+	products := initProducts(t)
+	beer, wine := products[0].GetID(), products[2].GetID()
+	os, err := order.NewOrderService(
+		order.WithMemoryCustomerRepository(),
+		order.WithMemoryProductRepository(products),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs, err := billing.NewCustomerBilling(billing.WithCustomerRepository(os.Customers))
+	if err != nil {
+		t.Fatal(err)
+	}
+	programme, err := loyalty.NewProgramme(
+		loyalty.WithPointsPerUnit(100),
+		loyalty.WithReward(wine, 150),
+		loyalty.WithTierThresholds(300, 1000),
+		loyalty.WithExpiry(30*24*time.Hour),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2022, 11, 1, 18, 0, 0, 0, time.UTC)
+	tavern, err := NewTavern(
+		WithOrderService(os),
+		WithBillingService(bs),
+		WithLoyaltyProgramme(programme),
+		WithClock(func() time.Time { return now }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid, err := os.AddCustomer("Percy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Two paid beers earn 398 points and promote the customer to silver
+	for i := 0; i < 2; i++ {
+		if _, err := tavern.Order(context.Background(), uid, []uuid.UUID{beer}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c, err := os.Customers.Get(uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.PointsBalance(now); got != 398 {
+		t.Errorf("Expected 398 points, got %d", got)
+	}
+	if got := programme.Tier(c.LifetimePoints()); got != loyalty.TierSilver {
+		t.Errorf("Expected tier %s, got %s", loyalty.TierSilver, got)
+	}
+
+	// The wine is paid with points, only the beer is billed and earns points
+	placed, err := tavern.Order(context.Background(), uid, []uuid.UUID{beer}, WithRewards(wine))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(placed.GetLines()) != 2 || placed.Total() != 1.99 {
+		t.Errorf("Expected the beer and the free wine for 1.99, got %v for %v", placed.GetLines(), placed.Total())
+	}
+	c, err = os.Customers.Get(uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.PointsBalance(now); got != 447 {
+		t.Errorf("Expected 447 points, got %d", got)
+	}
+	transactions := c.GetTransactions()
+	if got := transactions[len(transactions)-1].GetAmount(); got != 199 {
+		t.Errorf("Expected a payment of 199 cents, got %d", got)
+	}
+
+	// Cancelling the order reverses the points it earned and gives back the points spent on the wine
+	if _, err := tavern.CancelOrder(context.Background(), placed.GetID()); err != nil {
+		t.Fatal(err)
+	}
+	c, err = os.Customers.Get(uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.PointsBalance(now); got != 398 {
+		t.Errorf("Expected 398 points, got %d", got)
+	}
+	if got := c.LifetimePoints(); got != 398 {
+		t.Errorf("Expected 398 lifetime points, got %d", got)
+	}
+	placed, err = tavern.Order(context.Background(), uid, []uuid.UUID{beer}, WithRewards(wine))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The points spent on an order that can not be paid are given back
+	_, err = tavern.Order(context.Background(), uid, []uuid.UUID{beer}, WithRewards(wine),
+		WithSplit(billing.Even{Payers: []uuid.UUID{uid, uuid.New()}}))
+	if !errors.Is(err, customer.ErrCustomerNotFound) {
+		t.Errorf("Expected error %v, got %v", customer.ErrCustomerNotFound, err)
+	}
+	c, err = os.Customers.Get(uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.PointsBalance(now); got != 447 {
+		t.Errorf("Expected 447 points, got %d", got)
+	}
+
+	if _, err := tavern.Order(context.Background(), uid, nil, WithRewards(beer)); !errors.Is(err, loyalty.ErrNotAReward) {
+		t.Errorf("Expected error %v, got %v", loyalty.ErrNotAReward, err)
+	}
+	// A month later the points expired
+	now = now.AddDate(0, 1, 1)
+	if _, err := tavern.Order(context.Background(), uid, nil, WithRewards(wine)); err != customer.ErrInsufficientPoints {
+		t.Errorf("Expected error %v, got %v", customer.ErrInsufficientPoints, err)
+	}
+}
+
+// flakyBilling fails the next refund when fail is set
+type flakyBilling struct {
+	billing.Service
+	fail bool
+}
+
+func (b *flakyBilling) Refund(ctx context.Context, refund billing.Refund) error {
+	if b.fail {
+		b.fail = false
+		return errors.New("payments unavailable")
+	}
+	return b.Service.Refund(ctx, refund)
+}
+
+// flakyVouchers fails the next release when fail is set
+type flakyVouchers struct {
+	voucher.Repository
+	fail bool
+}
+
+func (r *flakyVouchers) Release(code string, orderID uuid.UUID) error {
+	if r.fail {
+		r.fail = false
+		return errors.New("vouchers unavailable")
+	}
+	return r.Repository.Release(code, orderID)
+}
+
+func TestTavern_CancelAndRefund(t *testing.T) {
+	products := initProducts(t)
+	beer := []uuid.UUID{products[0].GetID()}
+	os, err := order.NewOrderService(
+		order.WithMemoryCustomerRepository(),
+		order.WithMemoryProductRepository(products),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs, err := billing.NewCustomerBilling(billing.WithCustomerRepository(os.Customers))
+	if err != nil {
+		t.Fatal(err)
+	}
+	vouchers := vouchermemory.New()
+	v, err := voucher.NewVoucher("WELCOME", voucher.WithAmount(1), voucher.WithPerCustomerLimit(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := vouchers.Add(v); err != nil {
+		t.Fatal(err)
+	}
+	tabs := tabmemory.New()
+	flaky := &flakyBilling{Service: bs}
+	flakyVouchers := &flakyVouchers{Repository: vouchers}
+	tavern, err := NewTavern(WithOrderService(os), WithBillingService(flaky), WithVoucherRepository(flakyVouchers), WithTabRepository(tabs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid, err := os.AddCustomer("Percy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	customerNow := func() customer.Customer {
+		c, err := os.Customers.Get(uid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	// Cancelling a paid order refunds it and gives the voucher back
+	placed, err := tavern.Order(context.Background(), uid, beer, WithVoucherCode("welcome"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tavern.CancelOrder(context.Background(), placed.GetID()); err != nil {
+		t.Fatal(err)
+	}
+	c := customerNow()
+	if got := c.GetTransactions()[len(c.GetTransactions())-1]; got.GetKind() != "voucher" || got.GetAmount() != 100 || got.GetFrom() != uid {
+		t.Errorf("Expected the voucher transaction to be reversed, got %v", got)
+	}
+	refund := c.GetTransactions()[len(c.GetTransactions())-2]
+	if refund.GetKind() != "refund" || refund.GetAmount() != 99 || refund.GetTo() != uid {
+		t.Errorf("Expected a refund of 99 cents, got %v", refund)
+	}
+	again, err := tavern.Order(context.Background(), uid, beer, WithVoucherCode("welcome"))
+	if err != nil {
+		t.Errorf("Expected the voucher to be redeemable again, got %v", err)
+	}
+	if v, err := vouchers.Get("WELCOME"); err != nil || len(v.GetRedemptions()) != 1 || v.GetRedemptions()[0].OrderID != again.GetID() {
+		t.Errorf("Expected the voucher to be redeemed for order %v, got %v %v", again.GetID(), v.GetRedemptions(), err)
+	}
+
+	// Served orders can not be cancelled but can be refunded in part
+	served, err := tavern.Order(context.Background(), uid, beer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.ServeOrder(context.Background(), served.GetID()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tavern.CancelOrder(context.Background(), served.GetID()); err != domainorder.ErrOrderServed {
+		t.Errorf("Expected error %v, got %v", domainorder.ErrOrderServed, err)
+	}
+	if _, err := tavern.RefundOrder(context.Background(), served.GetID(), 1.5, "spilled"); err != nil {
+		t.Fatal(err)
+	}
+	c = customerNow()
+	if got := c.GetTransactions()[len(c.GetTransactions())-1]; got.GetKind() != "refund" || got.GetAmount() != 150 {
+		t.Errorf("Expected a refund of 150 cents, got %v", got)
+	}
+	if _, err := tavern.RefundOrder(context.Background(), served.GetID(), 0.5, "too much"); err != domainorder.ErrInvalidRefund {
+		t.Errorf("Expected error %v, got %v", domainorder.ErrInvalidRefund, err)
+	}
+
+	// Cancelling an order on an open tab takes it off the tab
+	tb, err := tavern.OpenTab(context.Background(), uid, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	onTab, err := tavern.Order(context.Background(), uid, beer, WithTab(tb.GetID()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tavern.RefundOrder(context.Background(), onTab.GetID(), 1, "spilled"); err != ErrRefundOnTab {
+		t.Errorf("Expected error %v, got %v", ErrRefundOnTab, err)
+	}
+	if _, err := tavern.CancelOrder(context.Background(), onTab.GetID()); err != nil {
+		t.Fatal(err)
+	}
+	tb, err = tabs.Get(tb.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tb.OrderIDs()) != 0 {
+		t.Errorf("Expected the order to be taken off the tab, got %v", tb.OrderIDs())
+	}
+
+	// An order whose refund failed is not cancelled, cancelling it again refunds it
+	unpaid, err := tavern.Order(context.Background(), uid, beer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flaky.fail = true
+	if _, err := tavern.CancelOrder(context.Background(), unpaid.GetID()); err == nil {
+		t.Error("Expected the refund to fail the cancellation")
+	}
+	unpaid, err = os.Orders.Get(unpaid.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unpaid.GetStatus() != domainorder.StatusPlaced || unpaid.Refundable() != unpaid.Total() {
+		t.Errorf("Expected the order to be placed and not refunded, got %s with %v refundable", unpaid.GetStatus(), unpaid.Refundable())
+	}
+	if _, err := tavern.CancelOrder(context.Background(), unpaid.GetID()); err != nil {
+		t.Fatal(err)
+	}
+	c = customerNow()
+	if got := c.GetTransactions()[len(c.GetTransactions())-1]; got.GetKind() != "refund" || got.GetAmount() != 199 {
+		t.Errorf("Expected a refund of 199 cents, got %v", got)
+	}
+
+	// An order whose cancellation failed after the refund stays cancelling, cancelling it again resumes it
+	flakyVouchers.fail = true
+	if _, err := tavern.CancelOrder(context.Background(), again.GetID()); err == nil {
+		t.Error("Expected the voucher release to fail the cancellation")
+	}
+	again, err = os.Orders.Get(again.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.GetStatus() != domainorder.StatusCancelling || again.Refundable() != 0 {
+		t.Errorf("Expected the order to be refunded and cancelling, got %s with %v refundable", again.GetStatus(), again.Refundable())
+	}
+	if _, err := os.ServeOrder(context.Background(), again.GetID()); err != domainorder.ErrOrderCancelled {
+		t.Errorf("Expected error %v, got %v", domainorder.ErrOrderCancelled, err)
+	}
+	before := len(customerNow().GetTransactions())
+	cancelled, err := tavern.CancelOrder(context.Background(), again.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cancelled.GetStatus() != domainorder.StatusCancelled || len(cancelled.GetRefunds()) != 1 {
+		t.Errorf("Expected the order to be cancelled with one refund, got %s with %v", cancelled.GetStatus(), cancelled.GetRefunds())
+	}
+	c = customerNow()
+	if got := c.GetTransactions()[before:]; len(got) != 1 || got[0].GetKind() != "voucher" {
+		t.Errorf("Expected only the voucher to be reversed when resuming, got %v", got)
+	}
+	if v, err := vouchers.Get("WELCOME"); err != nil || len(v.GetRedemptions()) != 0 {
+		t.Errorf("Expected the redemption to be released, got %v %v", v.GetRedemptions(), err)
+	}
+}
+
+func TestTavern_IdempotentOrder(t *testing.T) {
+	products := initProducts(t)
+	os, err := order.NewOrderService(
+		order.WithMemoryCustomerRepository(),
+		order.WithMemoryProductRepository(products),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs, err := billing.NewCustomerBilling(billing.WithCustomerRepository(os.Customers))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tavern, err := NewTavern(WithOrderService(os), WithBillingService(bs), WithIdempotencyStore(idemmemory.New(), time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid, err := os.AddCustomer("Percy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	beer := []uuid.UUID{products[0].GetID()}
+
+	// The point of sale retries after a timeout, the customer is only charged once
+	first, err := tavern.Order(context.Background(), uid, beer, WithIdempotencyKey("pos-1"), WithTip(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	retry, err := tavern.Order(context.Background(), uid, beer, WithIdempotencyKey("pos-1"), WithTip(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if retry.GetID() != first.GetID() {
+		t.Errorf("Expected the retry to return order %v, got %v", first.GetID(), retry.GetID())
+	}
+	c, err := os.Customers.Get(uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.GetTransactions()) != 2 {
+		t.Errorf("Expected a single payment and tip, got %v", c.GetTransactions())
+	}
+	// The same key with another tip is another request
+	if _, err := tavern.Order(context.Background(), uid, beer, WithIdempotencyKey("pos-1"), WithTip(2)); !errors.Is(err, idempotency.ErrKeyReused) {
+		t.Errorf("Expected error %v, got %v", idempotency.ErrKeyReused, err)
+	}
+
+	// An order that failed once placed is not placed again, the retry gets the failure back
+	split := WithSplit(billing.Even{Payers: []uuid.UUID{uid, uuid.New()}})
+	if _, err := tavern.Order(context.Background(), uid, beer, WithIdempotencyKey("pos-2"), split); !errors.Is(err, customer.ErrCustomerNotFound) {
+		t.Errorf("Expected error %v, got %v", customer.ErrCustomerNotFound, err)
+	}
+	if _, err := tavern.Order(context.Background(), uid, beer, WithIdempotencyKey("pos-2"), split); !errors.Is(err, idempotency.ErrRequestFailed) {
+		t.Errorf("Expected error %v, got %v", idempotency.ErrRequestFailed, err)
+	}
+}
+
+func TestTavern_OrderSaga(t *testing.T) {
+	products := initProducts(t)
+	products[0].SetQuantity(2)
+	beer := products[0].GetID()
+	os, err := order.NewOrderService(
+		order.WithMemoryCustomerRepository(),
+		order.WithMemoryProductRepository(products),
+		order.WithStockReservation(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs, err := billing.NewWalletBilling(billing.WithCustomerRepository(os.Customers))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sagas := sagamemory.New()
+	tavern, err := NewTavern(WithOrderService(os), WithBillingService(bs), WithSagaStore(sagas))
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid, err := os.AddCustomer("Percy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bs.TopUp(context.Background(), uid, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	type testCase struct {
+		test            string
+		products        []uuid.UUID
+		expectedErr     error
+		expectedStock   int
+		expectedBalance float64
+	}
+
+	testCases := []testCase{
+		{test: "Order is placed and charged", products: []uuid.UUID{beer}, expectedStock: 1, expectedBalance: 1.01},
+		{test: "Failed charge cancels the order", products: []uuid.UUID{beer}, expectedErr: customer.ErrInsufficientFunds, expectedStock: 1, expectedBalance: 1.01},
+		{test: "Out of stock order is not placed", products: []uuid.UUID{beer, beer}, expectedErr: product.ErrOutOfStock, expectedStock: 1, expectedBalance: 1.01},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			_, err := tavern.Order(context.Background(), uid, tc.products)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+			p, err := os.Products.GetByID(beer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.GetQuantity() != tc.expectedStock {
+				t.Errorf("Expected %d in stock, got %d", tc.expectedStock, p.GetQuantity())
+			}
+			balance, err := bs.Balance(uid)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if balance != tc.expectedBalance {
+				t.Errorf("Expected balance %v, got %v", tc.expectedBalance, balance)
+			}
+		})
+	}
+
+	// An order interrupted by a crash after reserving the stock is carried on when the tavern restarts
+	if _, err := bs.TopUp(context.Background(), uid, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.ReserveStock(context.Background(), []uuid.UUID{beer}); err != nil {
+		t.Fatal(err)
+	}
+	interrupted := saga.State{ID: uuid.New(), Saga: orderingSaga, Status: saga.StatusRunning, Step: 1, Data: saga.Data{
+		sagaCustomer: uid.String(), sagaProducts: beer.String(), sagaTip: "0", sagaTipPercent: "0",
+		sagaStaff: uuid.Nil.String(), sagaPartySize: "0", sagaReserved: "true",
+	}}
+	if err := sagas.Save(interrupted); err != nil {
+		t.Fatal(err)
+	}
+	if err := tavern.ResumeOrders(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	state, err := sagas.Get(interrupted.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Status != saga.StatusCompleted || state.Data[sagaCharged] == "" || state.Data[sagaConfirmed] == "" {
+		t.Errorf("Expected the order to be charged and confirmed, got %v", state)
+	}
+	if balance, _ := bs.Balance(uid); balance != 0.02 {
+		t.Errorf("Expected balance 0.02, got %v", balance)
+	}
+	if p, _ := os.Products.GetByID(beer); p.GetQuantity() != 0 {
+		t.Errorf("Expected nothing left in stock, got %d", p.GetQuantity())
+	}
+
+	// The saga does not support orders it could not compensate
+	if _, err := tavern.Order(context.Background(), uid, []uuid.UUID{beer}, WithTab(uuid.New())); err != ErrNotSagaOrder {
+		t.Errorf("Expected error %v, got %v", ErrNotSagaOrder, err)
+	}
+
+	// Without stock reservation the saga does not take the products out of stock
+	fresh := initProducts(t)
+	unreserved, err := order.NewOrderService(
+		order.WithMemoryCustomerRepository(),
+		order.WithMemoryProductRepository(fresh),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tavern, err = NewTavern(WithOrderService(unreserved), WithSagaStore(sagamemory.New()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid, err = unreserved.AddCustomer("Anna")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tavern.Order(context.Background(), uid, []uuid.UUID{fresh[0].GetID()}); err != nil {
+		t.Errorf("Expected the order to be placed, got %v", err)
+	}
+}
+
+func TestTavern_Kitchen(t *testing.T) {
+	products := initProducts(t)
+	os, err := order.NewOrderService(
+		order.WithMemoryCustomerRepository(),
+		order.WithMemoryProductRepository(products),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := kitchen.NewKitchen(kitchen.WithMemoryTicketRepository(), kitchen.WithOrderRepository(os.Orders))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tavern, err := NewTavern(WithOrderService(os), WithKitchen(k))
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid, err := os.AddCustomer("Percy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	placed, err := tavern.Order(context.Background(), uid, []uuid.UUID{products[0].GetID(), products[1].GetID()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	queue, err := k.Queue(ticket.StationKitchen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queue) != 1 || queue[0].GetOrderID() != placed.GetID() || len(queue[0].GetLines()) != 2 {
+		t.Fatalf("Expected the order to be queued in the kitchen, got %v", queue)
+	}
+
+	// Cancelling the order takes its tickets off the queue
+	if _, err := tavern.CancelOrder(context.Background(), placed.GetID()); err != nil {
+		t.Fatal(err)
+	}
+	if queue, _ := k.Queue(ticket.StationKitchen); len(queue) != 0 {
+		t.Errorf("Expected the kitchen queue to be empty, got %v", queue)
+	}
+}
+
+func TestTavern_OrderUndone(t *testing.T) {
+	products := initProducts(t)
+	products[0].SetQuantity(1)
+	products[1].SetQuantity(1)
+	beer := []uuid.UUID{products[0].GetID()}
+	os, err := order.NewOrderService(
+		order.WithMemoryCustomerRepository(),
+		order.WithMemoryProductRepository(products),
+		order.WithStockReservation(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs, err := billing.NewWalletBilling(billing.WithCustomerRepository(os.Customers))
+	if err != nil {
+		t.Fatal(err)
+	}
+	vouchers := vouchermemory.New()
+	v, err := voucher.NewVoucher("WELCOME", voucher.WithAmount(1), voucher.WithPerCustomerLimit(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := vouchers.Add(v); err != nil {
+		t.Fatal(err)
+	}
+	tavern, err := NewTavern(WithOrderService(os), WithBillingService(bs), WithVoucherRepository(vouchers))
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid, err := os.AddCustomer("Percy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The empty wallet can not pay, the voucher and the stock are given back
+	if _, err := tavern.Order(context.Background(), uid, beer, WithVoucherCode("welcome")); !errors.Is(err, customer.ErrInsufficientFunds) {
+		t.Fatalf("Expected error %v, got %v", customer.ErrInsufficientFunds, err)
+	}
+	c, err := os.Customers.Get(uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := c.GetTransactions()
+	if len(got) != 2 || got[0].GetTo() != uid || got[1].GetFrom() != uid || got[1].GetAmount() != got[0].GetAmount() {
+		t.Errorf("Expected the voucher transaction to be reversed, got %v", got)
+	}
+	if p, _ := os.Products.GetByID(beer[0]); p.GetQuantity() != 1 {
+		t.Errorf("Expected the beer to be back in stock, got %d", p.GetQuantity())
+	}
+
+	if _, err := bs.TopUp(context.Background(), uid, 1); err != nil {
+		t.Fatal(err)
+	}
+	placed, err := tavern.Order(context.Background(), uid, beer, WithVoucherCode("welcome"))
+	if err != nil {
+		t.Fatalf("Expected the voucher to be redeemable again, got %v", err)
+	}
+	if placed.Total() != 0.99 {
+		t.Errorf("Expected total 0.99, got %v", placed.Total())
+	}
+	// When a payer of a split can not pay, those already billed are refunded
+	anna, err := os.AddCustomer("Anna")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bs.TopUp(context.Background(), uid, 1); err != nil {
+		t.Fatal(err)
+	}
+	_, err = tavern.Order(context.Background(), uid, []uuid.UUID{products[1].GetID()},
+		WithSplit(billing.Even{Payers: []uuid.UUID{uid, anna}}))
+	if !errors.Is(err, customer.ErrInsufficientFunds) {
+		t.Fatalf("Expected error %v, got %v", customer.ErrInsufficientFunds, err)
+	}
+	if balance, _ := bs.Balance(uid); balance != 1.01 {
+		t.Errorf("Expected balance 1.01, got %v", balance)
+	}
+}
@@ -0,0 +1,135 @@
+// Package telemetry holds the metrics and tracing instrumentation of the tavern.
+// Metrics are exposed in the Prometheus text format and spans are created through OpenTelemetry.
+package telemetry
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gegaryfa/tavern/domain/customer"
+	"github.com/gegaryfa/tavern/domain/product"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// TracerName is the instrumentation name used for every span created by the tavern
+const TracerName = "github.com/gegaryfa/tavern"
+
+// sentinels maps the domain errors to the label they are counted under.
+// Errors that are not listed here are counted as "other".
+var sentinels = []struct {
+	err   error
+	label string
+}{
+	{customer.ErrCustomerNotFound, "customer_not_found"},
+	{customer.ErrFailedToAddCustomer, "failed_to_add_customer"},
+	{customer.ErrUpdateCustomer, "update_customer"},
+	{customer.ErrInvalidCursor, "invalid_cursor"},
+	{product.ErrProductNotFound, "product_not_found"},
+	{product.ErrProductAlreadyExist, "product_already_exist"},
+	{product.ErrInvalidCursor, "invalid_cursor"},
+	{product.ErrProductDiscontinued, "product_discontinued"},
+}
+
+// ErrorLabel returns the metric label for err
+func ErrorLabel(err error) string {
+	for _, s := range sentinels {
+		if errors.Is(err, s.err) {
+			return s.label
+		}
+	}
+	return "other"
+}
+
+// Metrics holds all the Prometheus collectors of the tavern.
+// A nil *Metrics is valid and records nothing, which keeps the instrumented code free of nil checks.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	repositoryDuration *prometheus.HistogramVec
+	repositoryErrors   *prometheus.CounterVec
+
+	orderDuration prometheus.Histogram
+	orderErrors   *prometheus.CounterVec
+	orders        prometheus.Counter
+	revenue       prometheus.Counter
+}
+
+// NewMetrics creates the collectors and registers them on a new registry
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		repositoryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "tavern_repository_duration_seconds",
+			Help:    "Latency of repository calls.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"repository", "method"}),
+		repositoryErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "tavern_repository_errors_total",
+			Help: "Errors returned by repository calls, per domain error.",
+		}, []string{"repository", "method", "error"}),
+		orderDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "tavern_order_duration_seconds",
+			Help:    "Latency of order creation.",
+			Buckets: prometheus.DefBuckets,
+		}),
+		orderErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "tavern_order_errors_total",
+			Help: "Failed orders, per domain error.",
+		}, []string{"error"}),
+		orders: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "tavern_orders_total",
+			Help: "Orders created.",
+		}),
+		revenue: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "tavern_order_revenue_total",
+			Help: "Sum of the totals of all orders created.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		m.repositoryDuration,
+		m.repositoryErrors,
+		m.orderDuration,
+		m.orderErrors,
+		m.orders,
+		m.revenue,
+	)
+	return m
+}
+
+// Registry returns the registry the collectors are registered on, to add further collectors or gather them
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// Handler serves the metrics in the Prometheus text format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRepository records a single repository call
+func (m *Metrics) ObserveRepository(repository, method string, start time.Time, err error) {
+	if m == nil {
+		return
+	}
+	m.repositoryDuration.WithLabelValues(repository, method).Observe(time.Since(start).Seconds())
+	if err != nil {
+		m.repositoryErrors.WithLabelValues(repository, method, ErrorLabel(err)).Inc()
+	}
+}
+
+// ObserveOrder records a single order creation, total is only counted for successful orders
+func (m *Metrics) ObserveOrder(start time.Time, total float64, err error) {
+	if m == nil {
+		return
+	}
+	m.orderDuration.Observe(time.Since(start).Seconds())
+	if err != nil {
+		m.orderErrors.WithLabelValues(ErrorLabel(err)).Inc()
+		return
+	}
+	m.orders.Inc()
+	m.revenue.Add(total)
+}
@@ -0,0 +1,89 @@
+package telemetry
+
+import (
+	"time"
+
+	"github.com/gegaryfa/tavern/domain/customer"
+	"github.com/gegaryfa/tavern/domain/product"
+	"github.com/google/uuid"
+)
+
+// CustomerRepository is a customer.Repository decorator recording latency and errors of every call
+type CustomerRepository struct {
+	next    customer.Repository
+	metrics *Metrics
+}
+
+// NewCustomerRepository wraps next so that every call is recorded on m
+func NewCustomerRepository(next customer.Repository, m *Metrics) *CustomerRepository {
+	return &CustomerRepository{next: next, metrics: m}
+}
+
+func (r *CustomerRepository) Get(id uuid.UUID) (c customer.Customer, err error) {
+	defer r.observe("Get", time.Now(), &err)
+	return r.next.Get(id)
+}
+
+func (r *CustomerRepository) Add(c customer.Customer) (err error) {
+	defer r.observe("Add", time.Now(), &err)
+	return r.next.Add(c)
+}
+
+func (r *CustomerRepository) Update(c customer.Customer) (err error) {
+	defer r.observe("Update", time.Now(), &err)
+	return r.next.Update(c)
+}
+
+func (r *CustomerRepository) List(query customer.ListQuery) (page customer.Page, err error) {
+	defer r.observe("List", time.Now(), &err)
+	return r.next.List(query)
+}
+
+func (r *CustomerRepository) observe(method string, start time.Time, err *error) {
+	r.metrics.ObserveRepository("customer", method, start, *err)
+}
+
+// ProductRepository is a product.Repository decorator recording latency and errors of every call
+type ProductRepository struct {
+	next    product.Repository
+	metrics *Metrics
+}
+
+// NewProductRepository wraps next so that every call is recorded on m
+func NewProductRepository(next product.Repository, m *Metrics) *ProductRepository {
+	return &ProductRepository{next: next, metrics: m}
+}
+
+func (r *ProductRepository) GetAll() (products []product.Product, err error) {
+	defer r.observe("GetAll", time.Now(), &err)
+	return r.next.GetAll()
+}
+
+func (r *ProductRepository) GetByID(id uuid.UUID) (p product.Product, err error) {
+	defer r.observe("GetByID", time.Now(), &err)
+	return r.next.GetByID(id)
+}
+
+func (r *ProductRepository) Add(p product.Product) (err error) {
+	defer r.observe("Add", time.Now(), &err)
+	return r.next.Add(p)
+}
+
+func (r *ProductRepository) Update(p product.Product) (err error) {
+	defer r.observe("Update", time.Now(), &err)
+	return r.next.Update(p)
+}
+
+func (r *ProductRepository) Delete(id uuid.UUID) (err error) {
+	defer r.observe("Delete", time.Now(), &err)
+	return r.next.Delete(id)
+}
+
+func (r *ProductRepository) Find(query product.Query) (page product.Page, err error) {
+	defer r.observe("Find", time.Now(), &err)
+	return r.next.Find(query)
+}
+
+func (r *ProductRepository) observe(method string, start time.Time, err *error) {
+	r.metrics.ObserveRepository("product", method, start, *err)
+}
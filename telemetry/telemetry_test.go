@@ -0,0 +1,105 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gegaryfa/tavern/domain/customer"
+	"github.com/gegaryfa/tavern/domain/customer/memory"
+	"github.com/gegaryfa/tavern/domain/product"
+	"github.com/google/uuid"
+)
+
+func TestErrorLabel(t *testing.T) {
+	type testCase struct {
+		test     string
+		err      error
+		expected string
+	}
+
+	testCases := []testCase{
+		{
+			test:     "sentinel",
+			err:      product.ErrProductNotFound,
+			expected: "product_not_found",
+		}, {
+			test:     "wrapped sentinel",
+			err:      fmt.Errorf("customer already exists: %w", customer.ErrFailedToAddCustomer),
+			expected: "failed_to_add_customer",
+		}, {
+			test:     "unknown error",
+			err:      fmt.Errorf("connection refused"),
+			expected: "other",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			if got := ErrorLabel(tc.err); got != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestMetrics_Handler(t *testing.T) {
+	m := NewMetrics()
+	repo := NewCustomerRepository(memory.New(), m)
+
+	if _, err := repo.Get(uuid.New()); err != customer.ErrCustomerNotFound {
+		t.Fatalf("Expected error %v, got %v", customer.ErrCustomerNotFound, err)
+	}
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+
+	expected := []string{
+		`tavern_repository_duration_seconds_count{method="Get",repository="customer"} 1`,
+		`tavern_repository_errors_total{error="customer_not_found",method="Get",repository="customer"} 1`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(body, line) {
+			t.Errorf("Expected metrics output to contain %q, got:\n%s", line, body)
+		}
+	}
+}
+
+func TestMetrics_Nil(t *testing.T) {
+	// A nil Metrics must be safe to use
+	var m *Metrics
+	repo := NewCustomerRepository(memory.New(), m)
+	if _, err := repo.Get(uuid.New()); err != customer.ErrCustomerNotFound {
+		t.Errorf("Expected error %v, got %v", customer.ErrCustomerNotFound, err)
+	}
+}
+
+func TestContextLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := ContextLogger(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	ctx := WithRequestID(context.Background(), "req-1")
+	logger.With(slog.String("customer_id", "percy")).InfoContext(ctx, "order created")
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record["request_id"] != "req-1" {
+		t.Errorf("Expected request_id req-1, got %v", record["request_id"])
+	}
+	if record["customer_id"] != "percy" {
+		t.Errorf("Expected customer_id percy, got %v", record["customer_id"])
+	}
+}
+
+func TestContextLogger_Nil(t *testing.T) {
+	// A nil logger must not panic, nothing is written
+	ContextLogger(nil).InfoContext(WithRequestID(context.Background(), "req-1"), "order created")
+}
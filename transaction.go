@@ -1,14 +1,89 @@
 package tavern
 
 import (
+	"math"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// TransactionKind tells what a transaction pays for
+type TransactionKind string
+
+const (
+	// TransactionPayment pays for orders
+	TransactionPayment TransactionKind = "payment"
+	// TransactionTip is a tip for the staff, kept apart from the payment so tips can be reported on
+	TransactionTip TransactionKind = "tip"
+	// TransactionServiceCharge is the service charge added to the bill of large parties
+	TransactionServiceCharge TransactionKind = "service_charge"
+	// TransactionVoucher is the value of a redeemed voucher
+	TransactionVoucher TransactionKind = "voucher"
+	// TransactionTopUp is money put in the wallet of a customer
+	TransactionTopUp TransactionKind = "top_up"
+	// TransactionRefund gives money back to a customer
+	TransactionRefund TransactionKind = "refund"
+)
+
+// Transaction is a value object that represents a payment between two parties
 type Transaction struct {
+	// amount is in cents
 	amount    int
 	from      uuid.UUID
 	to        uuid.UUID
 	createdAt time.Time
+	// reference describes what the transaction is for, such as the voucher it redeems
+	reference string
+	kind      TransactionKind
+}
+
+// NewTransaction creates a Transaction, it is used both when paying and when loading stored transactions
+func NewTransaction(amount int, from, to uuid.UUID, createdAt time.Time) Transaction {
+	return Transaction{
+		amount:    amount,
+		from:      from,
+		to:        to,
+		createdAt: createdAt,
+	}
+}
+
+func (t Transaction) GetAmount() int {
+	return t.amount
+}
+
+func (t Transaction) GetFrom() uuid.UUID {
+	return t.from
+}
+
+func (t Transaction) GetTo() uuid.UUID {
+	return t.to
+}
+
+func (t Transaction) GetCreatedAt() time.Time {
+	return t.createdAt
+}
+
+func (t Transaction) GetReference() string {
+	return t.reference
+}
+
+// WithReference returns a copy of the transaction describing what it is for
+func (t Transaction) WithReference(reference string) Transaction {
+	t.reference = reference
+	return t
+}
+
+func (t Transaction) GetKind() TransactionKind {
+	return t.kind
+}
+
+// WithKind returns a copy of the transaction of the given kind
+func (t Transaction) WithKind(kind TransactionKind) Transaction {
+	t.kind = kind
+	return t
+}
+
+// Cents converts an amount of money to the cents a transaction holds
+func Cents(amount float64) int {
+	return int(math.Round(amount * 100))
 }